@@ -3,16 +3,18 @@ package blockchain
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
 	"go.uber.org/zap"
 	"time"
 )
 
 //区块结构
-//Transactions、MerkleRoot、PrevBlockHash、Nonce、Height需要进行哈希计算
+//Transactions、MerkleRoot、WitnessRoot、PrevBlockHash、Nonce、Height需要进行哈希计算
 type Block struct {
 	Timestamp     int64  //时间戳
 	Hash          []byte //当前区块哈希值
 	MerkleRoot    []byte //默克尔树根
+	WitnessRoot   []byte //见证数据默克尔树根
 	PrevBlockHash []byte //前块哈希值
 	Nonce         int    //随机值
 
@@ -21,19 +23,53 @@ type Block struct {
 }
 
 func (b *Block) GetMerkleRoot() []byte {
-	var txHashes [][]byte
+	tree := NewMerkleTree(b.merkleLeaves())
+
+	return tree.MerkleRoot.Data
+}
+
+// GetWitnessRoot 计算包含见证数据的交易哈希（wtxid）所构成的默克尔树根
+func (b *Block) GetWitnessRoot() []byte {
+	var leaves [][]byte
 
 	for _, tx := range b.Transactions {
-		txHashes = append(txHashes, tx.Serialize())
+		leaves = append(leaves, tx.WitnessHash())
 	}
-	tree := NewMerkleTree(txHashes)
+	tree := NewMerkleTree(leaves)
 
 	return tree.MerkleRoot.Data
 }
 
+// MerkleProof 生成区块中指定交易的默克尔证明
+func (b *Block) MerkleProof(txID []byte) (*MerkleProof, error) {
+	for _, tx := range b.Transactions {
+		if bytes.Equal(tx.ID, txID) {
+			return NewMerkleProof(b.merkleLeaves(), MerkleLeaf(tx))
+		}
+	}
+
+	return nil, errors.New("Transaction is not in block")
+}
+
+// MerkleLeaf 交易在默克尔树中对应的叶数据，即不包含见证数据的交易ID
+func MerkleLeaf(tx *Transaction) []byte {
+	return tx.Hash()
+}
+
+// merkleLeaves 获取区块中所有交易的叶数据
+func (b *Block) merkleLeaves() [][]byte {
+	var leaves [][]byte
+
+	for _, tx := range b.Transactions {
+		leaves = append(leaves, MerkleLeaf(tx))
+	}
+
+	return leaves
+}
+
 // CreateBlock 创建区块
 func CreateBlock(txs []*Transaction, prevHash []byte, height int) *Block {
-	block := &Block{time.Now().Unix(), []byte{}, []byte{}, prevHash, 0, txs, height}
+	block := &Block{time.Now().Unix(), []byte{}, []byte{}, []byte{}, prevHash, 0, txs, height}
 
 	//挖矿成功后赋值最终的nonce和区块哈希值
 	pow := NewProof(block)
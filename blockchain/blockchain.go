@@ -1,6 +1,7 @@
 package blockchain
 
 import (
+	"Golang_Bitcoin_Sample/wallet"
 	"bytes"
 	"crypto/ecdsa"
 	"encoding/hex"
@@ -35,6 +36,11 @@ func DBexists(path string) bool {
 	return true
 }
 
+// BlockChainExists 判断节点对应的区块链数据库是否存在
+func BlockChainExists(nodeId string) bool {
+	return DBexists(fmt.Sprintf(dbPath, nodeId))
+}
+
 // retry 数据库启动辅助函数
 func retry(dir string, originalOpts badger.Options) (*badger.DB, error) {
 	lockPath := filepath.Join(dir, "LOCK")
@@ -101,10 +107,8 @@ func (chain *BlockChain) MineBlock(transactions []*Transaction) *Block {
 	var lastHash []byte
 	var lastHeight int
 
-	for _, tx := range transactions {
-		if chain.VerifyTransaction(tx) != true {
-			log.Panic("Invalid Transaction")
-		}
+	if chain.VerifyTransactions(transactions) != true {
+		log.Panic("Invalid Transaction")
 	}
 
 	err := chain.Database.View(func(txn *badger.Txn) error {
@@ -205,6 +209,96 @@ func (bc *BlockChain) FindTransaction(ID []byte) (Transaction, error) {
 	return Transaction{}, errors.New("Transaction does not exist")
 }
 
+// FindDataCarrier 查找携带指定数据输出的交易，返回最早包含该数据的区块与交易
+func (bc *BlockChain) FindDataCarrier(data []byte) (*Block, *Transaction, error) {
+	var foundBlock *Block
+	var foundTx *Transaction
+
+	iter := bc.Iterator()
+
+	for {
+		block := iter.Next()
+
+		for _, tx := range block.Transactions {
+			for _, out := range tx.Outputs {
+				// 从最新区块向前遍历，持续覆盖以保留最早的记录
+				if out.IsDataCarrier() && bytes.Equal(out.Data, data) {
+					foundBlock, foundTx = block, tx
+				}
+			}
+		}
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	if foundTx == nil {
+		return nil, nil, errors.New("Data carrier does not exist")
+	}
+
+	return foundBlock, foundTx, nil
+}
+
+// AddressTx 与某个公钥哈希相关的一笔交易
+type AddressTx struct {
+	TxID      []byte // 交易ID
+	Height    int    // 所在区块高度
+	Timestamp int64  // 所在区块时间
+	Received  int    // 支付给该公钥哈希的金额
+	Sent      int    // 花费该公钥哈希所持有UTXO的金额
+}
+
+// FindAddressHistory 按区块顺序查找与公钥哈希相关的所有交易，只需要公钥哈希，只读地址同样适用
+func (bc *BlockChain) FindAddressHistory(pubKeyHash []byte) []AddressTx {
+	var blocks []*Block
+
+	iter := bc.Iterator()
+	for {
+		block := iter.Next()
+		blocks = append(blocks, block)
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	// 从创世区块开始正向遍历，记录属于该公钥哈希的输出，以便识别之后花费它们的输入
+	owned := make(map[string]int)
+	var history []AddressTx
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+
+		for _, tx := range block.Transactions {
+			entry := AddressTx{tx.ID, block.Height, block.Timestamp, 0, 0}
+
+			if !tx.IsCoinbaseTx() {
+				for _, in := range tx.Inputs {
+					key := fmt.Sprintf("%x:%d", in.ID, in.Out)
+					if value, ok := owned[key]; ok {
+						entry.Sent += value
+						delete(owned, key)
+					}
+				}
+			}
+
+			for outIdx, out := range tx.Outputs {
+				if !out.IsDataCarrier() && out.PubKeyHashEquals(pubKeyHash) {
+					entry.Received += out.Value
+					owned[fmt.Sprintf("%x:%d", tx.ID, outIdx)] = out.Value
+				}
+			}
+
+			if entry.Received > 0 || entry.Sent > 0 {
+				history = append(history, entry)
+			}
+		}
+	}
+
+	return history
+}
+
 // SignTransaction 签署交易
 func (bc *BlockChain) SignTransaction(tx *Transaction, privKey ecdsa.PrivateKey) {
 	prevTXs := make(map[string]Transaction)
@@ -223,19 +317,90 @@ func (bc *BlockChain) SignTransaction(tx *Transaction, privKey ecdsa.PrivateKey)
 
 // VerifyTransaction 验证交易合法性
 func (bc *BlockChain) VerifyTransaction(tx *Transaction) bool {
-	if tx.IsCoinbaseTx() {
-		return true
+	return bc.VerifyTransactions([]*Transaction{tx})
+}
+
+// VerifyTransactions 验证一组交易（如一个区块）的合法性，其中所有Schnorr签名合并为一次批量验证
+func (bc *BlockChain) VerifyTransactions(txs []*Transaction) bool {
+	batch := &wallet.SchnorrBatch{}
+
+	// 同一批次中排在前面的交易可以作为后面交易的前序交易
+	batchTXs := make(map[string]Transaction)
+	fees, reward := 0, 0
+	for _, tx := range txs {
+		if tx.IsCoinbaseTx() {
+			for _, out := range tx.Outputs {
+				if out.Value < 0 {
+					return false
+				}
+				reward += out.Value
+			}
+			continue
+		}
+		prevTXs, err := bc.prevTransactions(tx, batchTXs)
+		if err != nil {
+			zap.L().Error("bc.FindTransaction() failed", zap.Error(err))
+			return false
+		}
+		batchTXs[hex.EncodeToString(tx.ID)] = *tx
+
+		if !tx.verify(prevTXs, batch) {
+			return false
+		}
+		fees += tx.fee(prevTXs)
+	}
+
+	// 币基交易领取的金额不能超过出块奖励与批次中交易手续费之和
+	if reward > Subsidy+fees {
+		return false
+	}
+
+	return batch.Verify()
+}
+
+// FindInvalidTransaction 逐笔验证一组交易，返回第一笔不合法交易的序号，全部合法时返回-1
+// 每笔交易的Schnorr签名单独验证，用于在批量验证失败后找出不合法的交易
+func (bc *BlockChain) FindInvalidTransaction(txs []*Transaction) int {
+	batchTXs := make(map[string]Transaction)
+	for i, tx := range txs {
+		if tx.IsCoinbaseTx() {
+			continue
+		}
+
+		prevTXs, err := bc.prevTransactions(tx, batchTXs)
+		if err != nil {
+			return i
+		}
+
+		batch := &wallet.SchnorrBatch{}
+		if !tx.verify(prevTXs, batch) || !batch.Verify() {
+			return i
+		}
+		batchTXs[hex.EncodeToString(tx.ID)] = *tx
 	}
+
+	return -1
+}
+
+// prevTransactions 查找交易输入引用的前序交易，优先使用同一批次中排在前面的交易
+func (bc *BlockChain) prevTransactions(tx *Transaction, batchTXs map[string]Transaction) (map[string]Transaction, error) {
 	prevTXs := make(map[string]Transaction)
 
 	for _, in := range tx.Inputs {
-		prevTX, err := bc.FindTransaction(in.ID)
-		zap.L().Error("bc.FindTransaction() failed", zap.Error(err))
+		id := hex.EncodeToString(in.ID)
+		if prevTX, ok := batchTXs[id]; ok {
+			prevTXs[id] = prevTX
+			continue
+		}
 
-		prevTXs[hex.EncodeToString(prevTX.ID)] = prevTX
+		prevTX, err := bc.FindTransaction(in.ID)
+		if err != nil {
+			return nil, err
+		}
+		prevTXs[id] = prevTX
 	}
 
-	return tx.Verify(prevTXs)
+	return prevTXs, nil
 }
 
 // FindUTXO 查找UTXO
@@ -264,10 +429,15 @@ func (chain *BlockChain) FindUTXO() map[string]TxOutputs {
 					}
 				}
 
+				// 数据输出不可花费，不进入UTXO集合
+				if out.IsDataCarrier() {
+					continue
+				}
+
 				//如果输出结构中的交易没有被使用过，则添加到UTXO集合中
 				//UTXO也是交易ID 和 未使用Output的关系映射
 				outs := UTXO[txID]
-				outs.Outputs = append(outs.Outputs, out)
+				outs.Append(out, outIdx)
 				UTXO[txID] = outs
 			}
 
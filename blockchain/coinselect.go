@@ -0,0 +1,373 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 交易大小估计（字节），用于按费率计算手续费
+const (
+	TxOverheadSize = 11 // 版本、输入输出计数等固定部分
+	TxInputSize    = 68 // 一个输入及其见证数据
+	TxOutputSize   = 31 // 一个输出
+)
+
+// 选币相关错误
+var (
+	ErrInsufficientFunds   = errors.New("not enough funds")
+	ErrNoChangelessMatch   = errors.New("no changeless coin selection found")
+	ErrUnknownCoinSelector = errors.New("unknown coin selection strategy")
+)
+
+// OutPoint 指向某笔交易的某个输出
+type OutPoint struct {
+	TxID  string // 交易ID（十六进制）
+	Index int    // 输出索引
+}
+
+// String 输出点的文本表示 txid:vout
+func (o OutPoint) String() string {
+	return fmt.Sprintf("%s:%d", o.TxID, o.Index)
+}
+
+// ParseOutPoint 解析 txid:vout 格式的输出点
+func ParseOutPoint(s string) (OutPoint, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return OutPoint{}, fmt.Errorf("outpoint %q must be txid:vout", s)
+	}
+
+	if _, err := hex.DecodeString(parts[0]); err != nil || len(parts[0]) != 64 {
+		return OutPoint{}, fmt.Errorf("invalid txid in outpoint %q", s)
+	}
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 {
+		return OutPoint{}, fmt.Errorf("invalid vout in outpoint %q", s)
+	}
+
+	return OutPoint{strings.ToLower(parts[0]), index}, nil
+}
+
+// SpendableOutput 可供花费的UTXO及其位置
+type SpendableOutput struct {
+	OutPoint
+	Output TxOutput
+}
+
+// EffectiveValue 扣除花费该输出所需手续费后的有效金额
+func (s SpendableOutput) EffectiveValue(feeRate int) int {
+	return s.Output.Value - feeRate*TxInputSize
+}
+
+// SelectionOptions 选币参数
+type SelectionOptions struct {
+	FeeRate      int // 每字节手续费
+	CostOfChange int // 产生找零的成本：找零输出的手续费与将来花费它的手续费
+}
+
+// CoinSelector 选币策略，从候选UTXO中选出有效金额之和不小于target的组合
+type CoinSelector interface {
+	Select(utxos []SpendableOutput, target int, opts SelectionOptions) ([]SpendableOutput, error)
+}
+
+// Greedy 按UTXO集合的遍历顺序累加，直到金额足够，是引入选币策略之前的默认行为
+type Greedy struct{}
+
+// Select 按原有顺序累加
+func (Greedy) Select(utxos []SpendableOutput, target int, opts SelectionOptions) ([]SpendableOutput, error) {
+	return accumulate(utxos, target, opts.FeeRate)
+}
+
+// LargestFirst 优先选择金额最大的UTXO，输入数量最少
+type LargestFirst struct{}
+
+// Select 按有效金额从大到小累加
+func (LargestFirst) Select(utxos []SpendableOutput, target int, opts SelectionOptions) ([]SpendableOutput, error) {
+	sorted := sortByEffectiveValue(utxos, opts.FeeRate)
+
+	return accumulate(sorted, target, opts.FeeRate)
+}
+
+// SmallestFirst 优先选择金额最小的UTXO，用于合并零散的小额UTXO
+type SmallestFirst struct{}
+
+// Select 按有效金额从小到大累加
+func (SmallestFirst) Select(utxos []SpendableOutput, target int, opts SelectionOptions) ([]SpendableOutput, error) {
+	sorted := sortByEffectiveValue(utxos, opts.FeeRate)
+	for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+		sorted[i], sorted[j] = sorted[j], sorted[i]
+	}
+
+	return accumulate(sorted, target, opts.FeeRate)
+}
+
+// RandomSelector 随机顺序选择UTXO，避免选币结果暴露钱包中的UTXO分布
+type RandomSelector struct{}
+
+// Select 随机打乱后累加
+func (RandomSelector) Select(utxos []SpendableOutput, target int, opts SelectionOptions) ([]SpendableOutput, error) {
+	shuffled := append([]SpendableOutput{}, utxos...)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	return accumulate(shuffled, target, opts.FeeRate)
+}
+
+// 分支定界法的最大尝试次数
+const bnbMaxTries = 100000
+
+// BranchAndBound 分支定界法，寻找有效金额之和落在 [target, target+CostOfChange] 内的组合，使交易无需找零
+// 找不到时使用Fallback策略，Fallback为空时返回ErrNoChangelessMatch
+type BranchAndBound struct {
+	Fallback CoinSelector
+}
+
+// Select 深度优先搜索按有效金额降序排列的UTXO的包含/排除二叉树
+func (b BranchAndBound) Select(utxos []SpendableOutput, target int, opts SelectionOptions) ([]SpendableOutput, error) {
+	selected, err := b.search(utxos, target, opts)
+	if err == ErrNoChangelessMatch && b.Fallback != nil {
+		return b.Fallback.Select(utxos, target, opts)
+	}
+
+	return selected, err
+}
+
+func (b BranchAndBound) search(utxos []SpendableOutput, target int, opts SelectionOptions) ([]SpendableOutput, error) {
+	sorted := sortByEffectiveValue(utxos, opts.FeeRate)
+	values := make([]int, len(sorted))
+	remaining := 0
+	for i, utxo := range sorted {
+		values[i] = utxo.EffectiveValue(opts.FeeRate)
+		remaining += values[i]
+	}
+	if remaining < target {
+		return nil, ErrInsufficientFunds
+	}
+
+	upper := target + opts.CostOfChange
+	current := 0
+	var path, best []int
+	bestWaste := -1
+
+	for tries, depth := 0, 0; tries < bnbMaxTries; tries++ {
+		backtrack := false
+		switch {
+		case current+remaining < target || current > upper:
+			// 剩余的UTXO全部加入也不够，或者已经超出上限
+			backtrack = true
+		case current >= target:
+			// 找到一个可行解，多出的金额即为浪费
+			if waste := current - target; bestWaste < 0 || waste < bestWaste {
+				best, bestWaste = append([]int{}, path...), waste
+			}
+			backtrack = true
+		}
+
+		if backtrack {
+			// 回溯到最近一个被包含的UTXO，改为排除它
+			for depth > 0 && (len(path) == 0 || path[len(path)-1] != depth-1) {
+				depth--
+				remaining += values[depth]
+			}
+			if len(path) == 0 {
+				break
+			}
+			last := path[len(path)-1]
+			path = path[:len(path)-1]
+			current -= values[last]
+			depth = last + 1
+			continue
+		}
+
+		if depth >= len(values) {
+			continue
+		}
+
+		// 包含当前UTXO并继续向下搜索
+		remaining -= values[depth]
+		current += values[depth]
+		path = append(path, depth)
+		depth++
+	}
+
+	if best == nil {
+		return nil, ErrNoChangelessMatch
+	}
+
+	selected := make([]SpendableOutput, len(best))
+	for i, idx := range best {
+		selected[i] = sorted[idx]
+	}
+
+	return selected, nil
+}
+
+// NewCoinSelector 根据名称创建选币策略：greedy（默认）、bnb、largest、smallest、random
+func NewCoinSelector(name string) (CoinSelector, error) {
+	switch strings.ToLower(name) {
+	case "", "greedy":
+		return Greedy{}, nil
+	case "bnb":
+		return BranchAndBound{Fallback: RandomSelector{}}, nil
+	case "largest":
+		return LargestFirst{}, nil
+	case "smallest":
+		return SmallestFirst{}, nil
+	case "random":
+		return RandomSelector{}, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownCoinSelector, name)
+	}
+}
+
+// CoinControl 交易构造时的选币控制
+type CoinControl struct {
+	Selector    CoinSelector // 选币策略，为空时按UTXO集合的遍历顺序累加
+	FeeRate     int          // 每字节手续费
+	Pinned      []OutPoint   // 必须花费的输出
+	Excluded    []OutPoint   // 不得花费的输出
+	Replaceable bool         // 交易打包前可以被手续费更高的交易替换
+}
+
+// CoinSelection 选币结果
+type CoinSelection struct {
+	Inputs []SpendableOutput // 被花费的UTXO
+	Fee    int               // 手续费
+	Change int               // 找零金额，为0时不产生找零输出
+}
+
+// SelectCoins 从候选UTXO中选币，支付amount并覆盖手续费
+// outputs为不含找零的输出数量；固定的输出先计入，剩余部分由选币策略补足
+func (cc *CoinControl) SelectCoins(utxos []SpendableOutput, amount, outputs int) (*CoinSelection, error) {
+	if cc == nil {
+		cc = &CoinControl{}
+	}
+	selector := cc.Selector
+	if selector == nil {
+		selector = Greedy{}
+	}
+
+	excluded := make(map[OutPoint]bool)
+	for _, op := range cc.Excluded {
+		excluded[op] = true
+	}
+	pinned := make(map[OutPoint]bool)
+	for _, op := range cc.Pinned {
+		if excluded[op] {
+			return nil, fmt.Errorf("outpoint %s is both pinned and excluded", op)
+		}
+		pinned[op] = true
+	}
+
+	// 分离必须花费的UTXO，其余UTXO中只保留有效金额为正的作为候选
+	var selected, candidates []SpendableOutput
+	for _, utxo := range utxos {
+		switch {
+		case pinned[utxo.OutPoint]:
+			selected = append(selected, utxo)
+			delete(pinned, utxo.OutPoint)
+		case excluded[utxo.OutPoint]:
+		case utxo.EffectiveValue(cc.FeeRate) > 0:
+			candidates = append(candidates, utxo)
+		}
+	}
+	for op := range pinned {
+		return nil, fmt.Errorf("outpoint %s is not spendable", op)
+	}
+
+	opts := SelectionOptions{cc.FeeRate, cc.FeeRate * (TxOutputSize + TxInputSize)}
+	fixedFee := cc.FeeRate * (TxOverheadSize + outputs*TxOutputSize)
+	target := amount + fixedFee
+	for _, utxo := range selected {
+		target -= utxo.EffectiveValue(cc.FeeRate)
+	}
+
+	if target > 0 {
+		more, err := selector.Select(candidates, target, opts)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, more...)
+	}
+
+	return newCoinSelection(selected, amount, outputs, cc.FeeRate)
+}
+
+// newCoinSelection 计算手续费与找零，找零不足以覆盖自身成本时并入手续费
+func newCoinSelection(inputs []SpendableOutput, amount, outputs, feeRate int) (*CoinSelection, error) {
+	total := 0
+	for _, in := range inputs {
+		total += in.Output.Value
+	}
+
+	fee := feeRate * (TxOverheadSize + len(inputs)*TxInputSize + outputs*TxOutputSize)
+	if total < amount+fee {
+		return nil, ErrInsufficientFunds
+	}
+
+	change := total - amount - fee
+	changeFee := feeRate * TxOutputSize
+	if change <= changeFee+feeRate*TxInputSize {
+		// 找零过小，花费它的手续费比它本身还高，直接作为手续费
+		return &CoinSelection{inputs, fee + change, 0}, nil
+	}
+
+	return &CoinSelection{inputs, fee + changeFee, change - changeFee}, nil
+}
+
+// sortByEffectiveValue 按有效金额从大到小排序，金额相同时按输出点排序以保证结果稳定
+func sortByEffectiveValue(utxos []SpendableOutput, feeRate int) []SpendableOutput {
+	sorted := append([]SpendableOutput{}, utxos...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		vi, vj := sorted[i].EffectiveValue(feeRate), sorted[j].EffectiveValue(feeRate)
+		if vi != vj {
+			return vi > vj
+		}
+		return sorted[i].OutPoint.String() < sorted[j].OutPoint.String()
+	})
+
+	return sorted
+}
+
+// accumulate 按顺序累加UTXO，直到有效金额之和达到target
+func accumulate(utxos []SpendableOutput, target, feeRate int) ([]SpendableOutput, error) {
+	var selected []SpendableOutput
+	sum := 0
+
+	for _, utxo := range utxos {
+		if sum >= target {
+			break
+		}
+		selected = append(selected, utxo)
+		sum += utxo.EffectiveValue(feeRate)
+	}
+
+	if sum < target {
+		return nil, ErrInsufficientFunds
+	}
+
+	return selected, nil
+}
+
+// toTxInputs 将选中的UTXO转换为输入结构，replaceable为真时每个输入都发出可替换信号
+func toTxInputs(utxos []SpendableOutput, replaceable bool) []TxInput {
+	inputs := make([]TxInput, 0, len(utxos))
+
+	for _, utxo := range utxos {
+		txID, err := hex.DecodeString(utxo.TxID)
+		if err != nil {
+			zap.L().Error("hex.DecodeString() failed", zap.Error(err))
+			continue
+		}
+		inputs = append(inputs, TxInput{txID, utxo.Index, nil, replaceable})
+	}
+
+	return inputs
+}
@@ -0,0 +1,125 @@
+package blockchain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// newCoins 按给定金额构造候选UTXO
+func newCoins(values ...int) []SpendableOutput {
+	coins := make([]SpendableOutput, len(values))
+	for i, value := range values {
+		txID := fmt.Sprintf("%064x", i+1)
+		coins[i] = SpendableOutput{OutPoint{txID, 0}, TxOutput{value, nil, nil, OutputECDSA}}
+	}
+
+	return coins
+}
+
+func sumValues(coins []SpendableOutput) int {
+	sum := 0
+	for _, coin := range coins {
+		sum += coin.Output.Value
+	}
+
+	return sum
+}
+
+func TestCoinSelectors(t *testing.T) {
+	coins := newCoins(1, 2, 5, 10, 20)
+	opts := SelectionOptions{}
+
+	// 分支定界法找到金额恰好相等的组合，无需找零
+	selected, err := BranchAndBound{}.Select(coins, 17, opts)
+	if err != nil || sumValues(selected) != 17 {
+		t.Errorf("BranchAndBound error: 期望选中金额17，实际 %d %v", sumValues(selected), err)
+	}
+	if _, err := (BranchAndBound{}).Select(newCoins(10, 20), 15, opts); err != ErrNoChangelessMatch {
+		t.Errorf("BranchAndBound error: 不存在无找零组合时应返回 ErrNoChangelessMatch，实际 %v", err)
+	}
+	if selected, err := (BranchAndBound{LargestFirst{}}).Select(newCoins(10, 20), 15, opts); err != nil || sumValues(selected) != 20 {
+		t.Errorf("BranchAndBound error: 应回退到Fallback策略 %v", err)
+	}
+
+	// 默认策略按原有顺序累加
+	if selector, _ := NewCoinSelector(""); selector != (Greedy{}) {
+		t.Errorf("NewCoinSelector error: 默认策略为 %T", selector)
+	}
+	selected, err = Greedy{}.Select(coins, 12, opts)
+	if err != nil || len(selected) != 4 || sumValues(selected) != 18 {
+		t.Errorf("Greedy error: 期望选中1、2、5、10，实际 %v %v", selected, err)
+	}
+
+	selected, err = LargestFirst{}.Select(coins, 12, opts)
+	if err != nil || len(selected) != 1 || selected[0].Output.Value != 20 {
+		t.Errorf("LargestFirst error: 期望只选中金额20的UTXO，实际 %v %v", selected, err)
+	}
+
+	selected, err = SmallestFirst{}.Select(coins, 7, opts)
+	if err != nil || len(selected) != 3 || sumValues(selected) != 8 {
+		t.Errorf("SmallestFirst error: 期望选中1、2、5，实际 %v %v", selected, err)
+	}
+
+	if _, err := (RandomSelector{}).Select(coins, 39, opts); err != ErrInsufficientFunds {
+		t.Errorf("RandomSelector error: 余额不足时应返回 ErrInsufficientFunds，实际 %v", err)
+	}
+}
+
+func TestSelectCoins(t *testing.T) {
+	coins := newCoins(1000, 2000, 5000)
+
+	// 费率为1时，金额1000的UTXO有效金额为 1000-68
+	if v := coins[0].EffectiveValue(1); v != 1000-TxInputSize {
+		t.Errorf("EffectiveValue error: 期望 %d，实际 %d", 1000-TxInputSize, v)
+	}
+
+	// 固定花费的输出必须被选中，排除的输出不得被选中
+	cc := &CoinControl{LargestFirst{}, 1, []OutPoint{coins[0].OutPoint}, []OutPoint{coins[2].OutPoint}, false}
+	selection, err := cc.SelectCoins(coins, 2500, 1)
+	if err != nil {
+		t.Fatalf("SelectCoins error: %v", err)
+	}
+	if len(selection.Inputs) != 2 || selection.Inputs[0].OutPoint != coins[0].OutPoint {
+		t.Errorf("SelectCoins error: 选中的输入错误 %v", selection.Inputs)
+	}
+	if sumValues(selection.Inputs) != 2500+selection.Fee+selection.Change {
+		t.Errorf("SelectCoins error: 输入金额不等于支付金额、手续费与找零之和")
+	}
+	expectedFee := TxOverheadSize + 2*TxInputSize + 2*TxOutputSize
+	if selection.Fee != expectedFee {
+		t.Errorf("SelectCoins error: 期望手续费 %d，实际 %d", expectedFee, selection.Fee)
+	}
+
+	// 排除后余额不足
+	cc.Pinned = nil
+	if _, err := cc.SelectCoins(coins, 3000, 1); err != ErrInsufficientFunds {
+		t.Errorf("SelectCoins error: 余额不足时应返回 ErrInsufficientFunds，实际 %v", err)
+	}
+
+	// 找零过小时并入手续费
+	selection, err = (&CoinControl{FeeRate: 1}).SelectCoins(newCoins(1000), 1000-TxOverheadSize-TxInputSize-TxOutputSize-10, 1)
+	if err != nil || selection.Change != 0 {
+		t.Errorf("SelectCoins error: 找零过小时不应产生找零输出 %v %v", selection, err)
+	}
+
+	// 不属于候选集合的固定输出
+	missing := OutPoint{strings.Repeat("ab", 32), 3}
+	if _, err := (&CoinControl{Pinned: []OutPoint{missing}}).SelectCoins(coins, 1, 1); err == nil {
+		t.Error("SelectCoins error: 固定输出不可花费时应返回错误")
+	}
+}
+
+func TestParseOutPoint(t *testing.T) {
+	txID := strings.Repeat("0f", 32)
+	op, err := ParseOutPoint(txID + ":2")
+	if err != nil || op.TxID != txID || op.Index != 2 || op.String() != txID+":2" {
+		t.Errorf("ParseOutPoint error: %v %v", op, err)
+	}
+
+	for _, s := range []string{txID, "zz:1", txID + ":-1", txID + ":x"} {
+		if _, err := ParseOutPoint(s); err == nil {
+			t.Errorf("ParseOutPoint error: %q 应解析失败", s)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestDataCarrierRejected(t *testing.T) {
+	if _, err := NewDataOutput(make([]byte, MaxDataCarrierSize+1)); err == nil {
+		t.Error("NewDataOutput error: 超过长度上限的数据输出应返回错误")
+	}
+
+	w := wallet.NewWallet()
+	coinbase := CoinbaseTx(string(w.GenerateAddress()), "")
+	prevTXs := map[string]Transaction{hex.EncodeToString(coinbase.ID): *coinbase}
+
+	// 手工构造绕过 NewDataOutput 检查的数据输出，签名有效但交易不合法
+	newTx := func(data TxOutput) *Transaction {
+		tx := Transaction{nil, []TxInput{{coinbase.ID, 0, nil, false}}, []TxOutput{data, *NewTXOutput(coinbase.Outputs[0].Value, string(w.GenerateAddress()))}, nil}
+		tx.ID = tx.Hash()
+		if err := tx.SignInput(0, w.PrivateKey, []TxOutput{coinbase.Outputs[0]}, SigHashAll); err != nil {
+			t.Fatalf("SignInput error: %v", err)
+		}
+		tx.Witness[0].PubKey = w.PublicKey
+		return &tx
+	}
+
+	valid := newTx(TxOutput{0, nil, []byte("hash"), OutputECDSA})
+	if !valid.Verify(prevTXs) {
+		t.Fatal("Verify error: 合法的数据输出交易验证失败")
+	}
+	if newTx(TxOutput{0, nil, make([]byte, MaxDataCarrierSize+1), OutputECDSA}).Verify(prevTXs) {
+		t.Error("Verify error: 超过长度上限的数据输出通过了验证")
+	}
+	if newTx(TxOutput{1, nil, []byte("hash"), OutputECDSA}).Verify(prevTXs) {
+		t.Error("Verify error: 金额非零的数据输出通过了验证")
+	}
+}
+
+func TestDataCarrierNotInUTXOSet(t *testing.T) {
+	nodeId := "datacarrier_test"
+	path := fmt.Sprintf(dbPath, nodeId)
+	if err := os.MkdirAll(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./tmp")
+
+	w := wallet.NewWallet()
+	address := string(w.GenerateAddress())
+	chain := InitBlockChain(address, nodeId)
+	defer chain.Database.Close()
+	UTXO := UTXOSet{chain, nil}
+	UTXO.Reindex()
+
+	data := []byte("document hash")
+	tx, err := NewDataTransaction(w, data, &UTXO)
+	if err != nil {
+		t.Fatalf("NewDataTransaction error: %v", err)
+	}
+	UTXO.Update(chain.MineBlock([]*Transaction{CoinbaseTx(address, ""), tx}))
+
+	// 数据输出（序号0）不进入UTXO集合，找零输出可以花费；重建UTXO集合后同样如此
+	for i := 0; i < 2; i++ {
+		if _, ok := UTXO.FindOutput(tx.ID, 0); ok {
+			t.Error("UTXOSet error: 数据输出进入了UTXO集合")
+		}
+		if _, ok := UTXO.FindOutput(tx.ID, 1); !ok {
+			t.Error("UTXOSet error: 找零输出不在UTXO集合中")
+		}
+		UTXO.Reindex()
+	}
+
+	_, found, err := chain.FindDataCarrier(data)
+	if err != nil || !bytes.Equal(found.ID, tx.ID) {
+		t.Errorf("FindDataCarrier error: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// 交易的规范编码，用于计算签名摘要与交易ID
+// gob的编码结果包含进程内按首次使用顺序分配的类型编号，同一笔交易在不同节点上可能得到不同的字节，因此不能作为哈希原像
+// 规范编码中整数为8字节大端序，变长字段与列表前置4字节大端序长度，nil与空切片的编码相同
+
+// encode 交易的规范编码，witness为false时不包含见证数据，交易ID本身不参与编码
+func (tx *Transaction) encode(witness bool) []byte {
+	var buf bytes.Buffer
+
+	writeUint32(&buf, uint32(len(tx.Inputs)))
+	for _, in := range tx.Inputs {
+		writeBytes(&buf, in.ID)
+		writeInt64(&buf, int64(in.Out))
+		writeBytes(&buf, in.Coinbase)
+		writeBool(&buf, in.Replaceable)
+	}
+
+	writeUint32(&buf, uint32(len(tx.Outputs)))
+	for _, out := range tx.Outputs {
+		writeInt64(&buf, int64(out.Value))
+		writeBytes(&buf, out.PubKeyHash)
+		writeBytes(&buf, out.Data)
+		buf.WriteByte(out.Type)
+	}
+
+	if witness {
+		writeUint32(&buf, uint32(len(tx.Witness)))
+		for _, w := range tx.Witness {
+			writeBytes(&buf, w.Signature)
+			writeBytes(&buf, w.PubKey)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// writeUint32 写入4字节大端序整数
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+// writeInt64 写入8字节大端序整数
+func writeInt64(buf *bytes.Buffer, v int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(v))
+	buf.Write(b[:])
+}
+
+// writeBytes 写入长度前缀与字节数据
+func writeBytes(buf *bytes.Buffer, data []byte) {
+	writeUint32(buf, uint32(len(data)))
+	buf.Write(data)
+}
+
+// writeBool 写入一个字节的布尔值
+func writeBool(buf *bytes.Buffer, v bool) {
+	if v {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+}
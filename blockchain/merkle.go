@@ -3,6 +3,7 @@ package blockchain
 import (
 	"bytes"
 	"crypto/sha256"
+	"errors"
 	"go.uber.org/zap"
 	"sort"
 )
@@ -17,6 +18,12 @@ type MerkleNode struct {
 	Data  []byte
 }
 
+// MerkleProof 默克尔证明，记录叶节点到根节点路径上的兄弟节点
+type MerkleProof struct {
+	Siblings [][]byte // 自底向上的兄弟节点哈希
+	IsLeft   []bool   // 兄弟节点是否位于左侧
+}
+
 // NewMerkleNode 构建新的MerkleTree Node
 func NewMerkleNode(left, right *MerkleNode, data []byte) *MerkleNode {
 	node := MerkleNode{}
@@ -78,3 +85,69 @@ func NewMerkleTree(data [][]byte) *MerkleTree {
 
 	return &tree
 }
+
+// NewMerkleProof 为指定的叶数据生成默克尔证明
+func NewMerkleProof(data [][]byte, target []byte) (*MerkleProof, error) {
+	// 与NewMerkleTree保持相同的排序规则，但不修改调用方的切片
+	leaves := make([][]byte, len(data))
+	copy(leaves, data)
+	sort.Slice(leaves, func(i, j int) bool {
+		return bytes.Compare(leaves[i], leaves[j]) < 0
+	})
+
+	index := -1
+	var level [][]byte
+	for i, leaf := range leaves {
+		if index < 0 && bytes.Equal(leaf, target) {
+			index = i
+		}
+		hash := sha256.Sum256(leaf)
+		level = append(level, hash[:])
+	}
+	if index < 0 {
+		return nil, errors.New("target is not in merkle tree")
+	}
+
+	// 逐层向上记录兄弟节点，直至根节点
+	proof := &MerkleProof{}
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+
+		sibling := index ^ 1
+		proof.Siblings = append(proof.Siblings, level[sibling])
+		proof.IsLeft = append(proof.IsLeft, sibling < index)
+
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			hash := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+			next = append(next, hash[:])
+		}
+
+		level = next
+		index /= 2
+	}
+
+	return proof, nil
+}
+
+// Verify 根据叶数据和证明路径重新计算根节点，并与给定的默克尔根比较
+func (p *MerkleProof) Verify(leaf, root []byte) bool {
+	if len(p.Siblings) != len(p.IsLeft) {
+		return false
+	}
+
+	hash := sha256.Sum256(leaf)
+	current := hash[:]
+	for i, sibling := range p.Siblings {
+		if p.IsLeft[i] {
+			hash = sha256.Sum256(append(append([]byte{}, sibling...), current...))
+		} else {
+			hash = sha256.Sum256(append(append([]byte{}, current...), sibling...))
+		}
+		current = hash[:]
+	}
+
+	return bytes.Equal(current, root)
+}
@@ -0,0 +1,57 @@
+package blockchain
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestMerkleProof(t *testing.T) {
+	// 奇数个叶节点时最后一个节点被复制，偶数时不复制
+	for _, count := range []int{1, 2, 3, 4, 5, 8} {
+		var data [][]byte
+		for i := 0; i < count; i++ {
+			data = append(data, []byte(fmt.Sprintf("tx-%d", i)))
+		}
+		root := NewMerkleTree(append([][]byte{}, data...)).MerkleRoot.Data
+
+		for _, leaf := range data {
+			proof, err := NewMerkleProof(data, leaf)
+			if err != nil {
+				t.Fatalf("NewMerkleProof error: %d 个叶节点: %v", count, err)
+			}
+			if !proof.Verify(leaf, root) {
+				t.Errorf("Verify error: %d 个叶节点时 %s 的证明验证失败", count, leaf)
+			}
+		}
+	}
+}
+
+func TestMerkleProofTampered(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	root := NewMerkleTree(append([][]byte{}, data...)).MerkleRoot.Data
+
+	proof, err := NewMerkleProof(data, []byte("b"))
+	if err != nil {
+		t.Fatalf("NewMerkleProof error: %v", err)
+	}
+
+	if proof.Verify([]byte("d"), root) {
+		t.Error("Verify error: 不在树中的叶数据通过了验证")
+	}
+
+	proof.Siblings[0] = bytes.Repeat([]byte{0}, len(proof.Siblings[0]))
+	if proof.Verify([]byte("b"), root) {
+		t.Error("Verify error: 篡改兄弟节点后的证明通过了验证")
+	}
+
+	proof, _ = NewMerkleProof(data, []byte("b"))
+	proof.IsLeft[0] = !proof.IsLeft[0]
+	if proof.Verify([]byte("b"), root) {
+		t.Error("Verify error: 篡改兄弟节点位置后的证明通过了验证")
+	}
+
+	if _, err := NewMerkleProof(data, []byte("d")); err == nil {
+		t.Error("NewMerkleProof error: 不在树中的叶数据应返回错误")
+	}
+}
@@ -0,0 +1,205 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Payment 一笔付款：收款地址与金额
+type Payment struct {
+	Address string `json:"address"`
+	Amount  int    `json:"amount"`
+}
+
+// ValidatePayments 校验付款列表，拒绝空列表、无效地址、重复地址以及非正金额
+// 在选币与签名之前调用，保证列表中任何一项有误时都不会生成交易
+func ValidatePayments(payments []Payment) error {
+	if len(payments) == 0 {
+		return errors.New("no payments given")
+	}
+
+	seen := make(map[string]int)
+	for i, p := range payments {
+		// 同一地址的Base58Check与Bech32形式视为重复
+		normalized, err := wallet.NormalizeAddress(p.Address)
+		if err != nil {
+			return fmt.Errorf("payment %d: %w", i+1, err)
+		}
+		if first, ok := seen[normalized]; ok {
+			return fmt.Errorf("payment %d: duplicate address %s (first used in payment %d)", i+1, p.Address, first)
+		}
+		if p.Amount <= 0 {
+			return fmt.Errorf("payment %d: amount must be positive", i+1)
+		}
+		seen[normalized] = i + 1
+	}
+
+	return nil
+}
+
+// TotalAmount 付款列表的总金额
+func TotalAmount(payments []Payment) int {
+	total := 0
+	for _, p := range payments {
+		total += p.Amount
+	}
+
+	return total
+}
+
+// ReadPaymentsFile 读取付款文件，按扩展名区分JSON与CSV，其他扩展名根据内容判断
+func ReadPaymentsFile(path string) ([]Payment, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ParsePaymentsJSON(data)
+	case ".csv":
+		return ParsePaymentsCSV(bytes.NewReader(data))
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return ParsePaymentsJSON(data)
+	}
+
+	return ParsePaymentsCSV(bytes.NewReader(data))
+}
+
+// ParsePaymentsJSON 解析JSON格式的付款列表，支持两种写法：
+// [{"address": "1...", "amount": 10}, ...] 或 {"1...": 10, ...}
+// 对象写法按文件中的顺序输出，重复的键不会被静默覆盖
+func ParsePaymentsJSON(data []byte) ([]Payment, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var payments []Payment
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&payments); err != nil {
+			return nil, fmt.Errorf("parse payments: %w", err)
+		}
+
+		return payments, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.New("parse payments: expected a JSON array or object")
+	}
+
+	var payments []Payment
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("parse payments: %w", err)
+		}
+
+		var amount int
+		if err := decoder.Decode(&amount); err != nil {
+			return nil, fmt.Errorf("parse payments: amount of %v: %w", token, err)
+		}
+		payments = append(payments, Payment{token.(string), amount})
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("parse payments: %w", err)
+	}
+
+	return payments, nil
+}
+
+// ParsePaymentsCSV 解析CSV格式的付款列表，每行为 地址,金额
+// 第一行金额不是整数时视为表头并跳过，空行与 # 开头的行被忽略
+func ParsePaymentsCSV(r io.Reader) ([]Payment, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var payments []Payment
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse payments: %w", err)
+		}
+
+		amount, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			row, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("parse payments: line %d: invalid amount %q", row, record[1])
+		}
+		payments = append(payments, Payment{strings.TrimSpace(record[0]), amount})
+	}
+
+	return payments, nil
+}
+
+// NewMultiOutputTransaction 创建向多个地址付款的交易，所有付款共用一次选币与一个找零输出
+func NewMultiOutputTransaction(w *wallet.Wallet, payments []Payment, UTXO *UTXOSet, cc *CoinControl) (*Transaction, error) {
+	from := fmt.Sprintf("%s", w.GenerateAddress())
+
+	tx, err := NewUnsignedMultiOutputTransaction(from, w.PublicKey, payments, UTXO, cc)
+	if err != nil {
+		return nil, err
+	}
+
+	// 对交易进行签名，将签名信息保存在见证结构中；被花费的输出可能来自未打包的交易
+	keys := map[string]ecdsa.PrivateKey{hex.EncodeToString(wallet.PublicKeyHash(w.PublicKey)): w.PrivateKey}
+	if err := UTXO.SignTransactionWithKeys(tx, keys); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// NewUnsignedMultiOutputTransaction 构造向多个地址付款的未签名交易
+func NewUnsignedMultiOutputTransaction(from string, pubKey []byte, payments []Payment, UTXO *UTXOSet, cc *CoinControl) (*Transaction, error) {
+	if err := ValidatePayments(payments); err != nil {
+		return nil, err
+	}
+
+	// 获取交易发送方公钥哈希
+	pubKeyHash := NewTXOutput(0, from).PubKeyHash
+	// 按选币策略从发送方的UTXO中选出需要花费的部分
+	selection, err := cc.SelectCoins(UTXO.FindAllSpendable(pubKeyHash), TotalAmount(payments), len(payments))
+	if err != nil {
+		return nil, err
+	}
+
+	// 将选中的UTXO用于构造输入结构
+	inputs := toTxInputs(selection.Inputs, cc != nil && cc.Replaceable)
+
+	// 按付款列表的顺序构造输出，最后是找零
+	outputs := make([]TxOutput, 0, len(payments)+1)
+	for _, p := range payments {
+		outputs = append(outputs, *NewTXOutput(p.Amount, p.Address))
+	}
+	if selection.Change > 0 {
+		outputs = append(outputs, *NewTXOutput(selection.Change, from))
+	}
+
+	// 组装交易结构体
+	tx := Transaction{nil, inputs, outputs, newWitness(len(inputs), pubKey)}
+	tx.ID = tx.Hash()
+
+	return &tx, nil
+}
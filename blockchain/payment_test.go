@@ -0,0 +1,65 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePayments(t *testing.T) {
+	a := string(wallet.NewWallet().GenerateAddress())
+	b := string(wallet.NewSchnorrWallet().GenerateAddress())
+	expected := []Payment{{a, 10}, {b, 25}}
+
+	inputs := map[string]func() ([]Payment, error){
+		"json array": func() ([]Payment, error) {
+			return ParsePaymentsJSON([]byte(fmt.Sprintf(`[{"address":"%s","amount":10},{"address":"%s","amount":25}]`, a, b)))
+		},
+		"json object": func() ([]Payment, error) {
+			return ParsePaymentsJSON([]byte(fmt.Sprintf(`{"%s": 10, "%s": 25}`, a, b)))
+		},
+		"csv": func() ([]Payment, error) {
+			return ParsePaymentsCSV(strings.NewReader(fmt.Sprintf("address,amount\n# payroll\n%s, 10\n\n%s,25\n", a, b)))
+		},
+	}
+	for name, parse := range inputs {
+		payments, err := parse()
+		if err != nil {
+			t.Errorf("%s: 解析失败 %v", name, err)
+			continue
+		}
+		if fmt.Sprint(payments) != fmt.Sprint(expected) {
+			t.Errorf("%s: 期望 %v，实际 %v", name, expected, payments)
+		}
+		if err := ValidatePayments(payments); err != nil {
+			t.Errorf("%s: 校验失败 %v", name, err)
+		}
+	}
+
+	if _, err := ParsePaymentsCSV(strings.NewReader(fmt.Sprintf("%s,10\n%s,ten\n", a, b))); err == nil {
+		t.Error("ParsePaymentsCSV error: 金额非法时应返回错误")
+	}
+}
+
+func TestValidatePayments(t *testing.T) {
+	a := string(wallet.NewWallet().GenerateAddress())
+	b := string(wallet.NewWallet().GenerateAddress())
+
+	invalid := [][]Payment{
+		nil,
+		{{a, 1}, {a, 2}},
+		{{a, 1}, {"not-an-address", 2}},
+		{{a, 1}, {a[:len(a)-1] + "x", 2}},
+		{{a, 1}, {b, 0}},
+	}
+	for _, payments := range invalid {
+		if err := ValidatePayments(payments); err == nil {
+			t.Errorf("ValidatePayments error: %v 应校验失败", payments)
+		}
+	}
+
+	if total := TotalAmount([]Payment{{a, 3}, {b, 4}}); total != 7 {
+		t.Errorf("TotalAmount error: 期望 7，实际 %d", total)
+	}
+}
@@ -24,8 +24,9 @@ func NewProof(b *Block) *ProofOfWork {
 	target := big.NewInt(1)
 	target.Lsh(target, uint(256-Difficulty))
 
-	//计算merkelRoot
+	//计算merkelRoot，以及承诺见证数据的witnessRoot
 	b.MerkleRoot = b.GetMerkleRoot()
+	b.WitnessRoot = b.GetWitnessRoot()
 
 	//返回一个挖矿对象
 	pow := &ProofOfWork{b, target}
@@ -39,6 +40,7 @@ func (pow *ProofOfWork) InitData(nonce int) []byte {
 		[][]byte{
 			pow.Block.PrevBlockHash,    //上一个区块的哈希值
 			pow.Block.MerkleRoot,       //默克尔树根
+			pow.Block.WitnessRoot,      //见证数据默克尔树根
 			ToHex(pow.Block.Timestamp), //区块时间戳
 			ToHex(int64(nonce)),
 			ToHex(int64(Difficulty)),
@@ -0,0 +1,286 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// 部分签名交易序列化数据的前缀
+var psbtMagic = []byte("psbt\xff")
+
+// 输入引用的输出序号上限，防止畸形数据在验证时分配过多内存
+const maxOutputIndex = 1 << 16
+
+// PSBT 部分签名交易，携带未签名的交易、被花费的输出与各方的部分签名
+// 签名只需要交易与被花费的输出，不需要UTXO数据库，因此可以在离线的机器上完成
+type PSBT struct {
+	Tx     Transaction // 未签名的交易，见证数据为空
+	Inputs []PSBTInput // 与交易输入一一对应
+}
+
+// PSBTInput 部分签名交易中的输入信息
+// 不带ANYONECANPAY的签名承诺了全部输入的PrevOut，任一输入伪造的金额或公钥哈希都会使签名无效，
+// 因此签名者看到的手续费是可信的；带ANYONECANPAY的签名只承诺自己的输入，其他输入的金额不可信
+type PSBTInput struct {
+	PrevOut     TxOutput          // 被花费的输出
+	PartialSigs map[string][]byte // 十六进制公钥到签名的映射，签名末尾为签名哈希类型
+}
+
+// NewPSBT 由未签名的交易及其引用的输出创建部分签名交易，交易中已有的见证数据会被丢弃
+func NewPSBT(tx *Transaction, prevOuts []TxOutput) (*PSBT, error) {
+	if tx.IsCoinbaseTx() {
+		return nil, errors.New("coinbase transactions cannot be signed")
+	}
+	if len(prevOuts) != len(tx.Inputs) {
+		return nil, fmt.Errorf("expected %d previous outputs, got %d", len(tx.Inputs), len(prevOuts))
+	}
+
+	unsigned := Transaction{tx.ID, tx.Inputs, tx.Outputs, nil}
+	if err := unsigned.checkUnsigned(); err != nil {
+		return nil, err
+	}
+
+	inputs := make([]PSBTInput, len(prevOuts))
+	for i, prevOut := range prevOuts {
+		inputs[i] = PSBTInput{prevOut, make(map[string][]byte)}
+	}
+
+	return &PSBT{unsigned, inputs}, nil
+}
+
+// CreatePSBT 从区块链中查找交易引用的输出，创建部分签名交易
+func (bc *BlockChain) CreatePSBT(tx *Transaction) (*PSBT, error) {
+	prevOuts := make([]TxOutput, len(tx.Inputs))
+	for i, in := range tx.Inputs {
+		prevTX, err := bc.FindTransaction(in.ID)
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		if in.Out < 0 || in.Out >= len(prevTX.Outputs) {
+			return nil, fmt.Errorf("input %d: output %d does not exist", i, in.Out)
+		}
+		prevOuts[i] = prevTX.Outputs[in.Out]
+	}
+
+	return NewPSBT(tx, prevOuts)
+}
+
+// Sign 使用私钥签署所有被花费输出锁定在该公钥上的输入，返回签署的输入数量
+func (p *PSBT) Sign(privKey ecdsa.PrivateKey, pubKey []byte, hashType SigHashType) (int, error) {
+	if !hashType.IsValid() {
+		return 0, fmt.Errorf("invalid sighash type %s", hashType)
+	}
+
+	pubKeyHash := wallet.PublicKeyHash(pubKey)
+	signer := p.Tx
+	prevOuts := p.prevOuts()
+
+	signed := 0
+	for i := range p.Inputs {
+		input := &p.Inputs[i]
+		if !input.PrevOut.PubKeyHashEquals(pubKeyHash) {
+			continue
+		}
+
+		if err := signer.SignInput(i, privKey, prevOuts, hashType); err != nil {
+			return signed, fmt.Errorf("input %d: %w", i, err)
+		}
+		input.PartialSigs[hex.EncodeToString(pubKey)] = signer.Witness[i].Signature
+		signed++
+	}
+
+	return signed, nil
+}
+
+// SignWithWallets 使用钱包中所有可签名的地址签署部分签名交易，返回签署的输入数量
+func (p *PSBT) SignWithWallets(ws *wallet.Wallets, hashType SigHashType) (int, error) {
+	if ws.IsLocked() {
+		return 0, wallet.ErrWalletLocked
+	}
+
+	signed := 0
+	for _, w := range ws.Wallets {
+		n, err := p.Sign(w.PrivateKey, w.PublicKey, hashType)
+		if err != nil {
+			return signed, err
+		}
+		signed += n
+	}
+
+	return signed, nil
+}
+
+// Combine 合并另一份相同交易的部分签名
+func (p *PSBT) Combine(other *PSBT) error {
+	if !bytes.Equal(p.Tx.ID, other.Tx.ID) || len(p.Inputs) != len(other.Inputs) {
+		return errors.New("cannot combine partially signed transactions of different transactions")
+	}
+
+	for i := range p.Inputs {
+		if !reflect.DeepEqual(p.Inputs[i].PrevOut, other.Inputs[i].PrevOut) {
+			return fmt.Errorf("input %d: previous outputs differ", i)
+		}
+	}
+
+	for i := range p.Inputs {
+		for pubKey, sig := range other.Inputs[i].PartialSigs {
+			p.Inputs[i].PartialSigs[pubKey] = sig
+		}
+	}
+
+	return nil
+}
+
+// IsComplete 是否每个输入都已有可用于花费的签名
+func (p *PSBT) IsComplete() bool {
+	for i := range p.Inputs {
+		if _, _, ok := p.Inputs[i].finalSignature(); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Finalize 将部分签名填入见证结构，生成可以广播的完整交易，并验证所有签名
+func (p *PSBT) Finalize() (*Transaction, error) {
+	tx := Transaction{p.Tx.ID, p.Tx.Inputs, p.Tx.Outputs, make([]TxWitness, len(p.Inputs))}
+
+	for i := range p.Inputs {
+		pubKey, sig, ok := p.Inputs[i].finalSignature()
+		if !ok {
+			return nil, fmt.Errorf("input %d is not signed", i)
+		}
+		tx.Witness[i] = TxWitness{sig, pubKey}
+	}
+
+	// 输出金额之和超过输入金额之和的交易会凭空增发货币
+	if fee := p.Fee(); fee < 0 {
+		return nil, fmt.Errorf("outputs exceed inputs by %d", -fee)
+	}
+	if !tx.Verify(p.prevTransactions()) {
+		return nil, errors.New("finalized transaction has invalid signatures")
+	}
+
+	return &tx, nil
+}
+
+// Fee 交易手续费：被花费输出的金额之和减去输出金额之和
+func (p *PSBT) Fee() int {
+	fee := 0
+	for _, input := range p.Inputs {
+		fee += input.PrevOut.Value
+	}
+	for _, out := range p.Tx.Outputs {
+		fee -= out.Value
+	}
+
+	return fee
+}
+
+// Serialize 序列化部分签名交易
+func (p *PSBT) Serialize() []byte {
+	var encoded bytes.Buffer
+	encoded.Write(psbtMagic)
+
+	if err := gob.NewEncoder(&encoded).Encode(p); err != nil {
+		return nil
+	}
+
+	return encoded.Bytes()
+}
+
+// String 部分签名交易的Base64文本形式，便于在机器之间复制传递
+func (p *PSBT) String() string {
+	return base64.StdEncoding.EncodeToString(p.Serialize())
+}
+
+// DeserializePSBT 反序列化部分签名交易，并检查其与交易的一致性
+func DeserializePSBT(data []byte) (*PSBT, error) {
+	if !bytes.HasPrefix(data, psbtMagic) {
+		return nil, errors.New("not a partially signed transaction")
+	}
+
+	var p PSBT
+	if err := gob.NewDecoder(bytes.NewReader(data[len(psbtMagic):])).Decode(&p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Inputs) != len(p.Tx.Inputs) {
+		return nil, errors.New("malformed partially signed transaction")
+	}
+	if err := p.Tx.checkUnsigned(); err != nil {
+		return nil, err
+	}
+	for i := range p.Inputs {
+		if p.Inputs[i].PartialSigs == nil {
+			p.Inputs[i].PartialSigs = make(map[string][]byte)
+		}
+	}
+
+	return &p, nil
+}
+
+// DecodePSBT 解析Base64文本形式的部分签名交易
+func DecodePSBT(s string) (*PSBT, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return DeserializePSBT(data)
+}
+
+// checkUnsigned 检查交易ID与内容一致，且输入引用的交易与输出序号合法
+func (tx *Transaction) checkUnsigned() error {
+	if !bytes.Equal(tx.ID, tx.Hash()) {
+		return errors.New("transaction id does not match its contents")
+	}
+	for i, in := range tx.Inputs {
+		if len(in.ID) == 0 {
+			return fmt.Errorf("input %d: missing previous transaction id", i)
+		}
+		if in.Out < 0 || in.Out >= maxOutputIndex {
+			return fmt.Errorf("input %d: invalid output index %d", i, in.Out)
+		}
+	}
+
+	return nil
+}
+
+// finalSignature 找到公钥与被花费输出匹配的签名
+func (in *PSBTInput) finalSignature() ([]byte, []byte, bool) {
+	for pubKeyHex, sig := range in.PartialSigs {
+		pubKey, err := hex.DecodeString(pubKeyHex)
+		if err != nil || len(sig) == 0 {
+			continue
+		}
+		if in.PrevOut.PubKeyHashEquals(wallet.PublicKeyHash(pubKey)) {
+			return pubKey, sig, true
+		}
+	}
+
+	return nil, nil, false
+}
+
+// prevOuts 部分签名交易中携带的被花费输出，按输入顺序排列
+func (p *PSBT) prevOuts() []TxOutput {
+	prevOuts := make([]TxOutput, len(p.Inputs))
+	for i, input := range p.Inputs {
+		prevOuts[i] = input.PrevOut
+	}
+
+	return prevOuts
+}
+
+// prevTransactions 由部分签名交易中携带的输出构造验证所需的前序交易
+func (p *PSBT) prevTransactions() map[string]Transaction {
+	return prevTransactions(p.Tx.Inputs, p.prevOuts())
+}
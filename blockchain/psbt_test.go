@@ -0,0 +1,101 @@
+package blockchain
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestPSBTMultiPartySigning(t *testing.T) {
+	alice, bob, tx, prevTXs := newSigHashFixture()
+	prevOuts := []TxOutput{
+		prevTXs[hex.EncodeToString(tx.Inputs[0].ID)].Outputs[0],
+		prevTXs[hex.EncodeToString(tx.Inputs[1].ID)].Outputs[0],
+	}
+
+	p, err := NewPSBT(&tx, prevOuts)
+	if err != nil {
+		t.Fatalf("NewPSBT error: %v", err)
+	}
+
+	// 双方各自对文本形式的副本签名，只签署属于自己的输入
+	aliceCopy, err := DecodePSBT(p.String())
+	if err != nil {
+		t.Fatalf("DecodePSBT error: %v", err)
+	}
+	bobCopy, _ := DecodePSBT(p.String())
+	if n, err := aliceCopy.Sign(alice.PrivateKey, alice.PublicKey, SigHashAll); n != 1 || err != nil {
+		t.Errorf("Sign error: 期望签署1个输入，实际 %d %v", n, err)
+	}
+	if n, err := bobCopy.Sign(bob.PrivateKey, bob.PublicKey, SigHashAll); n != 1 || err != nil {
+		t.Errorf("Sign error: 期望签署1个输入，实际 %d %v", n, err)
+	}
+
+	if aliceCopy.IsComplete() {
+		t.Error("IsComplete error: 只有一方签名时不应完成")
+	}
+	if _, err := aliceCopy.Finalize(); err == nil {
+		t.Error("Finalize error: 缺少签名时应返回错误")
+	}
+
+	// 合并后可以生成完整的交易
+	if err := aliceCopy.Combine(bobCopy); err != nil {
+		t.Fatalf("Combine error: %v", err)
+	}
+	final, err := aliceCopy.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize error: %v", err)
+	}
+	if !final.Verify(prevTXs) {
+		t.Error("Finalize error: 生成的交易验证失败")
+	}
+	if aliceCopy.Fee() != 21 {
+		t.Errorf("Fee error: 期望 21，实际 %d", aliceCopy.Fee())
+	}
+
+	// 不同交易的部分签名不能合并
+	other := tx
+	other.Outputs = []TxOutput{tx.Outputs[0]}
+	other.ID = other.Hash()
+	otherPSBT, _ := NewPSBT(&other, prevOuts)
+	if err := aliceCopy.Combine(otherPSBT); err == nil {
+		t.Error("Combine error: 不同交易的部分签名不应合并")
+	}
+
+	// 伪造被花费输出的金额后签名失效
+	forged, _ := DecodePSBT(aliceCopy.String())
+	forged.Inputs[0].PrevOut.Value++
+	if _, err := forged.Finalize(); err == nil {
+		t.Error("Finalize error: 被花费输出被篡改时签名应失效")
+	}
+
+	// 另一方虚报自己输入的金额，让签名者误以为手续费较低：签名者的签名承诺了虚报的金额，在真实交易中无效
+	lying, _ := NewPSBT(&tx, prevOuts)
+	lying.Inputs[1].PrevOut.Value += 20
+	if n, _ := lying.Sign(alice.PrivateKey, alice.PublicKey, SigHashAll); n != 1 || lying.Fee() != 41 {
+		t.Fatalf("Sign error: 签署 %d 个输入，手续费 %d", n, lying.Fee())
+	}
+	honest, _ := NewPSBT(&tx, prevOuts)
+	honest.Inputs[0].PartialSigs = lying.Inputs[0].PartialSigs
+	honest.Sign(bob.PrivateKey, bob.PublicKey, SigHashAll)
+	if _, err := honest.Finalize(); err == nil {
+		t.Error("Finalize error: 其他输入的金额被虚报时签名应失效")
+	}
+
+	// 输出金额之和超过输入金额之和时手续费为负，即使签名完整也不能生成交易
+	inflated := tx
+	inflated.Outputs = []TxOutput{tx.Outputs[0], tx.Outputs[1], tx.Outputs[0], tx.Outputs[0]}
+	inflated.ID = inflated.Hash()
+	inflatedPSBT, _ := NewPSBT(&inflated, prevOuts)
+	inflatedPSBT.Sign(alice.PrivateKey, alice.PublicKey, SigHashAll)
+	inflatedPSBT.Sign(bob.PrivateKey, bob.PublicKey, SigHashAll)
+	if !inflatedPSBT.IsComplete() || inflatedPSBT.Fee() >= 0 {
+		t.Fatalf("Sign error: 期望签名完整且手续费为负，实际 %d", inflatedPSBT.Fee())
+	}
+	if _, err := inflatedPSBT.Finalize(); err == nil {
+		t.Error("Finalize error: 手续费为负时应返回错误")
+	}
+
+	if _, err := DecodePSBT(hex.EncodeToString(tx.Serialize())); err == nil {
+		t.Error("DecodePSBT error: 非部分签名交易数据应解析失败")
+	}
+}
@@ -0,0 +1,138 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotReplaceable = errors.New("transaction does not signal replaceability")
+	ErrNoChangeOutput = errors.New("transaction has no change output to pay a higher fee")
+)
+
+// SignalsReplacement 交易是否允许被替换，任一输入发出可替换信号即可
+func (tx *Transaction) SignalsReplacement() bool {
+	for _, in := range tx.Inputs {
+		if in.Replaceable {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BumpFeeTransaction 构造手续费为newFee的替换交易：花费相同的输入，增加的手续费从钱包的找零输出中扣除
+// prevOuts为原交易各输入花费的输出（按输入顺序），新交易使用钱包私钥重新签名
+func BumpFeeTransaction(ws *wallet.Wallets, orig *Transaction, prevOuts []TxOutput, newFee int) (*Transaction, error) {
+	if !orig.SignalsReplacement() {
+		return nil, ErrNotReplaceable
+	}
+	if len(prevOuts) != len(orig.Inputs) {
+		return nil, fmt.Errorf("expected %d previous outputs, got %d", len(orig.Inputs), len(prevOuts))
+	}
+
+	oldFee := 0
+	for _, prevOut := range prevOuts {
+		oldFee += prevOut.Value
+	}
+	for _, out := range orig.Outputs {
+		oldFee -= out.Value
+	}
+	if newFee <= oldFee {
+		return nil, fmt.Errorf("new fee %d must be higher than the current fee %d", newFee, oldFee)
+	}
+
+	changeIdx := changeOutput(ws, orig)
+	if changeIdx < 0 {
+		return nil, ErrNoChangeOutput
+	}
+	delta := newFee - oldFee
+	if orig.Outputs[changeIdx].Value < delta {
+		return nil, fmt.Errorf("change of %d cannot pay an additional fee of %d", orig.Outputs[changeIdx].Value, delta)
+	}
+
+	// 复制输入与输出，找零减少为0时删除找零输出
+	inputs := append([]TxInput{}, orig.Inputs...)
+	var outputs []TxOutput
+	for i, out := range orig.Outputs {
+		if i == changeIdx {
+			out.Value -= delta
+			if out.Value == 0 {
+				continue
+			}
+		}
+		outputs = append(outputs, out)
+	}
+
+	tx := Transaction{nil, inputs, outputs, nil}
+	tx.ID = tx.Hash()
+
+	p, err := NewPSBT(&tx, prevOuts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.SignWithWallets(ws, SigHashAll); err != nil {
+		return nil, err
+	}
+
+	return p.Finalize()
+}
+
+// changeOutput 交易中的找零输出序号，没有时返回-1
+// 优先选择支付给钱包找零地址的输出；没有时（如从指定地址付款）选择最后一个支付给钱包的输出，前提是交易还有其他输出
+func changeOutput(ws *wallet.Wallets, tx *Transaction) int {
+	owned := walletPubKeyHashes(ws)
+
+	last, others := -1, 0
+	for i, out := range tx.Outputs {
+		address, ok := owned[hex.EncodeToString(out.PubKeyHash)]
+		if out.IsDataCarrier() || !ok {
+			others++
+			continue
+		}
+		if ws.GetMeta(address).Purpose == wallet.PurposeChange {
+			return i
+		}
+		last = i
+	}
+
+	if others == 0 {
+		return -1
+	}
+
+	return last
+}
+
+// PendingDescendants 未打包交易中直接或间接花费txID输出的交易，按pending的顺序排列
+// 替换txID时交易池会一并移出这些交易，替换交易须为它们的手续费买单
+func PendingDescendants(pending []*Transaction, txID []byte) []*Transaction {
+	replaced := map[string]bool{hex.EncodeToString(txID): true}
+
+	var descendants []*Transaction
+	for found := true; found; {
+		found = false
+		for _, tx := range pending {
+			id := hex.EncodeToString(tx.ID)
+			if replaced[id] {
+				continue
+			}
+			for _, in := range tx.Inputs {
+				if replaced[hex.EncodeToString(in.ID)] {
+					replaced[id], found = true, true
+					break
+				}
+			}
+		}
+	}
+
+	for _, tx := range pending {
+		if id := hex.EncodeToString(tx.ID); replaced[id] && !bytes.Equal(tx.ID, txID) {
+			descendants = append(descendants, tx)
+		}
+	}
+
+	return descendants
+}
@@ -0,0 +1,77 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"errors"
+	"testing"
+)
+
+func TestBumpFeeTransaction(t *testing.T) {
+	ws := &wallet.Wallets{Wallets: make(map[string]*wallet.Wallet)}
+	mine, _ := ws.NewReceiveAddress(false)
+	change, _ := ws.NewChangeAddress(false)
+	payee := string(wallet.NewWallet().GenerateAddress())
+
+	coinbase := CoinbaseTx(mine, "")
+	prevOuts := []TxOutput{coinbase.Outputs[0]}
+	newOrig := func(replaceable bool) *Transaction {
+		tx := Transaction{nil, []TxInput{{coinbase.ID, 0, nil, replaceable}}, []TxOutput{*NewTXOutput(5, payee), *NewTXOutput(14, change)}, nil}
+		tx.ID = tx.Hash()
+		return &tx
+	}
+
+	// 手续费从1提高到3，找零减少2，重新签名后交易有效且仍可被替换
+	bumped, err := BumpFeeTransaction(ws, newOrig(true), prevOuts, 3)
+	if err != nil {
+		t.Fatalf("BumpFeeTransaction error: %v", err)
+	}
+	if bumped.Outputs[0].Value != 5 || bumped.Outputs[1].Value != 12 || !bumped.SignalsReplacement() {
+		t.Errorf("BumpFeeTransaction error: 输出 %d, %d", bumped.Outputs[0].Value, bumped.Outputs[1].Value)
+	}
+	if !bumped.VerifyPrevOuts(prevOuts) {
+		t.Error("BumpFeeTransaction error: 替换交易签名无效")
+	}
+
+	// 找零正好用完时删除找零输出
+	bumped, err = BumpFeeTransaction(ws, newOrig(true), prevOuts, 15)
+	if err != nil || len(bumped.Outputs) != 1 {
+		t.Errorf("BumpFeeTransaction error: 找零用完后仍有 %d 个输出: %v", len(bumped.Outputs), err)
+	}
+
+	if _, err := BumpFeeTransaction(ws, newOrig(true), prevOuts, 16); err == nil {
+		t.Error("BumpFeeTransaction error: 找零不足时应失败")
+	}
+	if _, err := BumpFeeTransaction(ws, newOrig(true), prevOuts, 1); err == nil {
+		t.Error("BumpFeeTransaction error: 手续费未提高时应失败")
+	}
+	if _, err := BumpFeeTransaction(ws, newOrig(false), prevOuts, 3); !errors.Is(err, ErrNotReplaceable) {
+		t.Errorf("BumpFeeTransaction error: 未发出可替换信号的交易: %v", err)
+	}
+}
+
+func TestPendingDescendants(t *testing.T) {
+	newTx := func(value int, parents ...*Transaction) *Transaction {
+		var inputs []TxInput
+		for _, parent := range parents {
+			inputs = append(inputs, TxInput{parent.ID, 0, nil, true})
+		}
+		tx := Transaction{nil, inputs, []TxOutput{{Value: value}}, nil}
+		tx.ID = tx.Hash()
+		return &tx
+	}
+
+	coinbase := newTx(20)
+	orig := newTx(19, coinbase)
+	other := newTx(18, coinbase)
+	child := newTx(17, orig)
+	grandchild := newTx(15, child, other)
+
+	// 孙交易排在子交易之前时也能找到
+	descendants := PendingDescendants([]*Transaction{grandchild, orig, other, child}, orig.ID)
+	if len(descendants) != 2 || descendants[0] != grandchild || descendants[1] != child {
+		t.Errorf("PendingDescendants error: 找到 %d 笔子孙交易", len(descendants))
+	}
+	if descendants := PendingDescendants([]*Transaction{orig, other}, orig.ID); len(descendants) != 0 {
+		t.Errorf("PendingDescendants error: 没有子孙交易时返回 %d 笔", len(descendants))
+	}
+}
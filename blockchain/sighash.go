@@ -0,0 +1,152 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SigHashType 签名哈希类型，决定签名承诺交易中的哪些输入和输出，附加在每个签名的末尾
+type SigHashType byte
+
+const (
+	SigHashAll          SigHashType = 0x01 // 承诺所有输入和输出
+	SigHashNone         SigHashType = 0x02 // 承诺所有输入，不承诺任何输出
+	SigHashSingle       SigHashType = 0x03 // 承诺所有输入，以及与当前输入同序号的输出
+	SigHashAnyoneCanPay SigHashType = 0x80 // 修饰符：只承诺当前输入
+
+	sigHashMask = 0x1f
+)
+
+// Base 去除ANYONECANPAY修饰符后的基本类型
+func (t SigHashType) Base() SigHashType {
+	return t & sigHashMask
+}
+
+// AnyoneCanPay 是否带有ANYONECANPAY修饰符
+func (t SigHashType) AnyoneCanPay() bool {
+	return t&SigHashAnyoneCanPay != 0
+}
+
+// IsValid 判断签名哈希类型是否合法
+func (t SigHashType) IsValid() bool {
+	if t&^(SigHashAnyoneCanPay|sigHashMask) != 0 {
+		return false
+	}
+
+	base := t.Base()
+	return base == SigHashAll || base == SigHashNone || base == SigHashSingle
+}
+
+// String 签名哈希类型的文本表示，如 ALL、SINGLE|ANYONECANPAY
+func (t SigHashType) String() string {
+	var name string
+	switch t.Base() {
+	case SigHashAll:
+		name = "ALL"
+	case SigHashNone:
+		name = "NONE"
+	case SigHashSingle:
+		name = "SINGLE"
+	default:
+		return fmt.Sprintf("UNKNOWN(0x%02x)", byte(t))
+	}
+
+	if t.AnyoneCanPay() {
+		name += "|ANYONECANPAY"
+	}
+
+	return name
+}
+
+// ParseSigHashType 解析签名哈希类型的文本表示
+func ParseSigHashType(name string) (SigHashType, error) {
+	var t SigHashType
+
+	for _, part := range strings.Split(strings.ToUpper(name), "|") {
+		switch strings.TrimSpace(part) {
+		case "ALL":
+			t |= SigHashAll
+		case "NONE":
+			t |= SigHashNone
+		case "SINGLE":
+			t |= SigHashSingle
+		case "ANYONECANPAY":
+			t |= SigHashAnyoneCanPay
+		default:
+			return 0, fmt.Errorf("unknown sighash type %q", part)
+		}
+	}
+
+	if !t.IsValid() {
+		return 0, fmt.Errorf("invalid sighash type %q", name)
+	}
+
+	return t, nil
+}
+
+// SigHash 计算第inIdx个输入在指定签名哈希类型下的待签名摘要
+// prevOuts为各输入引用的输出（按输入顺序）；不带ANYONECANPAY时签名承诺全部被花费输出的金额、公钥哈希与类型，
+// 离线签名者据此得到的手续费不会被其他输入伪造的金额欺骗；带ANYONECANPAY时只承诺当前输入引用的输出
+func (tx *Transaction) SigHash(inIdx int, prevOuts []TxOutput, hashType SigHashType) ([]byte, error) {
+	if !hashType.IsValid() {
+		return nil, fmt.Errorf("invalid sighash type 0x%02x", byte(hashType))
+	}
+	if inIdx < 0 || inIdx >= len(tx.Inputs) {
+		return nil, errors.New("input index out of range")
+	}
+	if len(prevOuts) != len(tx.Inputs) {
+		return nil, fmt.Errorf("expected %d previous outputs, got %d", len(tx.Inputs), len(prevOuts))
+	}
+	prevOut := prevOuts[inIdx]
+
+	// 获取精简后的交易，交易ID不参与规范编码
+	txCopy := tx.TrimmedCopy()
+	in := txCopy.Inputs[inIdx]
+
+	switch hashType.Base() {
+	case SigHashNone:
+		// 不承诺任何输出，其他人可以任意修改输出
+		txCopy.Outputs = nil
+	case SigHashSingle:
+		// 只承诺同序号的输出，之前的输出置空占位，之后的输出截断
+		if inIdx >= len(txCopy.Outputs) {
+			return nil, errors.New("SIGHASH_SINGLE without matching output")
+		}
+		txCopy.Outputs = txCopy.Outputs[:inIdx+1]
+		for i := 0; i < inIdx; i++ {
+			txCopy.Outputs[i] = TxOutput{-1, nil, nil, OutputECDSA}
+		}
+	}
+
+	// ANYONECANPAY只承诺当前输入，其他人可以追加输入
+	if hashType.AnyoneCanPay() {
+		txCopy.Inputs = []TxInput{in}
+	}
+
+	// 签名原像：精简交易的规范编码 + 全部被花费的输出（ANYONECANPAY时省略）
+	// + 当前输入引用的输出位置 + 被花费输出的金额、锁定公钥哈希与类型 + 签名哈希类型
+	var preimage bytes.Buffer
+	preimage.Write(txCopy.encode(false))
+	if !hashType.AnyoneCanPay() {
+		writeInt64(&preimage, int64(len(prevOuts)))
+		for _, out := range prevOuts {
+			writeInt64(&preimage, int64(out.Value))
+			writeBytes(&preimage, out.PubKeyHash)
+			preimage.WriteByte(out.Type)
+		}
+	}
+	writeBytes(&preimage, in.ID)
+	writeInt64(&preimage, int64(in.Out))
+	writeInt64(&preimage, int64(prevOut.Value))
+	writeBytes(&preimage, prevOut.PubKeyHash)
+	preimage.WriteByte(prevOut.Type)
+	preimage.WriteByte(byte(hashType))
+
+	first := sha256.Sum256(preimage.Bytes())
+	hash := sha256.Sum256(first[:])
+
+	return hash[:], nil
+}
@@ -0,0 +1,211 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"testing"
+)
+
+// newSigHashFixture 构造两笔前序交易及一笔花费它们的交易
+func newSigHashFixture() (*wallet.Wallet, *wallet.Wallet, Transaction, map[string]Transaction) {
+	alice := wallet.NewWallet()
+	bob := wallet.NewWallet()
+	aliceAddr := string(alice.GenerateAddress())
+	bobAddr := string(bob.GenerateAddress())
+
+	prevA := CoinbaseTx(aliceAddr, "alice")
+	prevB := CoinbaseTx(bobAddr, "bob")
+	prevTXs := map[string]Transaction{
+		hex.EncodeToString(prevA.ID): *prevA,
+		hex.EncodeToString(prevB.ID): *prevB,
+	}
+
+	tx := Transaction{
+		Inputs:  []TxInput{{prevA.ID, 0, nil, false}, {prevB.ID, 0, nil, false}},
+		Outputs: []TxOutput{*NewTXOutput(12, aliceAddr), *NewTXOutput(7, bobAddr)},
+		Witness: []TxWitness{{nil, alice.PublicKey}, {nil, bob.PublicKey}},
+	}
+	tx.ID = tx.Hash()
+
+	return alice, bob, tx, prevTXs
+}
+
+func TestSigHashTypes(t *testing.T) {
+	alice, bob, tx, prevTXs := newSigHashFixture()
+	prevOuts := []TxOutput{prevTXs[hex.EncodeToString(tx.Inputs[0].ID)].Outputs[0], prevTXs[hex.EncodeToString(tx.Inputs[1].ID)].Outputs[0]}
+
+	// SIGHASH_ALL：修改任意输出都会使签名失效
+	if err := tx.SignInput(0, alice.PrivateKey, prevOuts, SigHashAll); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+	if err := tx.SignInput(1, bob.PrivateKey, prevOuts, SigHashAll); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+	if !tx.Verify(prevTXs) {
+		t.Fatal("SIGHASH_ALL error: 签名验证失败")
+	}
+	tampered := tx
+	tampered.Outputs = append([]TxOutput{}, tx.Outputs...)
+	tampered.Outputs[1].Value = 6
+	tampered.ID = tampered.Hash()
+	if tampered.Verify(prevTXs) {
+		t.Error("SIGHASH_ALL error: 修改输出后签名仍然有效")
+	}
+
+	// SIGHASH_SINGLE：只承诺同序号输出，修改其他输出不影响签名
+	if err := tx.SignInput(0, alice.PrivateKey, prevOuts, SigHashSingle); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+	if err := tx.SignInput(1, bob.PrivateKey, prevOuts, SigHashNone); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+	tampered = tx
+	tampered.Outputs = append([]TxOutput{}, tx.Outputs...)
+	tampered.Outputs[1].Value = 6
+	tampered.ID = tampered.Hash()
+	if !tampered.Verify(prevTXs) {
+		t.Error("SIGHASH_SINGLE/NONE error: 未承诺的输出被修改后签名失效")
+	}
+	tampered.Outputs[0].Value = 11
+	tampered.ID = tampered.Hash()
+	if tampered.Verify(prevTXs) {
+		t.Error("SIGHASH_SINGLE error: 修改已承诺的输出后签名仍然有效")
+	}
+
+	// SIGHASH_ALL|ANYONECANPAY：只承诺当前输入，删除其他输入不影响签名
+	hashType := SigHashAll | SigHashAnyoneCanPay
+	if err := tx.SignInput(0, alice.PrivateKey, prevOuts, hashType); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+	single := tx
+	single.Inputs = tx.Inputs[:1]
+	single.Witness = tx.Witness[:1]
+	single.ID = single.Hash()
+	if !single.Verify(prevTXs) {
+		t.Error("ANYONECANPAY error: 删除其他输入后签名失效")
+	}
+	if got := SigHashType(tx.Witness[0].Signature[len(tx.Witness[0].Signature)-1]); got != hashType {
+		t.Errorf("SignInput error: 签名末尾的类型为 %s，期望 %s", got, hashType)
+	}
+}
+
+func TestParseSigHashType(t *testing.T) {
+	cases := map[string]SigHashType{
+		"ALL":                 SigHashAll,
+		"none":                SigHashNone,
+		"SINGLE|ANYONECANPAY": SigHashSingle | SigHashAnyoneCanPay,
+	}
+	for name, want := range cases {
+		got, err := ParseSigHashType(name)
+		if err != nil || got != want {
+			t.Errorf("ParseSigHashType(%q) = %s, %v，期望 %s", name, got, err, want)
+		}
+	}
+
+	if _, err := ParseSigHashType("ANYONECANPAY"); err == nil {
+		t.Error("ParseSigHashType error: 缺少基本类型时应当报错")
+	}
+}
+
+func TestTxIDExcludesWitness(t *testing.T) {
+	alice, bob, tx, prevTXs := newSigHashFixture()
+	prevOuts := []TxOutput{prevTXs[hex.EncodeToString(tx.Inputs[0].ID)].Outputs[0], prevTXs[hex.EncodeToString(tx.Inputs[1].ID)].Outputs[0]}
+	if err := tx.SignInput(0, alice.PrivateKey, prevOuts, SigHashAll); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+	if err := tx.SignInput(1, bob.PrivateKey, prevOuts, SigHashAll); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+
+	// 修改见证数据只改变wtxid，不改变交易ID
+	txid, wtxid := tx.Hash(), tx.WitnessHash()
+	tx.Witness[0].Signature = append([]byte{0x00}, tx.Witness[0].Signature...)
+	if !bytes.Equal(tx.Hash(), txid) {
+		t.Error("Hash error: 修改签名后交易ID发生变化")
+	}
+	if bytes.Equal(tx.WitnessHash(), wtxid) {
+		t.Error("WitnessHash error: 修改签名后wtxid没有变化")
+	}
+}
+
+func TestTxIDIndependentOfGobState(t *testing.T) {
+	// 节点在计算交易ID之前或之后可能编码过其他类型（如网络消息），交易ID与签名摘要都不能因此改变
+	tx := Transaction{Inputs: []TxInput{{[]byte{1}, 0, nil, false}}, Outputs: []TxOutput{{Value: 1}}}
+	prevOuts := []TxOutput{{Value: 1, PubKeyHash: []byte{2}}}
+	txid := tx.Hash()
+	sighash, err := tx.SigHash(0, prevOuts, SigHashAll)
+	if err != nil {
+		t.Fatalf("SigHash error: %v", err)
+	}
+
+	// 编码此前未出现过的类型，gob会为它们分配新的类型编号
+	var buff bytes.Buffer
+	if err := gob.NewEncoder(&buff).Encode(struct{ AddrFrom string }{"localhost:3001"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := gob.NewEncoder(&buff).Encode(map[string][]TxOutput{"gob": tx.Outputs}); err != nil {
+		t.Fatal(err)
+	}
+
+	if id := hex.EncodeToString(tx.Hash()); id != "21212bd9f20441fb6d46b60a4bd819ca52d997953940ef2b5cd048b6c2608a6c" || !bytes.Equal(tx.Hash(), txid) {
+		t.Errorf("Hash error: 交易ID为 %s", id)
+	}
+	if again, _ := tx.SigHash(0, prevOuts, SigHashAll); !bytes.Equal(again, sighash) {
+		t.Error("SigHash error: 编码其他类型后签名摘要发生变化")
+	}
+}
+
+func TestSchnorrSpend(t *testing.T) {
+	alice := wallet.NewSchnorrWallet()
+	carol, dave := wallet.NewWallet(), wallet.NewWallet()
+	pubKeys := [][]byte{carol.PublicKey, dave.PublicKey}
+	musigAddr, err := wallet.MuSigAddress(pubKeys)
+	if err != nil {
+		t.Fatalf("MuSigAddress error: %v", err)
+	}
+
+	// Schnorr地址与聚合地址锁定的输出都需要Schnorr签名
+	prevA := CoinbaseTx(string(alice.GenerateAddress()), "alice")
+	prevM := CoinbaseTx(musigAddr, "musig")
+	if prevA.Outputs[0].Type != OutputSchnorr || prevM.Outputs[0].Type != OutputSchnorr {
+		t.Fatal("NewTXOutput error: Schnorr地址的输出类型不正确")
+	}
+	prevTXs := map[string]Transaction{
+		hex.EncodeToString(prevA.ID): *prevA,
+		hex.EncodeToString(prevM.ID): *prevM,
+	}
+
+	aggKey, _ := wallet.AggregatePublicKeys(pubKeys)
+	tx := Transaction{
+		Inputs:  []TxInput{{prevA.ID, 0, nil, false}, {prevM.ID, 0, nil, false}},
+		Outputs: []TxOutput{*NewTXOutput(40, string(carol.GenerateAddress()))},
+		Witness: []TxWitness{{nil, alice.PublicKey}, {nil, aggKey}},
+	}
+	tx.ID = tx.Hash()
+
+	prevOuts := []TxOutput{prevA.Outputs[0], prevM.Outputs[0]}
+	if err := tx.SignInput(0, alice.PrivateKey, prevOuts, SigHashAll); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+
+	// 聚合地址的输入由两方共同签名，结果与单密钥签名相同
+	digest, _ := tx.SigHash(1, prevOuts, SigHashAll)
+	sig, err := wallet.MuSigSign([]*wallet.Wallet{carol, dave}, pubKeys, digest)
+	if err != nil {
+		t.Fatalf("MuSigSign error: %v", err)
+	}
+	tx.Witness[1].Signature = append(sig, byte(SigHashAll))
+
+	if !tx.Verify(prevTXs) {
+		t.Fatal("Verify error: Schnorr签名验证失败")
+	}
+
+	// 使用ECDSA签名花费Schnorr输出必须失败
+	ecdsaSig, _ := wallet.Sign(&alice.PrivateKey, digest)
+	tx.Witness[0].Signature = append(ecdsaSig, byte(SigHashAll))
+	if tx.Verify(prevTXs) {
+		t.Error("Verify error: Schnorr输出接受了错误的签名")
+	}
+}
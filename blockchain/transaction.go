@@ -4,49 +4,74 @@ import (
 	"Golang_Bitcoin_Sample/wallet"
 	"bytes"
 	"crypto/ecdsa"
-	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/gob"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"go.uber.org/zap"
 	"log"
-	"math/big"
 )
 
 type Transaction struct {
-	ID      []byte     // 交易ID
-	Inputs  []TxInput  // 输入结构
-	Outputs []TxOutput // 输出结构
+	ID      []byte      // 交易ID，只对非见证数据进行哈希
+	Inputs  []TxInput   // 输入结构
+	Outputs []TxOutput  // 输出结构
+	Witness []TxWitness // 见证数据，与输入结构一一对应
 }
 
 // 输入结构
 type TxInput struct {
-	ID        []byte // 交易哈希
-	Out       int    // 输出索引
+	ID          []byte // 交易哈希
+	Out         int    // 输出索引
+	Coinbase    []byte // 币基交易携带的附加数据
+	Replaceable bool   // 允许交易在打包前被手续费更高的交易替换（BIP125信号）
+}
+
+// 见证结构，签名与公钥不参与交易ID的计算，避免交易ID被篡改
+type TxWitness struct {
 	Signature []byte // 签名
 	PubKey    []byte // 签署人的公钥
 }
 
+// 每个区块的出块奖励
+const Subsidy = 20
+
+// 数据输出（OP_RETURN）允许携带的最大字节数
+const MaxDataCarrierSize = 80
+
+// 输出类型，决定花费该输出时使用的签名算法
+const (
+	OutputECDSA   byte = iota // ECDSA签名（默认）
+	OutputSchnorr             // BIP340 Schnorr签名，见证结构中的公钥为x-only公钥
+)
+
 // 输出结构
 type TxOutput struct {
 	Value      int    // 输出金额
 	PubKeyHash []byte // UTXO持有者的公钥哈希
+	Data       []byte // 数据负载，非空时该输出不可花费，也不会进入UTXO集合
+	Type       byte   // 输出类型，由收款地址的版本号决定
 }
 
 // 输出结构数组
 type TxOutputs struct {
 	Outputs []TxOutput
+	Indexes []int // 各输出在原交易中的索引
 }
 
 // Hash 交易ID获取
 func (tx *Transaction) Hash() []byte {
-	tmpTx := *tx
-	tmpTx.ID = []byte{}
+	//对除见证数据外的规范编码进行哈希计算，结果即为tx的ID，修改签名编码不会改变交易ID
+	hash := sha256.Sum256(tx.encode(false))
 
-	//将整个tx进行哈希计算，结果即为tx的ID
-	hash := sha256.Sum256(tmpTx.Serialize())
+	return hash[:]
+}
+
+// WitnessHash 获取包含见证数据的交易哈希（wtxid）
+func (tx *Transaction) WitnessHash() []byte {
+	hash := sha256.Sum256(tx.encode(true))
 
 	return hash[:]
 }
@@ -77,6 +102,21 @@ func (outs TxOutputs) SerializeOutputs() []byte {
 	return buffer.Bytes()
 }
 
+// Append 向输出结构切片中追加输出，并记录其在原交易中的索引
+func (outs *TxOutputs) Append(out TxOutput, outIdx int) {
+	outs.Outputs = append(outs.Outputs, out)
+	outs.Indexes = append(outs.Indexes, outIdx)
+}
+
+// Index 获取第i个输出在原交易中的索引（兼容未记录索引的旧数据）
+func (outs TxOutputs) Index(i int) int {
+	if i < len(outs.Indexes) {
+		return outs.Indexes[i]
+	}
+
+	return i
+}
+
 // DeserializeOutputs 反序列化输出结构切片
 func DeserializeOutputs(data []byte) TxOutputs {
 	var outputs TxOutputs
@@ -101,52 +141,91 @@ func DeserializeTransaction(data []byte) Transaction {
 	return transaction
 }
 
-// NewTransaction 创建新交易
-func NewTransaction(w *wallet.Wallet, to string, amount int, UTXO *UTXOSet) *Transaction {
-	var inputs []TxInput
-	var outputs []TxOutput
+// NewTransaction 创建新交易，cc为空时使用默认的选币策略且不收取手续费
+func NewTransaction(w *wallet.Wallet, to string, amount int, UTXO *UTXOSet, cc *CoinControl) (*Transaction, error) {
+	from := fmt.Sprintf("%s", w.GenerateAddress())
 
-	// 获取交易发送方公钥哈希
-	pubKeyHash := wallet.PublicKeyHash(w.PublicKey)
-	// 获取花销总额以及涉及的UTXO
-	accumulate, validOutputs := UTXO.FindSpendableOutputs(pubKeyHash, amount)
+	tx, err := NewUnsignedTransaction(from, w.PublicKey, to, amount, UTXO, cc)
+	if err != nil {
+		return nil, err
+	}
+
+	// 对交易进行签名，将签名信息保存在输入结构中
+	UTXO.Blockchain.SignTransaction(tx, w.PrivateKey)
+
+	return tx, nil
+}
+
+// NewUnsignedTransaction 构造未签名的交易，用于只读地址等无法在本节点签名的场景
+// 见证结构只填入发送方公钥（只知道地址时为空），签名由持有私钥的一方完成
+func NewUnsignedTransaction(from string, pubKey []byte, to string, amount int, UTXO *UTXOSet, cc *CoinControl) (*Transaction, error) {
+	return NewUnsignedMultiOutputTransaction(from, pubKey, []Payment{{to, amount}}, UTXO, cc)
+}
+
+// NewDataTransaction 创建携带数据输出的交易，引用的UTXO全部找零给发送方
+func NewDataTransaction(w *wallet.Wallet, data []byte, UTXO *UTXOSet) (*Transaction, error) {
+	dataOut, err := NewDataOutput(data)
+	if err != nil {
+		return nil, err
+	}
 
-	if accumulate < amount {
-		log.Panic("Error: not enough funds")
+	// 数据交易至少需要引用一笔UTXO，才能被签名并获得唯一的交易ID
+	pubKeyHash := wallet.PublicKeyHash(w.PublicKey)
+	accumulate, validOutputs := UTXO.FindSpendableOutputs(pubKeyHash, 1)
+	if accumulate < 1 {
+		return nil, errors.New("not enough funds")
 	}
 
-	// 将涉及的UTXO用于构造输入结构
+	inputs := buildInputs(validOutputs)
+
+	from := fmt.Sprintf("%s", w.GenerateAddress())
+	outputs := []TxOutput{*dataOut, *NewTXOutput(accumulate, from)}
+
+	// 组装交易结构体并签名
+	tx := Transaction{nil, inputs, outputs, newWitness(len(inputs), w.PublicKey)}
+	tx.ID = tx.Hash()
+	UTXO.Blockchain.SignTransaction(&tx, w.PrivateKey)
+
+	return &tx, nil
+}
+
+// buildInputs 将待花费的UTXO转换为输入结构
+func buildInputs(validOutputs map[string][]int) []TxInput {
+	var inputs []TxInput
+
 	for txid, outs := range validOutputs {
 		txID, err := hex.DecodeString(txid)
-		zap.L().Error("hex.DecodeString() failed", zap.Error(err))
+		if err != nil {
+			zap.L().Error("hex.DecodeString() failed", zap.Error(err))
+			continue
+		}
 
 		// out是一笔输出结构中的交易排名次序（从0开始）
 		for _, out := range outs {
-			input := TxInput{txID, out, nil, w.PublicKey}
-			inputs = append(inputs, input)
+			inputs = append(inputs, TxInput{txID, out, nil, false})
 		}
 	}
 
-	from := fmt.Sprintf("%s", w.GenerateAddress())
+	return inputs
+}
 
-	// 构造输出结构（UTXO不能拆分，多余的金额通过一笔新的UTXO发回给自己）
-	outputs = append(outputs, *NewTXOutput(amount, to))
-	if accumulate > amount {
-		outputs = append(outputs, *NewTXOutput(accumulate-amount, from))
+// newWitness 为每个输入构造带有签署人公钥的见证结构，签名在签署时填入
+func newWitness(count int, pubKey []byte) []TxWitness {
+	witness := make([]TxWitness, count)
+	for i := range witness {
+		witness[i].PubKey = pubKey
 	}
 
-	// 组装交易结构体
-	tx := Transaction{nil, inputs, outputs}
-	tx.ID = tx.Hash()
-
-	// 对交易进行签名，将签名信息保存在输入结构中
-	UTXO.Blockchain.SignTransaction(&tx, w.PrivateKey)
-
-	return &tx
+	return witness
 }
 
 // CoinbaseTx 创建CoinBase交易
 func CoinbaseTx(to, data string) *Transaction {
+	return CoinbaseTxWithFees(to, data, 0)
+}
+
+// CoinbaseTxWithFees 创建币基交易，矿工在出块奖励之外领取区块中全部交易的手续费
+func CoinbaseTxWithFees(to, data string, fees int) *Transaction {
 	if data == "" {
 		randData := make([]byte, 24)
 		_, err := rand.Read(randData)
@@ -155,39 +234,85 @@ func CoinbaseTx(to, data string) *Transaction {
 	}
 
 	// Coinbase特征的输入结构
-	txin := TxInput{[]byte{}, -1, nil, []byte(data)}
+	txin := TxInput{[]byte{}, -1, []byte(data), false}
 	//UTXO相关的输出结构
-	txout := NewTXOutput(20, to)
+	txout := NewTXOutput(Subsidy+fees, to)
 
 	// 组装交易结构体
-	tx := Transaction{nil, []TxInput{txin}, []TxOutput{*txout}}
+	tx := Transaction{nil, []TxInput{txin}, []TxOutput{*txout}, nil}
 	tx.ID = tx.Hash()
 
 	return &tx
 }
 
+// fee 交易手续费：被花费输出的金额之和减去输出金额之和，prevTXs需包含所有输入引用的交易
+func (tx *Transaction) fee(prevTXs map[string]Transaction) int {
+	fee := 0
+	for _, in := range tx.Inputs {
+		fee += prevTXs[hex.EncodeToString(in.ID)].Outputs[in.Out].Value
+	}
+	for _, out := range tx.Outputs {
+		fee -= out.Value
+	}
+
+	return fee
+}
+
 // 判断是不是Coinbase交易 IsCoinbaseTx
 func (tx *Transaction) IsCoinbaseTx() bool {
 	// 查看交易的输入结构特征
 	return len(tx.Inputs) == 1 && len(tx.Inputs[0].ID) == 0 && tx.Inputs[0].Out == -1
 }
 
+// SigOpCount 验证交易需要的签名检查次数，每个输入一次，币基交易为0
+func (tx *Transaction) SigOpCount() int {
+	if tx.IsCoinbaseTx() {
+		return 0
+	}
+
+	return len(tx.Inputs)
+}
+
 // NewTXOutput create a new TXOutput
 func NewTXOutput(value int, address string) *TxOutput {
-	txOut := &TxOutput{value, nil}
+	txOut := &TxOutput{value, nil, nil, OutputECDSA}
 	txOut.GetPublicKeyHash([]byte(address))
 
 	return txOut
 }
 
+// NewDataOutput 创建不可花费的数据输出（类似OP_RETURN），金额固定为0
+func NewDataOutput(data []byte) (*TxOutput, error) {
+	if len(data) == 0 {
+		return nil, errors.New("data output is empty")
+	}
+	if len(data) > MaxDataCarrierSize {
+		return nil, fmt.Errorf("data output exceeds %d bytes", MaxDataCarrierSize)
+	}
+
+	return &TxOutput{0, nil, data, OutputECDSA}, nil
+}
+
+// IsDataCarrier 判断输出是否为数据输出
+func (out *TxOutput) IsDataCarrier() bool {
+	return len(out.Data) > 0
+}
+
 func (out *TxOutput) GetPublicKeyHash(address []byte) {
-	//从address反推公钥哈希（除去校验和）
-	pubKeyHash := wallet.Base58Decode(address)
+	//从address反推公钥哈希，支持Base58Check与Bech32地址
+	decoded, err := wallet.DecodeAddress(string(address), wallet.ActiveNetParams)
+	if err != nil {
+		zap.L().Error("wallet.DecodeAddress() failed", zap.Error(err))
+		return
+	}
 
-	pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-4]
+	// Schnorr地址锁定的输出需要使用Schnorr签名花费
+	if decoded.Schnorr {
+		out.Type = OutputSchnorr
+	}
 
 	//输出结构--UTXO持有者公钥哈希赋值
-	out.PubKeyHash = pubKeyHash
+	out.PubKeyHash = decoded.PubKeyHash
 }
 
 // PubKeyHashEquals 判断公钥哈希是否相等
@@ -197,9 +322,16 @@ func (out *TxOutput) PubKeyHashEquals(pubKeyHash []byte) bool {
 
 // Sign 对交易进行签署，并将签名保存在输入结构中
 func (tx *Transaction) Sign(privKey ecdsa.PrivateKey, prevTXs map[string]Transaction) {
+	if err := tx.SignWithHashType(privKey, prevTXs, SigHashAll); err != nil {
+		zap.L().Error("tx.SignWithHashType() failed", zap.Error(err))
+	}
+}
+
+// SignWithHashType 使用指定的签名哈希类型对交易的所有输入进行签署
+func (tx *Transaction) SignWithHashType(privKey ecdsa.PrivateKey, prevTXs map[string]Transaction, hashType SigHashType) error {
 	// 币基交易没有输入结构
 	if tx.IsCoinbaseTx() {
-		return
+		return nil
 	}
 
 	for _, in := range tx.Inputs {
@@ -209,35 +341,91 @@ func (tx *Transaction) Sign(privKey ecdsa.PrivateKey, prevTXs map[string]Transac
 		}
 	}
 
-	// 获取精简后的交易
-	txCopy := tx.TrimmedCopy()
-
 	// 遍历交易中的所有输入结构
-	for inId, in := range txCopy.Inputs {
-		prevTX := prevTXs[hex.EncodeToString(in.ID)]
-		txCopy.Inputs[inId].Signature = nil
-
-		//输入脚本中的公钥，引用的UTXO持有者的公钥哈希作代替
-		//因为输入输出脚本合并执行的过程中，比对的也是公钥哈希后的结果，这里简化了公钥计算哈希值的过程
-		txCopy.Inputs[inId].PubKey = prevTX.Outputs[in.Out].PubKeyHash
+	prevOuts := make([]TxOutput, len(tx.Inputs))
+	for inId, in := range tx.Inputs {
+		prevOuts[inId] = prevTXs[hex.EncodeToString(in.ID)].Outputs[in.Out]
+	}
+	for inId := range tx.Inputs {
+		if err := tx.SignInput(inId, privKey, prevOuts, hashType); err != nil {
+			return err
+		}
+	}
 
-		dataToSign := fmt.Sprintf("%x\n", txCopy)
+	return nil
+}
 
-		// 使用私钥对交易输入信息进行签名
-		r, s, err := ecdsa.Sign(rand.Reader, &privKey, []byte(dataToSign))
-		zap.L().Error("ecdsa.Sign failed()", zap.Error(err))
+// SignInput 对单个输入进行签署，签名末尾附加签名哈希类型，并保存在对应的见证结构中
+// prevOuts为各输入引用的输出（按输入顺序）；配合ANYONECANPAY等类型，多方可以各自签署自己的输入
+func (tx *Transaction) SignInput(inIdx int, privKey ecdsa.PrivateKey, prevOuts []TxOutput, hashType SigHashType) error {
+	dataToSign, err := tx.SigHash(inIdx, prevOuts, hashType)
+	if err != nil {
+		return err
+	}
 
-		// r、s为椭圆曲线中的坐标信息
-		signature := append(r.Bytes(), s.Bytes()...)
+	// 见证结构与输入结构一一对应
+	for len(tx.Witness) < len(tx.Inputs) {
+		tx.Witness = append(tx.Witness, TxWitness{})
+	}
 
-		//对交易原本Tx的签名域进行赋值
-		tx.Inputs[inId].Signature = signature
-		txCopy.Inputs[inId].PubKey = nil
+	// 按被花费输出的类型选择签名算法，两者的签名均为定长的 r||s
+	var signature []byte
+	if prevOuts[inIdx].Type == OutputSchnorr {
+		signature, err = wallet.SignSchnorr(&privKey, dataToSign)
+	} else {
+		signature, err = wallet.Sign(&privKey, dataToSign)
 	}
+	if err != nil {
+		return err
+	}
+
+	//对交易原本Tx的见证结构进行赋值
+	tx.Witness[inIdx].Signature = append(signature, byte(hashType))
+
+	return nil
 }
 
 // Verify 验证交易是否合法
 func (tx *Transaction) Verify(prevTXs map[string]Transaction) bool {
+	batch := &wallet.SchnorrBatch{}
+
+	return tx.verify(prevTXs, batch) && batch.Verify()
+}
+
+// VerifyPrevOuts 使用各输入花费的输出（按输入顺序）验证交易，不需要查找完整的前序交易
+func (tx *Transaction) VerifyPrevOuts(prevOuts []TxOutput) bool {
+	if len(prevOuts) != len(tx.Inputs) {
+		return false
+	}
+	for _, in := range tx.Inputs {
+		if len(in.ID) == 0 || in.Out < 0 {
+			return false
+		}
+	}
+
+	return tx.Verify(prevTransactions(tx.Inputs, prevOuts))
+}
+
+// prevTransactions 由被花费的输出构造验证所需的前序交易，只填充被引用的输出
+func prevTransactions(inputs []TxInput, prevOuts []TxOutput) map[string]Transaction {
+	prevTXs := make(map[string]Transaction)
+
+	for i, in := range inputs {
+		id := hex.EncodeToString(in.ID)
+		prevTX := prevTXs[id]
+		prevTX.ID = in.ID
+		for len(prevTX.Outputs) <= in.Out {
+			prevTX.Outputs = append(prevTX.Outputs, TxOutput{})
+		}
+		prevTX.Outputs[in.Out] = prevOuts[i]
+		prevTXs[id] = prevTX
+	}
+
+	return prevTXs
+}
+
+// verify 验证交易，ECDSA签名立即验证，Schnorr签名加入batch中留待批量验证
+func (tx *Transaction) verify(prevTXs map[string]Transaction, batch *wallet.SchnorrBatch) bool {
 	//币基交易不需要验证UTXO的引用
 	if tx.IsCoinbaseTx() {
 		return true
@@ -250,60 +438,108 @@ func (tx *Transaction) Verify(prevTXs map[string]Transaction) bool {
 		}
 	}
 
-	// 获得精简后的交易，以及用于签名验证的椭圆曲线模型
-	txCopy := tx.TrimmedCopy()
-	curve := elliptic.P256()
+	// 交易ID必须与非见证数据一致，每个输入都需要对应的见证结构
+	if !bytes.Equal(tx.ID, tx.Hash()) || len(tx.Witness) != len(tx.Inputs) {
+		return false
+	}
 
-	for inId, in := range tx.Inputs {
-		// 交易原数据获取
+	// 数据输出只能携带有限的数据，且不能被引用花费
+	for _, out := range tx.Outputs {
+		if out.IsDataCarrier() && (out.Value != 0 || out.PubKeyHash != nil || len(out.Data) > MaxDataCarrierSize) {
+			return false
+		}
+	}
+	for _, in := range tx.Inputs {
 		prevTx := prevTXs[hex.EncodeToString(in.ID)]
-		txCopy.Inputs[inId].Signature = nil
-		txCopy.Inputs[inId].PubKey = prevTx.Outputs[in.Out].PubKeyHash
+		if in.Out < 0 || in.Out >= len(prevTx.Outputs) || prevTx.Outputs[in.Out].IsDataCarrier() {
+			return false
+		}
+	}
+
+	// 输出金额不能为负，输出金额之和不能超过被花费输出的金额之和，同一输出不能被重复引用
+	spent := make(map[string]bool)
+	for _, in := range tx.Inputs {
+		outPoint := fmt.Sprintf("%x:%d", in.ID, in.Out)
+		if spent[outPoint] {
+			return false
+		}
+		spent[outPoint] = true
+	}
+	for _, out := range tx.Outputs {
+		if out.Value < 0 {
+			return false
+		}
+	}
+	if tx.fee(prevTXs) < 0 {
+		return false
+	}
+
+	prevOuts := make([]TxOutput, len(tx.Inputs))
+	for inId, in := range tx.Inputs {
+		prevOuts[inId] = prevTXs[hex.EncodeToString(in.ID)].Outputs[in.Out]
+	}
+	for inId := range tx.Inputs {
+		witness := tx.Witness[inId]
+
+		// 签名末尾的一个字节为签名哈希类型
+		sigLen := len(witness.Signature) - 1
+		if sigLen <= 0 {
+			return false
+		}
+		hashType := SigHashType(witness.Signature[sigLen])
 
-		dataToVerify := fmt.Sprintf("%x\n", txCopy)
+		// 见证结构中的公钥必须与被花费输出锁定的公钥哈希一致
+		prevOut := prevOuts[inId]
+		if !prevOut.PubKeyHashEquals(wallet.PublicKeyHash(witness.PubKey)) {
+			return false
+		}
 
-		// 签名数据坐标化
-		r := big.Int{}
-		s := big.Int{}
+		// 按签名哈希类型重新计算待验证的摘要
+		dataToVerify, err := tx.SigHash(inId, prevOuts, hashType)
+		if err != nil {
+			return false
+		}
 
-		sigLen := len(in.Signature)
-		r.SetBytes(in.Signature[:(sigLen / 2)])
-		s.SetBytes(in.Signature[(sigLen / 2):])
+		// Schnorr签名不在此处验证，与同批次的其他签名一起批量验证
+		if prevOut.Type == OutputSchnorr {
+			if len(witness.PubKey) != wallet.SchnorrPubKeyLen || sigLen != wallet.SchnorrSignatureLen {
+				return false
+			}
+			batch.Add(witness.PubKey, dataToVerify, witness.Signature[:sigLen])
+			continue
+		}
 
-		// 公钥数据坐标化
-		x := big.Int{}
-		y := big.Int{}
-		keyLen := len(in.PubKey)
-		x.SetBytes(in.PubKey[:(keyLen / 2)])
-		y.SetBytes(in.PubKey[(keyLen / 2):])
-		rawPubKey := ecdsa.PublicKey{Curve: curve, X: &x, Y: &y}
+		// 解析公钥：secp256k1压缩公钥或旧的P256公钥
+		rawPubKey, err := wallet.ParsePublicKey(witness.PubKey)
+		if err != nil {
+			return false
+		}
 
-		// 验证交易是否合法
-		if ecdsa.Verify(&rawPubKey, []byte(dataToVerify), &r, &s) == false {
+		// 验证交易是否合法，签名必须为定长编码且为低S值
+		if wallet.VerifySignature(rawPubKey, dataToVerify, witness.Signature[:sigLen]) == false {
 			return false
 		}
-		txCopy.Inputs[inId].PubKey = nil
 	}
 
 	return true
 }
 
-// TrimmedCopy 获取除了输入结构的交易摘要，用于签名
+// TrimmedCopy 获取除了见证数据的交易摘要，用于签名
 func (tx *Transaction) TrimmedCopy() Transaction {
 	var inputs []TxInput
 	var outputs []TxOutput
 
-	// 输出结构剔除签名和公钥信息
+	// 复制输入结构，签名和公钥信息位于见证结构中，不予复制；替换信号需要被签名承诺
 	for _, in := range tx.Inputs {
-		inputs = append(inputs, TxInput{in.ID, in.Out, nil, nil})
+		inputs = append(inputs, TxInput{in.ID, in.Out, in.Coinbase, in.Replaceable})
 	}
 
 	// 获取完整的输出结构
 	for _, out := range tx.Outputs {
-		outputs = append(outputs, TxOutput{out.Value, out.PubKeyHash})
+		outputs = append(outputs, TxOutput{out.Value, out.PubKeyHash, out.Data, out.Type})
 	}
 
-	txCopy := Transaction{tx.ID, inputs, outputs}
+	txCopy := Transaction{tx.ID, inputs, outputs, nil}
 
 	return txCopy
 }
@@ -0,0 +1,86 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestValueConservation(t *testing.T) {
+	w := wallet.NewWallet()
+	address := string(w.GenerateAddress())
+	coinbase := CoinbaseTx(address, "")
+	prevTXs := map[string]Transaction{hex.EncodeToString(coinbase.ID): *coinbase}
+
+	// 按给定的输入与输出金额构造并签名交易
+	newTx := func(inputs []TxInput, values ...int) *Transaction {
+		tx := Transaction{nil, inputs, nil, nil}
+		for _, value := range values {
+			tx.Outputs = append(tx.Outputs, *NewTXOutput(value, address))
+		}
+		tx.ID = tx.Hash()
+		prevOuts := make([]TxOutput, len(tx.Inputs))
+		for i := range prevOuts {
+			prevOuts[i] = coinbase.Outputs[0]
+		}
+		for i := range tx.Inputs {
+			if err := tx.SignInput(i, w.PrivateKey, prevOuts, SigHashAll); err != nil {
+				t.Fatalf("SignInput error: %v", err)
+			}
+			tx.Witness[i].PubKey = w.PublicKey
+		}
+		return &tx
+	}
+	in := []TxInput{{coinbase.ID, 0, nil, false}}
+
+	if !newTx(in, Subsidy-1, 1).Verify(prevTXs) {
+		t.Fatal("Verify error: 输出金额等于输入金额的交易验证失败")
+	}
+	if newTx(in, Subsidy, 1).Verify(prevTXs) {
+		t.Error("Verify error: 输出金额超过输入金额的交易通过了验证")
+	}
+	if newTx(in, Subsidy+5, -5).Verify(prevTXs) {
+		t.Error("Verify error: 包含负金额输出的交易通过了验证")
+	}
+	if newTx(append(in, in[0]), Subsidy*2).Verify(prevTXs) {
+		t.Error("Verify error: 重复引用同一输出的交易通过了验证")
+	}
+}
+
+func TestCoinbaseBound(t *testing.T) {
+	nodeId := "coinbase_test"
+	if err := os.MkdirAll(fmt.Sprintf(dbPath, nodeId), 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./tmp")
+
+	w := wallet.NewWallet()
+	address := string(w.GenerateAddress())
+	chain := InitBlockChain(address, nodeId)
+	defer chain.Database.Close()
+
+	// 花费创世区块的币基输出，支付2个单位的手续费
+	genesis, err := chain.GetBlock(chain.LastHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevTx := genesis.Transactions[0]
+	tx := Transaction{nil, []TxInput{{prevTx.ID, 0, nil, false}}, []TxOutput{*NewTXOutput(prevTx.Outputs[0].Value-2, address)}, nil}
+	tx.ID = tx.Hash()
+	if err := tx.SignInput(0, w.PrivateKey, []TxOutput{prevTx.Outputs[0]}, SigHashAll); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+	tx.Witness[0].PubKey = w.PublicKey
+
+	if !chain.VerifyTransactions([]*Transaction{&tx, CoinbaseTxWithFees(address, "", 2)}) {
+		t.Error("VerifyTransactions error: 领取出块奖励与全部手续费的币基交易验证失败")
+	}
+	if chain.VerifyTransactions([]*Transaction{&tx, CoinbaseTxWithFees(address, "", 3)}) {
+		t.Error("VerifyTransactions error: 币基交易领取的金额超过出块奖励与手续费之和")
+	}
+	if chain.VerifyTransactions([]*Transaction{CoinbaseTxWithFees(address, "", 1)}) {
+		t.Error("VerifyTransactions error: 没有手续费时币基交易领取的金额超过出块奖励")
+	}
+}
@@ -2,7 +2,9 @@ package blockchain
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"encoding/hex"
+	"fmt"
 	"github.com/dgraph-io/badger"
 	"go.uber.org/zap"
 	"log"
@@ -16,6 +18,7 @@ var (
 
 type UTXOSet struct {
 	Blockchain *BlockChain
+	Pending    []*Transaction // 尚未打包的交易：其输出可以继续花费，其花费的输出不再可用
 }
 
 // FindSpendableOutputs 查找可以使用的UTXO
@@ -42,10 +45,10 @@ func (u UTXOSet) FindSpendableOutputs(pubKeyHash []byte, amount int) (int, map[s
 			txID := hex.EncodeToString(k)
 			outs := DeserializeOutputs(v)
 
-			for outIdx, out := range outs.Outputs {
+			for i, out := range outs.Outputs {
 				if out.PubKeyHashEquals(pubKeyHash) && accumulated < amount {
 					accumulated += out.Value
-					unspentOuts[txID] = append(unspentOuts[txID], outIdx)
+					unspentOuts[txID] = append(unspentOuts[txID], outs.Index(i))
 				}
 			}
 		}
@@ -56,6 +59,150 @@ func (u UTXOSet) FindSpendableOutputs(pubKeyHash []byte, amount int) (int, map[s
 	return accumulated, unspentOuts
 }
 
+// FindAllSpendable 获取属于指定公钥哈希的全部UTXO及其输出点，供选币策略使用
+func (u UTXOSet) FindAllSpendable(pubKeyHash []byte) []SpendableOutput {
+	return u.FindSpendableByPubKeyHashes(map[string]bool{hex.EncodeToString(pubKeyHash): true})
+}
+
+// FindSpendableByPubKeyHashes 一次遍历获取属于任一公钥哈希（十六进制）的全部UTXO，用于钱包级选币
+func (u UTXOSet) FindSpendableByPubKeyHashes(pubKeyHashes map[string]bool) []SpendableOutput {
+	var spendable []SpendableOutput
+	db := u.Blockchain.Database
+
+	err := db.View(func(txn *badger.Txn) error {
+		// 初始化数据库迭代器
+		opts := badger.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(utxoPrefix); it.ValidForPrefix(utxoPrefix); it.Next() {
+			item := it.Item()
+			k := item.Key()
+			v, err := item.Value()
+			zap.L().Error("item.Value()", zap.Error(err))
+
+			//除去键值对前缀，获取交易ID
+			txID := hex.EncodeToString(bytes.TrimPrefix(k, utxoPrefix))
+			outs := DeserializeOutputs(v)
+
+			for i, out := range outs.Outputs {
+				if pubKeyHashes[hex.EncodeToString(out.PubKeyHash)] {
+					spendable = append(spendable, SpendableOutput{OutPoint{txID, outs.Index(i)}, out})
+				}
+			}
+		}
+		return nil
+	})
+	zap.L().Error("db.View()", zap.Error(err))
+
+	if len(u.Pending) == 0 {
+		return spendable
+	}
+
+	// 去掉已被未打包交易花费的输出，加入未打包交易中尚未被花费的输出
+	spent := u.pendingSpent()
+	confirmed := spendable
+	spendable = nil
+	for _, s := range confirmed {
+		if !spent[s.String()] {
+			spendable = append(spendable, s)
+		}
+	}
+	for _, tx := range u.Pending {
+		txID := hex.EncodeToString(tx.ID)
+		for i, out := range tx.Outputs {
+			outPoint := OutPoint{txID, i}
+			if !out.IsDataCarrier() && pubKeyHashes[hex.EncodeToString(out.PubKeyHash)] && !spent[outPoint.String()] {
+				spendable = append(spendable, SpendableOutput{outPoint, out})
+			}
+		}
+	}
+
+	return spendable
+}
+
+// FindOutput 查找未花费的输出，输出已被花费或不存在时返回false
+func (u UTXOSet) FindOutput(txID []byte, index int) (TxOutput, bool) {
+	var found TxOutput
+	var ok bool
+
+	if len(u.Pending) > 0 {
+		if u.pendingSpent()[fmt.Sprintf("%x:%d", txID, index)] {
+			return found, false
+		}
+		for _, tx := range u.Pending {
+			if bytes.Equal(tx.ID, txID) {
+				if index < 0 || index >= len(tx.Outputs) || tx.Outputs[index].IsDataCarrier() {
+					return found, false
+				}
+				return tx.Outputs[index], true
+			}
+		}
+	}
+
+	err := u.Blockchain.Database.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(append(utxoPrefix, txID...))
+		if err != nil {
+			return nil
+		}
+		v, err := item.Value()
+		if err != nil {
+			return err
+		}
+
+		outs := DeserializeOutputs(v)
+		for i, out := range outs.Outputs {
+			if outs.Index(i) == index {
+				found, ok = out, true
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		zap.L().Error("db.View()", zap.Error(err))
+	}
+
+	return found, ok
+}
+
+// SignTransactionWithKeys 使用多把私钥签署交易，每个输入按其引用输出的公钥哈希（十六进制）选择私钥
+// 被花费的输出可以来自未打包的交易
+func (u UTXOSet) SignTransactionWithKeys(tx *Transaction, keys map[string]ecdsa.PrivateKey) error {
+	prevOuts := make([]TxOutput, len(tx.Inputs))
+	for inIdx, in := range tx.Inputs {
+		prevOut, ok := u.FindOutput(in.ID, in.Out)
+		if !ok {
+			return fmt.Errorf("input %d spends an unknown or already spent output %x:%d", inIdx, in.ID, in.Out)
+		}
+		prevOuts[inIdx] = prevOut
+	}
+
+	for inIdx, prevOut := range prevOuts {
+		privKey, ok := keys[hex.EncodeToString(prevOut.PubKeyHash)]
+		if !ok {
+			return fmt.Errorf("no key to sign input %d", inIdx)
+		}
+		if err := tx.SignInput(inIdx, privKey, prevOuts, SigHashAll); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pendingSpent 未打包交易花费的输出 txid:vout
+func (u UTXOSet) pendingSpent() map[string]bool {
+	spent := make(map[string]bool)
+	for _, tx := range u.Pending {
+		for _, in := range tx.Inputs {
+			spent[fmt.Sprintf("%x:%d", in.ID, in.Out)] = true
+		}
+	}
+
+	return spent
+}
+
 // FindAddressBalance 通过地址的UTXO计算余额
 func (u UTXOSet) FindAddressBalance(pubKeyHash []byte) []TxOutput {
 	var UTXOs []TxOutput
@@ -89,6 +236,35 @@ func (u UTXOSet) FindAddressBalance(pubKeyHash []byte) []TxOutput {
 	return UTXOs
 }
 
+// FindPubKeyHashes 获取UTXO集合中所有持有未花费输出的公钥哈希（十六进制）
+func (u UTXOSet) FindPubKeyHashes() map[string]bool {
+	pubKeyHashes := make(map[string]bool)
+
+	db := u.Blockchain.Database
+
+	err := db.View(func(txn *badger.Txn) error {
+		// 初始化数据库迭代器
+		opts := badger.DefaultIteratorOptions
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(utxoPrefix); it.ValidForPrefix(utxoPrefix); it.Next() {
+			item := it.Item()
+			v, err := item.Value()
+			zap.L().Error("item.Value()", zap.Error(err))
+
+			outs := DeserializeOutputs(v)
+			for _, out := range outs.Outputs {
+				pubKeyHashes[hex.EncodeToString(out.PubKeyHash)] = true
+			}
+		}
+		return nil
+	})
+	zap.L().Error("db.View()", zap.Error(err))
+
+	return pubKeyHashes
+}
+
 // CountTransactions 统计UTXO的数量
 func (u UTXOSet) CountTransactions() int {
 	db := u.Blockchain.Database
@@ -161,10 +337,10 @@ func (u *UTXOSet) Update(block *Block) {
 
 					updatedOuts := TxOutputs{}
 					outs := DeserializeOutputs(tmpUTXO)
-					for outIdx, out := range outs.Outputs {
+					for i, out := range outs.Outputs {
 						//删除与当前输入相关的未花费交易输出(UTXO)，过滤掉被使用过的UTXO
-						if outIdx != in.Out {
-							updatedOuts.Outputs = append(updatedOuts.Outputs, out)
+						if outIdx := outs.Index(i); outIdx != in.Out {
+							updatedOuts.Append(out, outIdx)
 						}
 					}
 
@@ -184,10 +360,15 @@ func (u *UTXOSet) Update(block *Block) {
 				}
 			}
 
-			// 添加新的UTXO
+			// 添加新的UTXO，数据输出不可花费，不进入UTXO集合
 			newOutputs := TxOutputs{}
-			for _, out := range tx.Outputs {
-				newOutputs.Outputs = append(newOutputs.Outputs, out)
+			for outIdx, out := range tx.Outputs {
+				if !out.IsDataCarrier() {
+					newOutputs.Append(out, outIdx)
+				}
+			}
+			if len(newOutputs.Outputs) == 0 {
+				continue
 			}
 
 			txID := append(utxoPrefix, tx.ID...)
@@ -0,0 +1,106 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"sort"
+)
+
+// FundWalletTransaction 构造未签名的付款交易，从钱包中所有地址的UTXO中选币
+// 每个输入的见证结构填入对应地址的公钥，找零发送到新派生的找零地址，调用方需要保存钱包文件
+func FundWalletTransaction(ws *wallet.Wallets, payments []Payment, UTXO *UTXOSet, cc *CoinControl) (*Transaction, error) {
+	if err := ValidatePayments(payments); err != nil {
+		return nil, err
+	}
+
+	// 公钥哈希到钱包的映射，用于识别UTXO的归属
+	owners := walletOwners(ws)
+	pubKeyHashes := make(map[string]bool, len(owners))
+	for pkh := range owners {
+		pubKeyHashes[pkh] = true
+	}
+
+	selection, err := cc.SelectCoins(UTXO.FindSpendableByPubKeyHashes(pubKeyHashes), TotalAmount(payments), len(payments))
+	if err != nil {
+		return nil, err
+	}
+
+	// 构造输入，并为每个输入填入所属地址的公钥
+	inputs := toTxInputs(selection.Inputs, cc != nil && cc.Replaceable)
+	witness := make([]TxWitness, len(inputs))
+	for i, in := range selection.Inputs {
+		witness[i].PubKey = owners[hex.EncodeToString(in.Output.PubKeyHash)].PublicKey
+	}
+
+	outputs := make([]TxOutput, 0, len(payments)+1)
+	for _, p := range payments {
+		outputs = append(outputs, *NewTXOutput(p.Amount, p.Address))
+	}
+	if selection.Change > 0 {
+		// 找零使用新的找零地址，避免将多个地址的资金关联到某个已公开的收款地址
+		changeAddress, err := ws.NewChangeAddress(false)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, *NewTXOutput(selection.Change, changeAddress))
+	}
+
+	// 组装交易结构体
+	tx := Transaction{nil, inputs, outputs, witness}
+	tx.ID = tx.Hash()
+
+	return &tx, nil
+}
+
+// NewWalletTransaction 创建从整个钱包付款的交易，每个输入使用其所属地址的私钥签名
+func NewWalletTransaction(ws *wallet.Wallets, payments []Payment, UTXO *UTXOSet, cc *CoinControl) (*Transaction, error) {
+	if ws.IsLocked() {
+		return nil, wallet.ErrWalletLocked
+	}
+
+	tx, err := FundWalletTransaction(ws, payments, UTXO, cc)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]ecdsa.PrivateKey)
+	for pkh, w := range walletOwners(ws) {
+		keys[pkh] = w.PrivateKey
+	}
+
+	if err := UTXO.SignTransactionWithKeys(tx, keys); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// InputAddresses 交易输入所属的钱包地址，按地址排序
+func InputAddresses(ws *wallet.Wallets, tx *Transaction) []string {
+	seen := make(map[string]bool)
+	for _, w := range tx.Witness {
+		address := string(wallet.Wallet{PublicKey: w.PubKey}.GenerateAddress())
+		if _, ok := ws.Wallets[address]; ok {
+			seen[address] = true
+		}
+	}
+
+	addresses := make([]string, 0, len(seen))
+	for address := range seen {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	return addresses
+}
+
+// walletOwners 钱包中持有私钥的地址，按公钥哈希（十六进制）索引
+func walletOwners(ws *wallet.Wallets) map[string]*wallet.Wallet {
+	owners := make(map[string]*wallet.Wallet, len(ws.Wallets))
+	for _, w := range ws.Wallets {
+		owners[hex.EncodeToString(wallet.PublicKeyHash(w.PublicKey))] = w
+	}
+
+	return owners
+}
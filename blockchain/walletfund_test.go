@@ -0,0 +1,78 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestNewWalletTransaction(t *testing.T) {
+	nodeId := "walletfund_test"
+	path := fmt.Sprintf(dbPath, nodeId)
+	if err := os.MkdirAll(path, 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./tmp")
+
+	ws := &wallet.Wallets{Wallets: make(map[string]*wallet.Wallet)}
+	a, _ := ws.NewReceiveAddress(false)
+	b, _ := ws.NewReceiveAddress(true)
+	payee := string(wallet.NewWallet().GenerateAddress())
+
+	// 两个地址各持有20，单个地址的余额都不足以支付30
+	chain := InitBlockChain(a, nodeId)
+	defer chain.Database.Close()
+	UTXO := UTXOSet{chain, nil}
+	UTXO.Reindex()
+	UTXO.Update(chain.MineBlock([]*Transaction{CoinbaseTx(b, "")}))
+
+	tx, err := NewWalletTransaction(ws, []Payment{{payee, 30}}, &UTXO, nil)
+	if err != nil {
+		t.Fatalf("NewWalletTransaction error: %v", err)
+	}
+	if len(tx.Inputs) != 2 {
+		t.Errorf("NewWalletTransaction error: 期望花费两个地址的UTXO，实际输入数量 %d", len(tx.Inputs))
+	}
+	if !chain.VerifyTransaction(tx) {
+		t.Error("NewWalletTransaction error: 各输入使用对应私钥签名后验证失败")
+	}
+	if addresses := InputAddresses(ws, tx); len(addresses) != 2 {
+		t.Errorf("InputAddresses error: %v", addresses)
+	}
+
+	// 找零发送到钱包新派生的找零地址
+	if len(tx.Outputs) != 2 || tx.Outputs[1].Value != 10 {
+		t.Fatalf("NewWalletTransaction error: 找零输出错误 %v", tx.Outputs)
+	}
+	changeOwner := false
+	for address, w := range ws.Wallets {
+		if tx.Outputs[1].PubKeyHashEquals(wallet.PublicKeyHash(w.PublicKey)) {
+			changeOwner = address != a && address != b && ws.GetPath(address) == "m/44'/0'/0'/1/0"
+		}
+	}
+	if !changeOwner {
+		t.Error("NewWalletTransaction error: 找零未发送到新的找零地址")
+	}
+
+	if _, err := NewWalletTransaction(ws, []Payment{{payee, 41}}, &UTXO, nil); err != ErrInsufficientFunds {
+		t.Errorf("NewWalletTransaction error: 余额不足时应返回 ErrInsufficientFunds，实际 %v", err)
+	}
+
+	// 交易广播后尚未打包：已花费的UTXO不能再选用，未确认的找零可以继续花费
+	UTXO.Pending = []*Transaction{tx}
+	child, err := NewWalletTransaction(ws, []Payment{{payee, 5}}, &UTXO, nil)
+	if err != nil {
+		t.Fatalf("NewWalletTransaction error: 无法花费未确认的找零: %v", err)
+	}
+	if len(child.Inputs) != 1 || !bytes.Equal(child.Inputs[0].ID, tx.ID) || child.Inputs[0].Out != 1 {
+		t.Errorf("NewWalletTransaction error: 应花费未确认的找零，实际输入 %x:%d", child.Inputs[0].ID, child.Inputs[0].Out)
+	}
+	if !child.VerifyPrevOuts([]TxOutput{tx.Outputs[1]}) {
+		t.Error("NewWalletTransaction error: 花费未确认找零的交易签名无效")
+	}
+	if _, err := NewWalletTransaction(ws, []Payment{{payee, 11}}, &UTXO, nil); err != ErrInsufficientFunds {
+		t.Errorf("NewWalletTransaction error: 已被未确认交易花费的UTXO被再次选用: %v", err)
+	}
+}
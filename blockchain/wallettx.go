@@ -0,0 +1,276 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SyncWallet 将钱包的交易记录同步到当前主链
+// 先回滚已不在主链上的区块，再按顺序连接新的区块，返回回滚与连接的区块数
+func (bc *BlockChain) SyncWallet(ws *wallet.Wallets) (int, int) {
+	store := ws.Transactions()
+	mainChain := bc.mainChain()
+	onMainChain := make(map[string]bool, len(mainChain))
+	for _, block := range mainChain {
+		onMainChain[hex.EncodeToString(block.Hash)] = true
+	}
+
+	// 记录的最后一个区块不在主链上时，沿其父区块回滚直到与主链汇合
+	disconnected := 0
+	for store.TipHash != "" && !onMainChain[store.TipHash] {
+		hash, _ := hex.DecodeString(store.TipHash)
+		block, err := bc.GetBlock(hash)
+		if err != nil {
+			// 本地没有该区块（如区块链被重建），清除全部已确认的交易后重新扫描
+			store.Reset(0, store.TipHeight)
+			store.TipHash, store.TipHeight = "", -1
+			break
+		}
+		bc.DisconnectWalletBlock(ws, &block)
+		disconnected++
+	}
+
+	connected := 0
+	for _, block := range mainChain {
+		if block.Height > store.TipHeight {
+			bc.ConnectWalletBlock(ws, block)
+			connected++
+		}
+	}
+
+	return disconnected, connected
+}
+
+// RescanWallet 重新扫描主链中 [from, to] 高度区间内的区块，重建该区间的交易记录
+// to为负数时扫描到最新区块，返回区间内找到的钱包交易数
+func (bc *BlockChain) RescanWallet(ws *wallet.Wallets, from, to int) (int, error) {
+	bc.SyncWallet(ws)
+
+	mainChain := bc.mainChain()
+	bestHeight := len(mainChain) - 1
+	if to < 0 {
+		to = bestHeight
+	}
+	if from < 0 || from > to || to > bestHeight {
+		return 0, fmt.Errorf("invalid rescan range %d-%d, best height is %d", from, to, bestHeight)
+	}
+
+	store := ws.Transactions()
+	store.Reset(from, to)
+
+	owned := walletPubKeyHashes(ws)
+	found := 0
+	for _, block := range mainChain[from : to+1] {
+		found += connectWalletTxs(store, owned, block)
+	}
+
+	return found, nil
+}
+
+// ConnectWalletBlock 区块连接到主链时更新钱包的交易记录
+func (bc *BlockChain) ConnectWalletBlock(ws *wallet.Wallets, block *Block) {
+	store := ws.Transactions()
+	connectWalletTxs(store, walletPubKeyHashes(ws), block)
+
+	store.TipHash, store.TipHeight = hex.EncodeToString(block.Hash), block.Height
+}
+
+// DisconnectWalletBlock 区块从主链上断开时更新钱包的交易记录
+// 区块中的普通交易恢复为未确认，币基交易随区块失效而删除，因该区块中的交易而冲突的交易恢复为未确认
+func (bc *BlockChain) DisconnectWalletBlock(ws *wallet.Wallets, block *Block) {
+	store := ws.Transactions()
+	blockHash := hex.EncodeToString(block.Hash)
+
+	for i := len(block.Transactions) - 1; i >= 0; i-- {
+		id := hex.EncodeToString(block.Transactions[i].ID)
+
+		if wtx, ok := store.Txs[id]; ok && wtx.BlockHash == blockHash {
+			if wtx.Coinbase {
+				delete(store.Txs, id)
+			} else {
+				wtx.Status, wtx.BlockHash, wtx.Height = wallet.TxPending, "", -1
+			}
+		}
+
+		for _, wtx := range store.Txs {
+			if wtx.Status == wallet.TxConflicted && wtx.ConflictedBy == id {
+				wtx.Status, wtx.ConflictedBy = wallet.TxPending, ""
+			}
+		}
+	}
+
+	store.TipHash, store.TipHeight = hex.EncodeToString(block.PrevBlockHash), block.Height-1
+}
+
+// AddPendingWalletTx 记录钱包广播的未确认交易，与钱包无关时返回false
+func (bc *BlockChain) AddPendingWalletTx(ws *wallet.Wallets, tx *Transaction) bool {
+	store := ws.Transactions()
+	id := hex.EncodeToString(tx.ID)
+	if _, ok := store.Txs[id]; ok {
+		return true
+	}
+
+	wtx := newWalletTx(store, walletPubKeyHashes(ws), tx)
+	if wtx == nil {
+		return false
+	}
+	wtx.Status, wtx.Height, wtx.Timestamp = wallet.TxPending, -1, time.Now().Unix()
+	wtx.Raw = tx.Serialize()
+	store.Txs[id] = wtx
+
+	return true
+}
+
+// PendingWalletTxs 钱包广播且尚未打包的交易，按记录时间排列
+// 这些交易花费的输出不能再次选用，支付给钱包的输出（如找零）可以在打包前继续花费
+func PendingWalletTxs(ws *wallet.Wallets) []*Transaction {
+	var txs []*Transaction
+	for _, wtx := range ws.Transactions().List() {
+		if wtx.Status == wallet.TxPending && len(wtx.Raw) > 0 {
+			tx := DeserializeTransaction(wtx.Raw)
+			txs = append(txs, &tx)
+		}
+	}
+
+	return txs
+}
+
+// connectWalletTxs 记录区块中与钱包相关的交易，并将与之冲突的未确认交易标记为冲突，返回相关交易数
+func connectWalletTxs(store *wallet.TxStore, owned map[string]string, block *Block) int {
+	blockHash := hex.EncodeToString(block.Hash)
+
+	found := 0
+	for _, tx := range block.Transactions {
+		id := hex.EncodeToString(tx.ID)
+
+		if !tx.IsCoinbaseTx() {
+			for _, in := range tx.Inputs {
+				markConflicts(store, fmt.Sprintf("%x:%d", in.ID, in.Out), id)
+			}
+		}
+
+		wtx, ok := store.Txs[id]
+		if !ok {
+			if wtx = newWalletTx(store, owned, tx); wtx == nil {
+				continue
+			}
+			store.Txs[id] = wtx
+		}
+
+		wtx.Status, wtx.ConflictedBy, wtx.ReplacedBy = wallet.TxConfirmed, "", ""
+		wtx.BlockHash, wtx.Height, wtx.Timestamp = blockHash, block.Height, block.Timestamp
+		found++
+	}
+
+	return found
+}
+
+// markConflicts 主链中的交易花费了某个输出时，花费同一输出的其他未确认交易不可能再被打包
+func markConflicts(store *wallet.TxStore, outPoint, spender string) {
+	for id, wtx := range store.Txs {
+		if id == spender || wtx.Status != wallet.TxPending {
+			continue
+		}
+		for _, spent := range wtx.Spends {
+			if spent == outPoint {
+				wtx.Status, wtx.ConflictedBy = wallet.TxConflicted, spender
+				break
+			}
+		}
+	}
+}
+
+// newWalletTx 计算交易对钱包的影响，交易既不花费也不支付钱包地址时返回nil
+// 被花费的输出从已记录交易的收入中查找，因此交易记录需要从创世区块开始按顺序建立
+func newWalletTx(store *wallet.TxStore, owned map[string]string, tx *Transaction) *wallet.WalletTx {
+	wtx := &wallet.WalletTx{TxID: hex.EncodeToString(tx.ID), Height: -1, Coinbase: tx.IsCoinbaseTx(), Credits: make(map[int]wallet.Credit)}
+	addresses := make(map[string]bool)
+
+	allInputsOwned := !wtx.Coinbase
+	if !wtx.Coinbase {
+		for _, in := range tx.Inputs {
+			wtx.Spends = append(wtx.Spends, fmt.Sprintf("%x:%d", in.ID, in.Out))
+
+			prev, ok := store.Txs[hex.EncodeToString(in.ID)]
+			if !ok {
+				allInputsOwned = false
+				continue
+			}
+			credit, ok := prev.Credits[in.Out]
+			if !ok {
+				allInputsOwned = false
+				continue
+			}
+			wtx.Sent += credit.Value
+			addresses[credit.Address] = true
+		}
+	}
+
+	total := 0
+	for outIdx, out := range tx.Outputs {
+		total += out.Value
+		if out.IsDataCarrier() {
+			continue
+		}
+		if address, ok := owned[hex.EncodeToString(out.PubKeyHash)]; ok {
+			wtx.Credits[outIdx] = wallet.Credit{Value: out.Value, Address: address}
+			wtx.Received += out.Value
+			addresses[address] = true
+		}
+	}
+
+	if wtx.Sent == 0 && wtx.Received == 0 {
+		return nil
+	}
+	if allInputsOwned {
+		wtx.Fee = wtx.Sent - total
+	}
+
+	for address := range addresses {
+		wtx.Addresses = append(wtx.Addresses, address)
+	}
+	sort.Strings(wtx.Addresses)
+
+	return wtx
+}
+
+// walletPubKeyHashes 钱包地址（包括只读地址）按公钥哈希（十六进制）索引
+func walletPubKeyHashes(ws *wallet.Wallets) map[string]string {
+	owned := make(map[string]string, len(ws.Wallets)+len(ws.WatchOnly))
+	for address := range ws.Wallets {
+		if decoded, err := wallet.DecodeAddress(address, wallet.ActiveNetParams); err == nil {
+			owned[hex.EncodeToString(decoded.PubKeyHash)] = address
+		}
+	}
+	for address := range ws.WatchOnly {
+		if decoded, err := wallet.DecodeAddress(address, wallet.ActiveNetParams); err == nil {
+			owned[hex.EncodeToString(decoded.PubKeyHash)] = address
+		}
+	}
+
+	return owned
+}
+
+// mainChain 主链上的全部区块，按高度从创世区块开始排列
+func (bc *BlockChain) mainChain() []*Block {
+	var blocks []*Block
+
+	iter := bc.Iterator()
+	for {
+		block := iter.Next()
+		blocks = append(blocks, block)
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+
+	return blocks
+}
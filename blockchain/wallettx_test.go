@@ -0,0 +1,79 @@
+package blockchain
+
+import (
+	"Golang_Bitcoin_Sample/wallet"
+	"encoding/hex"
+	"testing"
+)
+
+func TestWalletTxStore(t *testing.T) {
+	ws := &wallet.Wallets{Wallets: make(map[string]*wallet.Wallet)}
+	mine, _ := ws.NewReceiveAddress(false)
+	payee := string(wallet.NewWallet().GenerateAddress())
+	bc := &BlockChain{}
+
+	// 区块1：挖矿奖励支付给钱包地址
+	coinbase := CoinbaseTx(mine, "")
+	block1 := &Block{Hash: []byte{1}, PrevBlockHash: []byte{0}, Transactions: []*Transaction{coinbase}, Height: 1, Timestamp: 100}
+	bc.ConnectWalletBlock(ws, block1)
+
+	spend := func(amount int) *Transaction {
+		tx := Transaction{nil, []TxInput{{coinbase.ID, 0, nil, false}}, []TxOutput{*NewTXOutput(amount, payee), *NewTXOutput(19-amount, mine)}, nil}
+		tx.ID = tx.Hash()
+		return &tx
+	}
+
+	// 广播的交易记录为未确认，手续费为1
+	pending := spend(5)
+	if !bc.AddPendingWalletTx(ws, pending) {
+		t.Fatal("AddPendingWalletTx error: 钱包相关的交易未被记录")
+	}
+	store := ws.Transactions()
+	wtx := store.Txs[hex.EncodeToString(pending.ID)]
+	if wtx.Status != wallet.TxPending || wtx.Net() != -6 || wtx.Fee != 1 || wtx.Category() != "send" {
+		t.Errorf("AddPendingWalletTx error: %+v", wtx)
+	}
+	if confirmed, unconfirmed := store.Balance(); confirmed != 20 || unconfirmed != -6 {
+		t.Errorf("Balance error: 已确认 %d，未确认 %d", confirmed, unconfirmed)
+	}
+
+	// 区块2打包了花费同一输出的另一笔交易，未确认的交易变为冲突
+	double := spend(7)
+	block2 := &Block{Hash: []byte{2}, PrevBlockHash: block1.Hash, Transactions: []*Transaction{double}, Height: 2, Timestamp: 200}
+	bc.ConnectWalletBlock(ws, block2)
+	if wtx.Status != wallet.TxConflicted || wtx.ConflictedBy != hex.EncodeToString(double.ID) {
+		t.Errorf("ConnectWalletBlock error: 未确认交易状态为 %s", wtx.Status)
+	}
+	if confirmed, unconfirmed := store.Balance(); confirmed != 12 || unconfirmed != 0 {
+		t.Errorf("Balance error: 已确认 %d，未确认 %d", confirmed, unconfirmed)
+	}
+	if c := store.Txs[hex.EncodeToString(double.ID)].Confirmations(2); c != 1 {
+		t.Errorf("Confirmations error: 期望 1，实际 %d", c)
+	}
+
+	// 区块2被回滚后，两笔交易都恢复为未确认
+	bc.DisconnectWalletBlock(ws, block2)
+	if wtx.Status != wallet.TxPending || store.Txs[hex.EncodeToString(double.ID)].Status != wallet.TxPending {
+		t.Error("DisconnectWalletBlock error: 回滚后交易未恢复为未确认")
+	}
+	if store.TipHeight != 1 {
+		t.Errorf("DisconnectWalletBlock error: 同步高度为 %d", store.TipHeight)
+	}
+
+	// 放弃的交易不计入余额，已确认的交易不能放弃
+	if err := store.Abandon(hex.EncodeToString(double.ID)); err != nil {
+		t.Fatalf("Abandon error: %v", err)
+	}
+	if confirmed, unconfirmed := store.Balance(); confirmed != 20 || unconfirmed != -6 {
+		t.Errorf("Balance error: 已确认 %d，未确认 %d", confirmed, unconfirmed)
+	}
+	if err := store.Abandon(hex.EncodeToString(coinbase.ID)); err == nil {
+		t.Error("Abandon error: 已确认的交易被放弃")
+	}
+
+	// 回滚区块1后币基交易随之删除
+	bc.DisconnectWalletBlock(ws, block1)
+	if _, ok := store.Txs[hex.EncodeToString(coinbase.ID)]; ok {
+		t.Error("DisconnectWalletBlock error: 回滚区块中的币基交易未删除")
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"Golang_Bitcoin_Sample/wallet"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// addressSortKeys listaddresses 支持的排序方式
+var addressSortKeys = map[string]bool{"created": true, "address": true, "label": true, "balance": true}
+
+// listAddresses 列出钱包中满足过滤条件的地址及其标签、用途、创建时间与余额
+// 本地存在区块链时从UTXO集合中统计余额
+func (cli *CommandLine) listAddresses(nodeID string, filter wallet.AddressFilter, sortBy string) {
+	wallets, _ := wallet.CreateWallets(nodeID)
+	infos := wallets.ListAddresses(filter)
+
+	balances := make(map[string]int)
+	if blockchain.BlockChainExists(nodeID) && len(infos) > 0 {
+		chain := blockchain.ContinueBlockChain(nodeID)
+		UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+		defer chain.Database.Close()
+
+		pubKeyHashes := make(map[string]bool)
+		for _, info := range infos {
+			pubKeyHashes[hex.EncodeToString(blockchain.NewTXOutput(0, info.Address).PubKeyHash)] = true
+		}
+		for _, utxo := range UTXOSet.FindSpendableByPubKeyHashes(pubKeyHashes) {
+			balances[hex.EncodeToString(utxo.Output.PubKeyHash)] += utxo.Output.Value
+		}
+	}
+	balanceOf := func(address string) int {
+		return balances[hex.EncodeToString(blockchain.NewTXOutput(0, address).PubKeyHash)]
+	}
+
+	// ListAddresses 已按创建时间与地址排序，稳定排序保证其他排序方式下相同键的顺序不变
+	switch sortBy {
+	case "address":
+		sort.SliceStable(infos, func(i, j int) bool { return infos[i].Address < infos[j].Address })
+	case "label":
+		sort.SliceStable(infos, func(i, j int) bool { return infos[i].Label < infos[j].Label })
+	case "balance":
+		sort.SliceStable(infos, func(i, j int) bool { return balanceOf(infos[i].Address) > balanceOf(infos[j].Address) })
+	}
+
+	for _, info := range infos {
+		created := "-"
+		if info.Created > 0 {
+			created = time.Unix(info.Created, 0).Format("2006-01-02 15:04:05")
+		}
+
+		line := fmt.Sprintf("%-34s %10d  %-7s  %-19s", info.Address, balanceOf(info.Address), info.Purpose, created)
+		if info.Label != "" {
+			line += fmt.Sprintf("  %q", info.Label)
+		}
+		if info.Path != "" {
+			line += "  " + info.Path
+		}
+		if info.WatchOnly {
+			line += "  [watch-only]"
+		}
+		fmt.Println(line)
+	}
+}
+
+// setLabel 设置钱包地址或地址簿地址的标签
+func (cli *CommandLine) setLabel(nodeID, address, label string) {
+	address, ok := normalizeAddress(address)
+	if !ok {
+		return
+	}
+
+	wallets, _ := wallet.CreateWallets(nodeID)
+	if err := wallets.SetLabel(address, label); err != nil {
+		fmt.Println(err)
+		return
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("Label of %s set to %q\n", address, label)
+}
+
+// addressBook 管理地址簿：添加或删除交易对手地址，未指定操作时列出地址簿
+func (cli *CommandLine) addressBook(nodeID, add, remove, label string) {
+	wallets, _ := wallet.CreateWallets(nodeID)
+
+	switch {
+	case add != "":
+		address, err := wallets.AddContact(add, label)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		wallets.SaveFile(nodeID)
+		fmt.Printf("Added %s to the address book\n", address)
+	case remove != "":
+		if err := wallets.RemoveContact(remove); err != nil {
+			fmt.Println(err)
+			return
+		}
+		wallets.SaveFile(nodeID)
+		fmt.Printf("Removed %s from the address book\n", remove)
+	default:
+		for _, address := range wallets.GetContacts() {
+			fmt.Printf("%-34s  %q\n", address, wallets.AddressBook[address].Label)
+		}
+	}
+}
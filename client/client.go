@@ -4,13 +4,20 @@ import (
 	"Golang_Bitcoin_Sample/blockchain"
 	"Golang_Bitcoin_Sample/network"
 	"Golang_Bitcoin_Sample/wallet"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"flag"
 	"fmt"
 	"go.uber.org/zap"
+	"io"
 	"log"
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type CommandLine struct{}
@@ -18,14 +25,51 @@ type CommandLine struct{}
 // printUsage 打印所有功能
 func (cli *CommandLine) printUsage() {
 	fmt.Println("Usage:")
-	fmt.Println(" getbalance -address 钱包地址 - 获取地址的余额")
+	fmt.Println(" getbalance [-address 钱包地址] - 获取地址的余额，省略 -address 时由钱包交易记录计算整个钱包的已确认与未确认余额")
+	fmt.Println(" listtransactions [-count N] - 输出钱包交易流水：时间、确认数、类别、金额、手续费、累计余额与状态（pending/confirmed/conflicted/abandoned）")
+	fmt.Println(" rescanblockchain [-from 高度] [-to 高度] - 重新扫描主链重建钱包交易记录，导入私钥或只读地址后使用")
+	fmt.Println(" abandontransaction -txid 交易ID - 放弃未确认或冲突的交易，不再计入余额")
+	fmt.Println(" bumpfee -txid 交易ID [-fee 手续费] - 为带 -rbf 发出的未确认交易提高手续费，从找零中扣除并重新签名后广播替换交易")
 	fmt.Println(" createblockchain -address 钱包地址 -创建一条区块链并发放一笔创世区块奖励至地址中")
 	fmt.Println(" printchain - 遍历区块链")
 	fmt.Println(" send -from 转账地址 -to 接收地址 -amount 转账数目 -mine 挖矿- 发送一定数量的代币，如果设置了-mine标志，则从该节点挖掘")
-	fmt.Println(" createwallet - 创建钱包地址")
-	fmt.Println(" listaddresses - 展示钱包文件中的所有钱包地址")
+	fmt.Println("      省略 -from 时从钱包中所有地址选币，并将找零发送到新的找零地址")
+	fmt.Println("      -unsigned 只构造未签名的交易并输出（用于只读地址）")
+	fmt.Println("      -uri bitcoin:地址?amount=金额 由付款请求填写接收地址与金额，可代替 -to 与 -amount")
+	fmt.Println("      -coinselect greedy|bnb|largest|smallest|random 选币策略（默认greedy按UTXO顺序选币），-feerate 每字节手续费")
+	fmt.Println("      -utxo txid:vout 必须花费的输出，-exclude txid:vout 不得花费的输出，可重复或以逗号分隔")
+	fmt.Println("      -rbf 允许交易打包前被手续费更高的交易替换；不带 -mine 时交易广播到网络")
+	fmt.Println(" sendmany [-from 转账地址] -file 付款文件 [-mine] [-unsigned] - 在一笔交易中向多个地址付款，文件为JSON（[{\"address\":...,\"amount\":...}] 或 {地址: 金额}）或CSV（地址,金额），支持与 send 相同的选币参数")
+	fmt.Println(" createpsbt [-from 转账地址] (-to 接收地址 -amount 转账数目 | -file 付款文件) [-out 文件] - 构造部分签名交易，携带被花费的输出，可在离线机器上签名")
+	fmt.Println(" inspectpsbt -psbt 部分签名交易 - 展示部分签名交易的输入、输出、手续费与签名进度，-psbt 可以是Base64文本或文件路径")
+	fmt.Println(" signpsbt -psbt 部分签名交易 [-sighash ALL] [-out 文件] - 使用钱包私钥签署，不需要区块链数据")
+	fmt.Println(" combinepsbt -psbt A -psbt B ... [-out 文件] - 合并多方对同一笔交易的部分签名")
+	fmt.Println(" finalizepsbt -psbt 部分签名交易 - 生成完整交易并以十六进制输出")
+	fmt.Println(" broadcastpsbt -psbt 部分签名交易 [-miner ADDRESS] - 验证后广播交易，指定 -miner 时在本节点挖矿打包")
+	fmt.Println(" createwallet [-schnorr] [-bech32] [-mnemonic -words 12|24] - 从HD钱包派生下一个收款地址，-schnorr 派生使用Schnorr签名的地址，-bech32 同时输出Bech32形式，-mnemonic 由新的助记词生成钱包种子，密码短语从标准输入读取")
+	fmt.Println(" getnewaddress [-amount 金额] [-label 标签] [-message 附言] [-schnorr] - 派生新的收款地址并记录标签，输出对应的 bitcoin: 付款请求URI")
+	fmt.Println(" validateaddress -address 地址 - 检查Base58Check或Bech32地址，地址有误时指出出错的位置")
+	fmt.Println(" restorewallet [-gap 20] - 从标准输入读取助记词与密码短语恢复钱包，并扫描UTXO集合找回已使用的地址")
+	fmt.Println(" encryptwallet - 从标准输入读取口令，加密钱包文件中的私钥与种子")
+	fmt.Println(" walletpassphrase [-timeout 60] - 从标准输入读取口令解锁钱包，超过 timeout 秒后自动锁定（过期的会话密钥在下一条命令或节点巡检时删除）")
+	fmt.Println(" walletlock - 立即锁定钱包")
+	fmt.Println(" importaddress -address 地址 - 导入只读地址，可查询余额与交易记录，但不能签名")
+	fmt.Println(" importpubkey -pubkey 十六进制公钥 - 导入只读公钥")
+	fmt.Println(" gethistory -address 地址 - 列出与地址相关的所有交易")
+	fmt.Println(" dumpprivkey -address 地址 - 以WIF格式导出地址对应的私钥")
+	fmt.Println(" signmessage -address 地址 -message 消息 - 使用地址的私钥对消息签名，输出Base64编码的签名，用于证明持有该地址")
+	fmt.Println(" verifymessage -address 地址 -signature 签名 -message 消息 - 验证消息签名是否由该地址的私钥生成")
+	fmt.Println(" importprivkey -wif 私钥 [-schnorr] [-rescan] - 导入WIF格式的私钥，-rescan 扫描区块链中属于该地址的输出")
+	fmt.Println(" listaddresses [-label 标签] [-purpose receive|change] [-watchonly] [-sort created|address|label|balance] - 展示钱包地址及其余额、用途、创建时间与标签，只读地址标记为 [watch-only]")
+	fmt.Println(" setlabel -address 地址 -label 标签 - 设置钱包地址或地址簿地址的标签，标签为空时清除")
+	fmt.Println(" addressbook [-add 地址 -label 标签] [-remove 地址] - 管理交易对手的地址簿，不带参数时列出地址簿")
 	fmt.Println(" reindexutxo - 更新UTXO集合")
+	fmt.Println(" timestamp -file 文件路径 -from 付款地址 -mine 挖矿 - 将文件哈希写入数据输出，为文件存证")
+	fmt.Println(" verifytimestamp -file 文件路径 - 证明文件哈希已被打包上链，并输出所在区块及时间")
 	fmt.Println(" startnode -miner ADDRESS - 使用 NODE_ID 环境变量指定的 ID 启动节点。-miner 选项启用挖矿。")
+	fmt.Println("   [-minetxs 2] [-minebytes 0] [-maxwait 0] - 交易池中的交易数、总字节数或最早交易的等待秒数达到该值时出块，0表示不使用该条件")
+	fmt.Println("   [-blockmaxsize 1048576] [-blockmaxsigops 20000] - 区块模板的字节数与签名检查次数上限，按手续费率选择交易")
+	fmt.Println(" savemempool - 通知 NODE_ID 对应的运行中节点将交易池写入文件，节点关闭时也会自动保存并在启动时重新加载")
 }
 
 // validateArgs() 检测输入的参数个数
@@ -37,38 +81,226 @@ func (client *CommandLine) validateArgs() {
 }
 
 // createWallet 生成钱包
-func (client *CommandLine) createWallet(nodeID string) {
+// withMnemonic为真时，由新生成的助记词（及可选的密码短语）初始化钱包种子
+func (client *CommandLine) createWallet(nodeID string, schnorr, withMnemonic, bech32 bool, words int) {
 	wallets, _ := wallet.CreateWallets(nodeID)
-	address := wallets.AddWallet()
+
+	if withMnemonic {
+		if wallets.HD != nil {
+			log.Panic("Wallet already has an HD seed, use restorewallet on a new node to restore from a mnemonic")
+		}
+
+		mnemonic, err := wallet.NewMnemonic(words)
+		if err != nil {
+			log.Panic(err)
+		}
+		seed, err := wallet.MnemonicToSeed(mnemonic, readSecret("Enter BIP39 passphrase (empty for none): "))
+		if err != nil {
+			log.Panic(err)
+		}
+		if err := wallets.InitHD(seed); err != nil {
+			log.Panic(err)
+		}
+
+		fmt.Printf("Mnemonic: %s\n", mnemonic)
+		fmt.Println("Write these words down and keep them safe, they are the only backup of this wallet")
+	}
+
+	address, err := wallets.NewReceiveAddress(schnorr)
+	if err != nil {
+		log.Panic(err)
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("New address is: %s\n", address)
+	if bech32 {
+		fmt.Printf("Bech32 form:    %s\n", wallets.Wallets[address].Bech32Address(wallet.ActiveNetParams))
+	}
+}
+
+// getNewAddress 派生新的收款地址，并输出包含金额、标签与附言的付款请求URI
+func (cli *CommandLine) getNewAddress(nodeID string, amount int, label, message string, schnorr bool) {
+	wallets, _ := wallet.CreateWallets(nodeID)
+	address, err := wallets.NewReceiveAddress(schnorr)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if label != "" {
+		wallets.SetLabel(address, label)
+	}
 	wallets.SaveFile(nodeID)
 
+	request := wallet.PaymentURI{Address: address, Amount: amount, Label: label, Message: message}
 	fmt.Printf("New address is: %s\n", address)
+	fmt.Printf("Payment URI:    %s\n", request)
 }
 
-// listAddresses 列出对应节点下保存的所有钱包文件
-func (cli *CommandLine) listAddresses(nodeID string) {
+// validateAddress 解析地址并展示其编码、类型与公钥哈希，地址有误时指出出错的位置
+func (cli *CommandLine) validateAddress(address string) {
+	decoded, err := wallet.DecodeAddress(address, wallet.ActiveNetParams)
+	if err != nil {
+		fmt.Println(err)
+
+		var addrErr *wallet.AddressError
+		if errors.As(err, &addrErr) && addrErr.Pos >= 0 && addrErr.Pos < len(address) {
+			fmt.Printf("  %s\n  %s^\n", address, strings.Repeat(" ", addrErr.Pos))
+		}
+		return
+	}
+
+	kind := "ECDSA"
+	if decoded.Schnorr {
+		kind = "Schnorr"
+	}
+
+	fmt.Printf("Address %s is valid\n", address)
+	fmt.Printf("  Type:        %s\n", kind)
+	fmt.Printf("  PubKeyHash:  %x\n", decoded.PubKeyHash)
+	fmt.Printf("  Base58Check: %s\n", decoded.Base58())
+	fmt.Printf("  Bech32:      %s\n", decoded.Bech32(wallet.ActiveNetParams))
+}
+
+// restoreWallet 由助记词恢复钱包，并在UTXO集合中扫描已使用的地址
+func (cli *CommandLine) restoreWallet(nodeID string, gapLimit int) {
 	wallets, _ := wallet.CreateWallets(nodeID)
-	addresses := wallets.GetAllAddresses()
+	if len(wallets.Wallets) > 0 || wallets.HD != nil {
+		log.Panic("Wallet file already exists, move it away before restoring")
+	}
+
+	mnemonic := readSecret("Enter mnemonic: ")
+	seed, err := wallet.MnemonicToSeed(mnemonic, readSecret("Enter BIP39 passphrase (empty for none): "))
+	if err != nil {
+		log.Panic(err)
+	}
+	if err := wallets.InitHD(seed); err != nil {
+		log.Panic(err)
+	}
+
+	// 区块链存在时，扫描UTXO集合找回已使用的地址
+	if blockchain.BlockChainExists(nodeID) {
+		chain := blockchain.ContinueBlockChain(nodeID)
+		UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+		pubKeyHashes := UTXOSet.FindPubKeyHashes()
+		chain.Database.Close()
+
+		found, err := wallets.Rescan(func(pubKeyHash []byte) bool {
+			return pubKeyHashes[hex.EncodeToString(pubKeyHash)]
+		}, gapLimit)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		for _, address := range found {
+			fmt.Printf("Found used address: %s  %s\n", address, wallets.GetPath(address))
+		}
+	}
+
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("Wallet restored with %d addresses\n", len(wallets.Wallets))
+}
+
+// encryptWallet 使用口令加密钱包文件，加密后钱包处于锁定状态
+func (cli *CommandLine) encryptWallet(nodeID string) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		fmt.Println("No wallet found, create one first")
+		return
+	}
+
+	if err := wallets.Encrypt(readSecret("Enter new wallet passphrase: ")); err != nil {
+		fmt.Println(err)
+		return
+	}
+	wallets.SaveFile(nodeID)
+	if err := wallet.RemoveUnlockSession(nodeID); err != nil {
+		zap.L().Error("wallet.RemoveUnlockSession() failed", zap.Error(err))
+	}
+
+	fmt.Println("Wallet encrypted, use walletpassphrase to unlock it before signing")
+}
+
+// walletPassphrase 使用口令解锁钱包，timeout秒后自动锁定
+func (cli *CommandLine) walletPassphrase(nodeID string, timeout int) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		fmt.Println("No wallet found, create one first")
+		return
+	}
+
+	if err := wallets.Unlock(readSecret("Enter wallet passphrase: ")); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := wallets.StartUnlockSession(nodeID, time.Duration(timeout)*time.Second); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("Wallet unlocked for %d seconds\n", timeout)
+}
+
+// walletLock 立即锁定钱包
+func (cli *CommandLine) walletLock(nodeID string) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		fmt.Println("No wallet found, create one first")
+		return
+	}
+	if !wallets.IsEncrypted() {
+		fmt.Println(wallet.ErrWalletNotEncrypted)
+		return
+	}
+
+	if err := wallets.EndUnlockSession(nodeID); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Wallet locked")
+}
+
+// 标准输入的读取缓冲，一条命令中多次读取时共用，避免前一次读取缓冲掉后面的行
+var stdin = bufio.NewReader(os.Stdin)
+
+// readSecret 从标准输入读取一行口令或助记词，避免通过命令行参数传递而出现在进程列表与shell历史中
+func readSecret(prompt string) string {
+	fmt.Print(prompt)
+	line, err := stdin.ReadString('\n')
+	if err != nil && err != io.EOF {
+		log.Panic(err)
+	}
+
+	return strings.TrimRight(line, "\r\n")
+}
 
-	for _, address := range addresses {
-		fmt.Println(address)
+// normalizeAddress 解析用户输入的地址并转换为钱包使用的Base58Check形式，地址有误时打印出错原因与位置
+func normalizeAddress(address string) (string, bool) {
+	normalized, err := wallet.NormalizeAddress(address)
+	if err != nil {
+		fmt.Println(err)
+		return "", false
 	}
 
+	return normalized, true
 }
 
 // createBlockChain 在当前的节点下创建区块链对象，并获得创世区块奖励
 func (cli *CommandLine) createBlockChain(address, nodeID string) {
 	// 验证钱包有效性
-	if !wallet.ValidateAddress(address) {
-		log.Panic("Address is not Valid")
+	address, ok := normalizeAddress(address)
+	if !ok {
+		return
 	}
 	// 初始化区块链对象，并获得创世区块的区块收益
 	chain := blockchain.InitBlockChain(address, nodeID)
 	defer chain.Database.Close()
 
 	// 更新数据库中的UTXO集合
-	UTXOSet := blockchain.UTXOSet{chain}
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
 	UTXOSet.Reindex()
+	syncWalletTxs(chain, nodeID)
 
 	fmt.Println("Finished!")
 }
@@ -94,76 +326,499 @@ func (cli *CommandLine) printChain(nodeID string) {
 		}
 		fmt.Println()
 
-		//遍历到创世区块后停止
-		if len(block.PrevBlockHash) == 0 {
-			break
-		}
+		//遍历到创世区块后停止
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+}
+
+// send 转账交易
+func (cli *CommandLine) send(from, to string, amount int, nodeID string, mineNow, unsigned bool, cc *blockchain.CoinControl) {
+	cli.sendPayments(from, []blockchain.Payment{{Address: to, Amount: amount}}, nodeID, mineNow, unsigned, cc)
+}
+
+// sendMany 从文件读取付款列表，在一笔交易中向多个地址付款
+func (cli *CommandLine) sendMany(from, file string, nodeID string, mineNow, unsigned bool, cc *blockchain.CoinControl) {
+	payments, err := blockchain.ReadPaymentsFile(file)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	cli.sendPayments(from, payments, nodeID, mineNow, unsigned, cc)
+}
+
+// sendPayments 构造并签名付款交易，付款列表校验失败时不会进行选币与签名
+func (cli *CommandLine) sendPayments(from string, payments []blockchain.Payment, nodeID string, mineNow, unsigned bool, cc *blockchain.CoinControl) {
+	//判断参与转账的地址的有效性
+	if err := blockchain.ValidatePayments(payments); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if from == "" {
+		cli.sendFromWallet(payments, nodeID, mineNow, unsigned, cc)
+		return
+	}
+	from, ok := normalizeAddress(from)
+	if !ok {
+		return
+	}
+
+	// 获取区块链对象、UTXO集对象
+	chain := blockchain.ContinueBlockChain(nodeID)
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+	defer chain.Database.Close()
+
+	// 从钱包文件中获取钱包集合，并通过地址获取具体钱包对象
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		zap.L().Error("wallet.CreateWallets()", zap.Error(err))
+		return
+	}
+
+	// 交易广播后才会被打包，可以花费钱包中尚未打包的交易的找零
+	if !mineNow {
+		useUnconfirmed(chain, wallets, &UTXOSet)
+	}
+
+	// 只构造未签名的交易，交给持有私钥的一方签名
+	if unsigned {
+		cli.sendUnsigned(wallets, from, payments, &UTXOSet, cc)
+		return
+	}
+
+	w, err := wallets.GetSigningWallet(from)
+	if err != nil {
+		fmt.Println(err)
+		if errors.Is(err, wallet.ErrWatchOnly) {
+			fmt.Println("Use -unsigned to build an unsigned transaction for offline signing")
+		}
+		return
+	}
+
+	// 创建交易对象
+	tx, err := blockchain.NewMultiOutputTransaction(w, payments, &UTXOSet, cc)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// 根据mineNow标记判断交易的处理方法
+	if mineNow {
+		// 将所有交易打包到候选区块中，开始挖矿
+		cbTx := blockchain.CoinbaseTx(from, "")
+		txs := []*blockchain.Transaction{cbTx, tx}
+		block := chain.MineBlock(txs)
+
+		//更新UTXO集合与钱包交易记录
+		UTXOSet.Update(block)
+		syncWalletTxs(chain, nodeID)
+	} else {
+		//广播交易
+		network.SendTx(network.KnownNodes[0], tx)
+		recordPendingTx(chain, nodeID, tx)
+		fmt.Printf("Transaction %x broadcast\n", tx.ID)
+	}
+
+	fmt.Println("Success!")
+}
+
+// sendFromWallet 从钱包中所有地址的UTXO中选币付款，找零发送到新的找零地址
+func (cli *CommandLine) sendFromWallet(payments []blockchain.Payment, nodeID string, mineNow, unsigned bool, cc *blockchain.CoinControl) {
+	// 获取区块链对象、UTXO集对象
+	chain := blockchain.ContinueBlockChain(nodeID)
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+	defer chain.Database.Close()
+
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		zap.L().Error("wallet.CreateWallets()", zap.Error(err))
+		return
+	}
+
+	// 交易广播后才会被打包，可以花费钱包中尚未打包的交易的找零
+	if !mineNow {
+		useUnconfirmed(chain, wallets, &UTXOSet)
+	}
+
+	var tx *blockchain.Transaction
+	if unsigned {
+		tx, err = blockchain.FundWalletTransaction(wallets, payments, &UTXOSet, cc)
+	} else {
+		tx, err = blockchain.NewWalletTransaction(wallets, payments, &UTXOSet, cc)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// 保存新派生的找零地址
+	wallets.SaveFile(nodeID)
+
+	fromAddresses := blockchain.InputAddresses(wallets, tx)
+	fmt.Printf("Spending from %s\n", strings.Join(fromAddresses, ", "))
+
+	if unsigned {
+		fmt.Printf("Unsigned transaction %x:\n%x\n", tx.ID, tx.Serialize())
+		return
+	}
+
+	// 根据mineNow标记判断交易的处理方法
+	if mineNow {
+		// 将所有交易打包到候选区块中，挖矿奖励发给第一个付款地址
+		cbTx := blockchain.CoinbaseTx(fromAddresses[0], "")
+		txs := []*blockchain.Transaction{cbTx, tx}
+		block := chain.MineBlock(txs)
+
+		//更新UTXO集合与钱包交易记录
+		UTXOSet.Update(block)
+		syncWalletTxs(chain, nodeID)
+	} else {
+		//广播交易
+		network.SendTx(network.KnownNodes[0], tx)
+		recordPendingTx(chain, nodeID, tx)
+		fmt.Printf("Transaction %x broadcast\n", tx.ID)
+	}
+
+	fmt.Println("Success!")
+}
+
+// sendUnsigned 构造未签名的交易并以十六进制输出
+func (cli *CommandLine) sendUnsigned(wallets *wallet.Wallets, from string, payments []blockchain.Payment, UTXOSet *blockchain.UTXOSet, cc *blockchain.CoinControl) {
+	// 见证结构中填入已知的公钥，只导入地址时公钥为空
+	var pubKey []byte
+	if w, ok := wallets.Wallets[from]; ok {
+		pubKey = w.PublicKey
+	} else if watch, ok := wallets.WatchOnly[from]; ok {
+		pubKey = watch.PublicKey
+	}
+
+	tx, err := blockchain.NewUnsignedMultiOutputTransaction(from, pubKey, payments, UTXOSet, cc)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("Unsigned transaction %x:\n%x\n", tx.ID, tx.Serialize())
+}
+
+// coinControlFlags 为命令注册选币相关参数，返回的函数在参数解析后构造选币控制
+func coinControlFlags(cmd *flag.FlagSet) func() (*blockchain.CoinControl, error) {
+	coinSelect := cmd.String("coinselect", "greedy", "Coin selection strategy: greedy, bnb, largest, smallest or random")
+	feeRate := cmd.Int("feerate", 0, "Fee rate per byte of transaction size")
+	rbf := cmd.Bool("rbf", false, "Signal that the transaction may be replaced by one paying a higher fee")
+	var pinned, excluded outPointList
+	cmd.Var(&pinned, "utxo", "Outpoint txid:vout that must be spent (repeatable)")
+	cmd.Var(&excluded, "exclude", "Outpoint txid:vout that must not be spent (repeatable)")
+
+	return func() (*blockchain.CoinControl, error) {
+		selector, err := blockchain.NewCoinSelector(*coinSelect)
+		if err != nil {
+			return nil, err
+		}
+		if *feeRate < 0 {
+			return nil, errors.New("fee rate must not be negative")
+		}
+
+		return &blockchain.CoinControl{Selector: selector, FeeRate: *feeRate, Pinned: pinned, Excluded: excluded, Replaceable: *rbf}, nil
+	}
+}
+
+// outPointList 可重复使用的输出点参数，每次可传入以逗号分隔的多个 txid:vout
+type outPointList []blockchain.OutPoint
+
+func (l *outPointList) String() string {
+	points := make([]string, len(*l))
+	for i, op := range *l {
+		points[i] = op.String()
+	}
+
+	return strings.Join(points, ",")
+}
+
+func (l *outPointList) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		op, err := blockchain.ParseOutPoint(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		*l = append(*l, op)
+	}
+
+	return nil
+}
+
+// importAddress 导入只读地址
+func (cli *CommandLine) importAddress(nodeID, address string) {
+	wallets, _ := wallet.CreateWallets(nodeID)
+	if err := wallets.ImportAddress(address); err != nil {
+		fmt.Println(err)
+		return
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("Imported watch-only address %s\n", address)
+}
+
+// importPubKey 导入只读公钥
+func (cli *CommandLine) importPubKey(nodeID, pubKeyHex string) {
+	pubKey, err := hex.DecodeString(pubKeyHex)
+	if err != nil {
+		fmt.Println("Public key must be hex encoded")
+		return
+	}
+
+	wallets, _ := wallet.CreateWallets(nodeID)
+	address, err := wallets.ImportPublicKey(pubKey)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("Imported watch-only address %s\n", address)
+}
+
+// dumpPrivKey 以WIF格式导出地址对应的私钥
+func (cli *CommandLine) dumpPrivKey(nodeID, address string) {
+	address, ok := normalizeAddress(address)
+	if !ok {
+		return
+	}
+
+	wallets, _ := wallet.CreateWallets(nodeID)
+	wif, err := wallets.DumpPrivateKey(address)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(wif)
+	if wallets.Wallets[address].IsSchnorr() {
+		fmt.Println("This is a Schnorr address, use importprivkey -schnorr to import it")
+	}
+}
+
+// signMessage 使用地址对应的私钥对消息签名
+func (cli *CommandLine) signMessage(nodeID, address, message string) {
+	address, ok := normalizeAddress(address)
+	if !ok {
+		return
+	}
+
+	wallets, _ := wallet.CreateWallets(nodeID)
+	w, err := wallets.GetSigningWallet(address)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	signature, err := w.SignMessage(message)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(signature)
+}
+
+// verifyMessage 验证消息签名，不需要钱包与区块链数据
+func (cli *CommandLine) verifyMessage(address, signature, message string) {
+	valid, err := wallet.VerifyMessage(address, signature, message)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if !valid {
+		fmt.Printf("Signature is NOT valid for %s\n", address)
+		return
+	}
+	fmt.Printf("Signature is valid for %s\n", address)
+}
+
+// importPrivKey 导入WIF格式的私钥，rescan为真时扫描区块链中属于该地址的输出
+func (cli *CommandLine) importPrivKey(nodeID, wif string, schnorr, rescan bool) {
+	wallets, _ := wallet.CreateWallets(nodeID)
+	address, err := wallets.ImportPrivateKey(wif, schnorr)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("Imported address %s\n", address)
+
+	if rescan && blockchain.BlockChainExists(nodeID) {
+		chain := blockchain.ContinueBlockChain(nodeID)
+		UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+		defer chain.Database.Close()
+
+		pubKeyHash := wallet.PublicKeyHash(wallets.Wallets[address].PublicKey)
+		history := chain.FindAddressHistory(pubKeyHash)
+		balance := 0
+		for _, out := range UTXOSet.FindAddressBalance(pubKeyHash) {
+			balance += out.Value
+		}
+
+		fmt.Printf("Rescan found %d transactions, balance %d\n", len(history), balance)
+	}
+}
+
+// getHistory 列出与地址相关的所有交易
+func (cli *CommandLine) getHistory(address, nodeID string) {
+	address, ok := normalizeAddress(address)
+	if !ok {
+		return
+	}
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	pubKeyHash := blockchain.NewTXOutput(0, address).PubKeyHash
+
+	wallets, _ := wallet.CreateWallets(nodeID)
+	if wallets.IsWatchOnly(address) {
+		fmt.Printf("History of %s [watch-only]:\n", address)
+	} else {
+		fmt.Printf("History of %s:\n", address)
+	}
+
+	for _, entry := range chain.FindAddressHistory(pubKeyHash) {
+		fmt.Printf("%x  height %d  %s  received %d  sent %d\n",
+			entry.TxID, entry.Height, time.Unix(entry.Timestamp, 0).Format(time.RFC3339), entry.Received, entry.Sent)
 	}
 }
 
-// send 转账交易
-func (cli *CommandLine) send(from, to string, amount int, nodeID string, mineNow bool) {
-	//判断参与转账的地址的有效性
-	if !wallet.ValidateAddress(to) {
-		zap.L().Error("To-Address is not Valid")
+// timestamp 将文件哈希写入交易的数据输出中，为文件存证
+func (cli *CommandLine) timestamp(file, from, nodeID string, mineNow bool) {
+	from, ok := normalizeAddress(from)
+	if !ok {
 		return
 	}
-	if !wallet.ValidateAddress(from) {
-		zap.L().Error("From-Address is not Valid")
+
+	fileHash, err := hashFile(file)
+	if err != nil {
+		zap.L().Error("hashFile() failed", zap.Error(err))
 		return
 	}
 
 	// 获取区块链对象、UTXO集对象
 	chain := blockchain.ContinueBlockChain(nodeID)
-	UTXOSet := blockchain.UTXOSet{chain}
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
 	defer chain.Database.Close()
 
-	// 从钱包文件中获取钱包集合，并通过地址获取具体钱包对象
 	wallets, err := wallet.CreateWallets(nodeID)
 	if err != nil {
 		zap.L().Error("wallet.CreateWallets()", zap.Error(err))
 		return
 	}
-	wallet := wallets.GetWallet(from)
+	wallet, err := wallets.GetSigningWallet(from)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
-	// 创建交易对象
-	tx := blockchain.NewTransaction(&wallet, to, amount, &UTXOSet)
+	// 创建携带文件哈希的交易
+	tx, err := blockchain.NewDataTransaction(wallet, fileHash, &UTXOSet)
+	if err != nil {
+		zap.L().Error("blockchain.NewDataTransaction() failed", zap.Error(err))
+		return
+	}
 
-	// 根据mineNow标记判断交易的处理方法
 	if mineNow {
-		// 将所有交易打包到候选区块中，开始挖矿
 		cbTx := blockchain.CoinbaseTx(from, "")
 		txs := []*blockchain.Transaction{cbTx, tx}
 		block := chain.MineBlock(txs)
 
-		//更新UTXO集合
 		UTXOSet.Update(block)
+		syncWalletTxs(chain, nodeID)
 	} else {
-		//广播交易
-		fmt.Println("send tx")
+		network.SendTx(network.KnownNodes[0], tx)
+		recordPendingTx(chain, nodeID, tx)
 	}
 
-	fmt.Println("Success!")
+	fmt.Printf("File hash %x anchored in transaction %x\n", fileHash, tx.ID)
+}
+
+// verifyTimestamp 查找文件哈希所在的交易，并验证其默克尔证明与出块时间
+func (cli *CommandLine) verifyTimestamp(file, nodeID string) {
+	fileHash, err := hashFile(file)
+	if err != nil {
+		zap.L().Error("hashFile() failed", zap.Error(err))
+		return
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	block, tx, err := chain.FindDataCarrier(fileHash)
+	if err != nil {
+		fmt.Printf("File hash %x is not timestamped\n", fileHash)
+		return
+	}
+
+	// 通过默克尔证明与工作量证明确认交易确实被该区块打包
+	proof, err := block.MerkleProof(tx.ID)
+	if err != nil {
+		zap.L().Error("block.MerkleProof() failed", zap.Error(err))
+		return
+	}
+	merkleValid := proof.Verify(blockchain.MerkleLeaf(tx), block.MerkleRoot)
+	powValid := blockchain.NewProof(block).Validate()
+
+	fmt.Printf("File hash: %x\n", fileHash)
+	fmt.Printf("Transaction: %x\n", tx.ID)
+	fmt.Printf("Block: %x (height %d)\n", block.Hash, block.Height)
+	fmt.Printf("Block time: %s\n", time.Unix(block.Timestamp, 0).UTC().Format(time.RFC3339))
+	for i, sibling := range proof.Siblings {
+		fmt.Printf("Merkle path %d: %x (left: %s)\n", i, sibling, strconv.FormatBool(proof.IsLeft[i]))
+	}
+	fmt.Printf("Merkle proof: %s\n", strconv.FormatBool(merkleValid))
+	fmt.Printf("PoW: %s\n", strconv.FormatBool(powValid))
+}
+
+// hashFile 计算文件内容的SHA-256哈希
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return nil, err
+	}
+
+	return hasher.Sum(nil), nil
 }
 
 // getBalance 获取当前地址还有多少UTXO
 func (cli *CommandLine) getBalance(address, nodeID string) {
-	if !wallet.ValidateAddress(address) {
-		log.Panic("Address is not Valid")
+	address, ok := normalizeAddress(address)
+	if !ok {
+		return
 	}
 	chain := blockchain.ContinueBlockChain(nodeID)
-	UTXOSet := blockchain.UTXOSet{chain}
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
 	defer chain.Database.Close()
 
 	balance := 0
-	pubKeyHash := wallet.Base58Decode([]byte(address))
-	pubKeyHash = pubKeyHash[1 : len(pubKeyHash)-4]
+	pubKeyHash := blockchain.NewTXOutput(0, address).PubKeyHash
 	UTXOs := UTXOSet.FindAddressBalance(pubKeyHash)
 
 	for _, out := range UTXOs {
 		balance += out.Value
 	}
 
+	// 只读地址在余额后标记
+	wallets, _ := wallet.CreateWallets(nodeID)
+	if wallets.IsWatchOnly(address) {
+		fmt.Printf("Balance of %s: %d [watch-only]\n", address, balance)
+		return
+	}
+
 	fmt.Printf("Balance of %s: %d\n", address, balance)
 }
 
@@ -171,7 +826,7 @@ func (cli *CommandLine) getBalance(address, nodeID string) {
 func (cli *CommandLine) reindexUTXO(nodeID string) {
 	chain := blockchain.ContinueBlockChain(nodeID)
 	defer chain.Database.Close()
-	UTXOSet := blockchain.UTXOSet{chain}
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
 	UTXOSet.Reindex()
 
 	count := UTXOSet.CountTransactions()
@@ -179,18 +834,28 @@ func (cli *CommandLine) reindexUTXO(nodeID string) {
 }
 
 // StartNode 开启节点通信功能
-func (cli *CommandLine) StartNode(nodeID, minerAddress string) {
+func (cli *CommandLine) StartNode(nodeID, minerAddress string, policy network.MiningPolicy) {
 	fmt.Printf("Starting Node %s\n", nodeID)
 
 	//判断钱包是否合法
 	if len(minerAddress) > 0 {
-		if wallet.ValidateAddress(minerAddress) {
+		if _, err := wallet.DecodeAddress(minerAddress, wallet.ActiveNetParams); err == nil {
 			fmt.Println("接收出块奖励的地址为: ", minerAddress)
 		} else {
-			log.Panic("地址格式不合法")
+			log.Panic("地址格式不合法: ", err)
 		}
 	}
-	network.StartServer(nodeID, minerAddress)
+	network.StartServer(nodeID, minerAddress, policy)
+}
+
+// saveMempool 通知本地运行的节点将交易池写入文件，结果输出在节点的日志中
+func (cli *CommandLine) saveMempool(nodeID string) {
+	addr := fmt.Sprintf("localhost:%s", nodeID)
+	if err := network.SendSaveMempool(nodeID); err != nil {
+		fmt.Printf("Node %s is not running: %v\n", addr, err)
+		return
+	}
+	fmt.Printf("Requested %s to save its mempool\n", addr)
 }
 
 // Run 客户端运行客户端
@@ -204,24 +869,129 @@ func (client *CommandLine) Run() {
 		runtime.Goexit()
 	}
 
+	// 每条命令开始前删除已过期的钱包解锁会话，不论该命令是否加载钱包
+	if err := wallet.ExpireUnlockSession(nodeID); err != nil {
+		zap.L().Error("wallet.ExpireUnlockSession() failed", zap.Error(err))
+	}
+
 	// 获取调用的具体方法
 	createWalletCmd := flag.NewFlagSet("createwallet", flag.ExitOnError)
+	getNewAddressCmd := flag.NewFlagSet("getnewaddress", flag.ExitOnError)
+	restoreWalletCmd := flag.NewFlagSet("restorewallet", flag.ExitOnError)
+	encryptWalletCmd := flag.NewFlagSet("encryptwallet", flag.ExitOnError)
+	walletPassphraseCmd := flag.NewFlagSet("walletpassphrase", flag.ExitOnError)
+	walletLockCmd := flag.NewFlagSet("walletlock", flag.ExitOnError)
+	importAddressCmd := flag.NewFlagSet("importaddress", flag.ExitOnError)
+	importPubKeyCmd := flag.NewFlagSet("importpubkey", flag.ExitOnError)
+	getHistoryCmd := flag.NewFlagSet("gethistory", flag.ExitOnError)
+	dumpPrivKeyCmd := flag.NewFlagSet("dumpprivkey", flag.ExitOnError)
+	importPrivKeyCmd := flag.NewFlagSet("importprivkey", flag.ExitOnError)
+	signMessageCmd := flag.NewFlagSet("signmessage", flag.ExitOnError)
+	verifyMessageCmd := flag.NewFlagSet("verifymessage", flag.ExitOnError)
 	createBlockchainCmd := flag.NewFlagSet("createblockchain", flag.ExitOnError)
+	validateAddressCmd := flag.NewFlagSet("validateaddress", flag.ExitOnError)
 	listAddressesCmd := flag.NewFlagSet("listaddresses", flag.ExitOnError)
+	setLabelCmd := flag.NewFlagSet("setlabel", flag.ExitOnError)
+	addressBookCmd := flag.NewFlagSet("addressbook", flag.ExitOnError)
 	printChainCmd := flag.NewFlagSet("printchain", flag.ExitOnError)
 	sendCmd := flag.NewFlagSet("send", flag.ExitOnError)
+	sendManyCmd := flag.NewFlagSet("sendmany", flag.ExitOnError)
+	createPSBTCmd := flag.NewFlagSet("createpsbt", flag.ExitOnError)
+	inspectPSBTCmd := flag.NewFlagSet("inspectpsbt", flag.ExitOnError)
+	signPSBTCmd := flag.NewFlagSet("signpsbt", flag.ExitOnError)
+	combinePSBTCmd := flag.NewFlagSet("combinepsbt", flag.ExitOnError)
+	finalizePSBTCmd := flag.NewFlagSet("finalizepsbt", flag.ExitOnError)
+	broadcastPSBTCmd := flag.NewFlagSet("broadcastpsbt", flag.ExitOnError)
 	getBalanceCmd := flag.NewFlagSet("getbalance", flag.ExitOnError)
+	listTransactionsCmd := flag.NewFlagSet("listtransactions", flag.ExitOnError)
+	rescanBlockchainCmd := flag.NewFlagSet("rescanblockchain", flag.ExitOnError)
+	abandonTransactionCmd := flag.NewFlagSet("abandontransaction", flag.ExitOnError)
+	bumpFeeCmd := flag.NewFlagSet("bumpfee", flag.ExitOnError)
 	reindexUTXOCmd := flag.NewFlagSet("reindexutxo", flag.ExitOnError)
 	startNodeCmd := flag.NewFlagSet("startnode", flag.ExitOnError)
+	saveMempoolCmd := flag.NewFlagSet("savemempool", flag.ExitOnError)
+	timestampCmd := flag.NewFlagSet("timestamp", flag.ExitOnError)
+	verifyTimestampCmd := flag.NewFlagSet("verifytimestamp", flag.ExitOnError)
 
 	// 命令行参数解析与获取
+	createWalletSchnorr := createWalletCmd.Bool("schnorr", false, "Create a wallet that signs with BIP340 Schnorr signatures")
+	createWalletMnemonic := createWalletCmd.Bool("mnemonic", false, "Initialize the wallet seed from a new BIP39 mnemonic")
+	createWalletWords := createWalletCmd.Int("words", 12, "Number of mnemonic words (12 or 24)")
+	createWalletBech32 := createWalletCmd.Bool("bech32", false, "Also print the Bech32 form of the new address")
+	getNewAddressAmount := getNewAddressCmd.Int("amount", 0, "Requested amount (omitted from the URI if 0)")
+	getNewAddressLabel := getNewAddressCmd.String("label", "", "Label of the payment request")
+	getNewAddressMessage := getNewAddressCmd.String("message", "", "Message shown to the payer")
+	getNewAddressSchnorr := getNewAddressCmd.Bool("schnorr", false, "Derive a Schnorr address")
+	validateAddressAddress := validateAddressCmd.String("address", "", "The address to check")
+	restoreWalletGap := restoreWalletCmd.Int("gap", wallet.DefaultGapLimit, "Stop scanning a chain after this many unused addresses")
+	walletPassphraseTimeout := walletPassphraseCmd.Int("timeout", 60, "Seconds to keep the wallet unlocked")
+	importAddressAddress := importAddressCmd.String("address", "", "The watch-only address to import")
+	importPubKeyPubKey := importPubKeyCmd.String("pubkey", "", "The hex encoded watch-only public key to import")
+	getHistoryAddress := getHistoryCmd.String("address", "", "The address to list transactions for")
+	dumpPrivKeyAddress := dumpPrivKeyCmd.String("address", "", "The address whose private key to export")
+	importPrivKeyWIF := importPrivKeyCmd.String("wif", "", "The private key in wallet import format")
+	importPrivKeySchnorr := importPrivKeyCmd.Bool("schnorr", false, "Import the key as a Schnorr address")
+	importPrivKeyRescan := importPrivKeyCmd.Bool("rescan", false, "Rescan the chain for outputs of the imported key")
+	signMessageAddress := signMessageCmd.String("address", "", "The address whose private key signs the message")
+	signMessageMessage := signMessageCmd.String("message", "", "The message to sign")
+	verifyMessageAddress := verifyMessageCmd.String("address", "", "The address that signed the message")
+	verifyMessageSignature := verifyMessageCmd.String("signature", "", "The base64 encoded signature")
+	verifyMessageMessage := verifyMessageCmd.String("message", "", "The signed message")
+	listAddressesLabel := listAddressesCmd.String("label", "", "Only list addresses whose label contains this text")
+	listAddressesPurpose := listAddressesCmd.String("purpose", "", "Only list receive or change addresses")
+	listAddressesWatchOnly := listAddressesCmd.Bool("watchonly", false, "Only list watch-only addresses")
+	listAddressesSort := listAddressesCmd.String("sort", "created", "Sort by created, address, label or balance")
+	setLabelAddress := setLabelCmd.String("address", "", "The wallet or address book address to label")
+	setLabelLabel := setLabelCmd.String("label", "", "The new label (empty to clear)")
+	addressBookAdd := addressBookCmd.String("add", "", "Add an address to the address book")
+	addressBookRemove := addressBookCmd.String("remove", "", "Remove an address from the address book")
+	addressBookLabel := addressBookCmd.String("label", "", "Label of the added address")
 	getBalanceAddress := getBalanceCmd.String("address", "", "The address to get balance for")
+	listTransactionsCount := listTransactionsCmd.Int("count", 0, "Only list the most recent transactions (0 lists all)")
+	rescanBlockchainFrom := rescanBlockchainCmd.Int("from", 0, "Height of the first block to rescan")
+	rescanBlockchainTo := rescanBlockchainCmd.Int("to", -1, "Height of the last block to rescan (-1 for the best block)")
+	abandonTransactionTxID := abandonTransactionCmd.String("txid", "", "The transaction to abandon")
+	bumpFeeTxID := bumpFeeCmd.String("txid", "", "The unconfirmed transaction to replace")
+	bumpFeeFee := bumpFeeCmd.Int("fee", 0, "Total fee of the replacement (default: the minimum the mempool accepts)")
 	createBlockchainAddress := createBlockchainCmd.String("address", "", "The address to send genesis block reward to")
-	sendFrom := sendCmd.String("from", "", "Source wallet address")
+	sendFrom := sendCmd.String("from", "", "Source wallet address (spend from the whole wallet if omitted)")
 	sendTo := sendCmd.String("to", "", "Destination wallet address")
 	sendAmount := sendCmd.Int("amount", 0, "Amount to send")
 	sendMine := sendCmd.Bool("mine", false, "Mine immediately on the same node")
+	sendUnsigned := sendCmd.Bool("unsigned", false, "Only build the transaction and print it unsigned")
+	sendURI := sendCmd.String("uri", "", "Payment request URI providing the destination and amount")
+	sendCoinControl := coinControlFlags(sendCmd)
+	sendManyFrom := sendManyCmd.String("from", "", "Source wallet address (spend from the whole wallet if omitted)")
+	sendManyFile := sendManyCmd.String("file", "", "JSON or CSV file of address/amount pairs")
+	sendManyMine := sendManyCmd.Bool("mine", false, "Mine immediately on the same node")
+	sendManyUnsigned := sendManyCmd.Bool("unsigned", false, "Only build the transaction and print it unsigned")
+	sendManyCoinControl := coinControlFlags(sendManyCmd)
+	createPSBTFrom := createPSBTCmd.String("from", "", "Source wallet address (spend from the whole wallet if omitted)")
+	createPSBTTo := createPSBTCmd.String("to", "", "Destination wallet address")
+	createPSBTAmount := createPSBTCmd.Int("amount", 0, "Amount to send")
+	createPSBTFile := createPSBTCmd.String("file", "", "JSON or CSV file of address/amount pairs instead of -to/-amount")
+	createPSBTOut := createPSBTCmd.String("out", "", "Write the partially signed transaction to this file")
+	createPSBTCoinControl := coinControlFlags(createPSBTCmd)
+	inspectPSBTPSBT := inspectPSBTCmd.String("psbt", "", "Base64 partially signed transaction or a file containing it")
+	signPSBTPSBT := signPSBTCmd.String("psbt", "", "Base64 partially signed transaction or a file containing it")
+	signPSBTSigHash := signPSBTCmd.String("sighash", "ALL", "Signature hash type, e.g. ALL or SINGLE|ANYONECANPAY")
+	signPSBTOut := signPSBTCmd.String("out", "", "Write the partially signed transaction to this file")
+	var combinePSBTPSBTs psbtList
+	combinePSBTCmd.Var(&combinePSBTPSBTs, "psbt", "Partially signed transaction to combine (repeatable)")
+	combinePSBTOut := combinePSBTCmd.String("out", "", "Write the combined partially signed transaction to this file")
+	finalizePSBTPSBT := finalizePSBTCmd.String("psbt", "", "Base64 partially signed transaction or a file containing it")
+	broadcastPSBTPSBT := broadcastPSBTCmd.String("psbt", "", "Base64 partially signed transaction or a file containing it")
+	broadcastPSBTMiner := broadcastPSBTCmd.String("miner", "", "Mine the transaction on this node and send the reward to ADDRESS")
 	startNodeMiner := startNodeCmd.String("miner", "", "Enable mining mode and send reward to ADDRESS")
+	startNodeMineTxs := startNodeCmd.Int("minetxs", network.DefaultMiningPolicy.MinTxs, "Mine when the mempool holds this many transactions (0 disables)")
+	startNodeMineBytes := startNodeCmd.Int("minebytes", network.DefaultMiningPolicy.MinBytes, "Mine when the mempool holds this many bytes (0 disables)")
+	startNodeMaxWait := startNodeCmd.Int("maxwait", 0, "Mine when the oldest mempool transaction has waited this many seconds (0 disables)")
+	startNodeBlockMaxSize := startNodeCmd.Int("blockmaxsize", network.DefaultMiningPolicy.Template.MaxBlockSize, "Maximum total transaction bytes in a mined block")
+	startNodeBlockMaxSigOps := startNodeCmd.Int("blockmaxsigops", network.DefaultMiningPolicy.Template.MaxBlockSigOps, "Maximum signature checks in a mined block")
+	timestampFile := timestampCmd.String("file", "", "The file to timestamp")
+	timestampFrom := timestampCmd.String("from", "", "Source wallet address paying for the transaction")
+	timestampMine := timestampCmd.Bool("mine", false, "Mine immediately on the same node")
+	verifyTimestampFile := verifyTimestampCmd.String("file", "", "The file to verify")
 
 	// 判断调用的方法类型
 	switch os.Args[1] {
@@ -230,11 +1000,81 @@ func (client *CommandLine) Run() {
 		if err != nil {
 			log.Panic(err)
 		}
+	case "getnewaddress":
+		err := getNewAddressCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "restorewallet":
+		err := restoreWalletCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "encryptwallet":
+		err := encryptWalletCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "walletpassphrase":
+		err := walletPassphraseCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "walletlock":
+		err := walletLockCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "importaddress":
+		err := importAddressCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "importpubkey":
+		err := importPubKeyCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "dumpprivkey":
+		err := dumpPrivKeyCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "importprivkey":
+		err := importPrivKeyCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "signmessage":
+		err := signMessageCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "verifymessage":
+		err := verifyMessageCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "gethistory":
+		err := getHistoryCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
 	case "listaddresses":
 		err := listAddressesCmd.Parse(os.Args[2:])
 		if err != nil {
 			log.Panic(err)
 		}
+	case "setlabel":
+		err := setLabelCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "addressbook":
+		err := addressBookCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
 	case "createblockchain":
 		err := createBlockchainCmd.Parse(os.Args[2:])
 		if err != nil {
@@ -250,6 +1090,46 @@ func (client *CommandLine) Run() {
 		if err != nil {
 			log.Panic(err)
 		}
+	case "sendmany":
+		err := sendManyCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "createpsbt":
+		err := createPSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "inspectpsbt":
+		err := inspectPSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "signpsbt":
+		err := signPSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "combinepsbt":
+		err := combinePSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "finalizepsbt":
+		err := finalizePSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "broadcastpsbt":
+		err := broadcastPSBTCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "validateaddress":
+		err := validateAddressCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
 	case "reindexutxo":
 		err := reindexUTXOCmd.Parse(os.Args[2:])
 		if err != nil {
@@ -260,6 +1140,46 @@ func (client *CommandLine) Run() {
 		if err != nil {
 			log.Panic(err)
 		}
+	case "listtransactions":
+		err := listTransactionsCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "rescanblockchain":
+		err := rescanBlockchainCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "abandontransaction":
+		err := abandonTransactionCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "bumpfee":
+		err := bumpFeeCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "timestamp":
+		err := timestampCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "verifytimestamp":
+		err := verifyTimestampCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "startnode":
+		err := startNodeCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
+	case "savemempool":
+		err := saveMempoolCmd.Parse(os.Args[2:])
+		if err != nil {
+			log.Panic(err)
+		}
 	default:
 		fmt.Println("方法调用错误")
 		runtime.Goexit()
@@ -267,10 +1187,30 @@ func (client *CommandLine) Run() {
 
 	if getBalanceCmd.Parsed() {
 		if *getBalanceAddress == "" {
-			getBalanceCmd.Usage()
+			client.getWalletBalance(nodeID)
+		} else {
+			client.getBalance(*getBalanceAddress, nodeID)
+		}
+	}
+	if listTransactionsCmd.Parsed() {
+		client.listTransactions(nodeID, *listTransactionsCount)
+	}
+	if rescanBlockchainCmd.Parsed() {
+		client.rescanBlockchain(nodeID, *rescanBlockchainFrom, *rescanBlockchainTo)
+	}
+	if abandonTransactionCmd.Parsed() {
+		if *abandonTransactionTxID == "" {
+			abandonTransactionCmd.Usage()
+			runtime.Goexit()
+		}
+		client.abandonTransaction(nodeID, *abandonTransactionTxID)
+	}
+	if bumpFeeCmd.Parsed() {
+		if *bumpFeeTxID == "" || *bumpFeeFee < 0 {
+			bumpFeeCmd.Usage()
 			runtime.Goexit()
 		}
-		client.getBalance(*getBalanceAddress, nodeID)
+		client.bumpFee(nodeID, *bumpFeeTxID, *bumpFeeFee)
 	}
 
 	if createBlockchainCmd.Parsed() {
@@ -286,22 +1226,256 @@ func (client *CommandLine) Run() {
 	}
 
 	if createWalletCmd.Parsed() {
-		client.createWallet(nodeID)
+		client.createWallet(nodeID, *createWalletSchnorr, *createWalletMnemonic, *createWalletBech32, *createWalletWords)
+	}
+	if getNewAddressCmd.Parsed() {
+		if *getNewAddressAmount < 0 {
+			getNewAddressCmd.Usage()
+			runtime.Goexit()
+		}
+		client.getNewAddress(nodeID, *getNewAddressAmount, *getNewAddressLabel, *getNewAddressMessage, *getNewAddressSchnorr)
+	}
+	if restoreWalletCmd.Parsed() {
+		client.restoreWallet(nodeID, *restoreWalletGap)
+	}
+	if encryptWalletCmd.Parsed() {
+		client.encryptWallet(nodeID)
+	}
+	if walletPassphraseCmd.Parsed() {
+		if *walletPassphraseTimeout <= 0 {
+			walletPassphraseCmd.Usage()
+			runtime.Goexit()
+		}
+		client.walletPassphrase(nodeID, *walletPassphraseTimeout)
+	}
+	if walletLockCmd.Parsed() {
+		client.walletLock(nodeID)
+	}
+	if importAddressCmd.Parsed() {
+		if *importAddressAddress == "" {
+			importAddressCmd.Usage()
+			runtime.Goexit()
+		}
+		client.importAddress(nodeID, *importAddressAddress)
+	}
+	if importPubKeyCmd.Parsed() {
+		if *importPubKeyPubKey == "" {
+			importPubKeyCmd.Usage()
+			runtime.Goexit()
+		}
+		client.importPubKey(nodeID, *importPubKeyPubKey)
+	}
+	if dumpPrivKeyCmd.Parsed() {
+		if *dumpPrivKeyAddress == "" {
+			dumpPrivKeyCmd.Usage()
+			runtime.Goexit()
+		}
+		client.dumpPrivKey(nodeID, *dumpPrivKeyAddress)
+	}
+	if importPrivKeyCmd.Parsed() {
+		if *importPrivKeyWIF == "" {
+			importPrivKeyCmd.Usage()
+			runtime.Goexit()
+		}
+		client.importPrivKey(nodeID, *importPrivKeyWIF, *importPrivKeySchnorr, *importPrivKeyRescan)
+	}
+	if signMessageCmd.Parsed() {
+		if *signMessageAddress == "" {
+			signMessageCmd.Usage()
+			runtime.Goexit()
+		}
+		client.signMessage(nodeID, *signMessageAddress, *signMessageMessage)
+	}
+	if verifyMessageCmd.Parsed() {
+		if *verifyMessageAddress == "" || *verifyMessageSignature == "" {
+			verifyMessageCmd.Usage()
+			runtime.Goexit()
+		}
+		client.verifyMessage(*verifyMessageAddress, *verifyMessageSignature, *verifyMessageMessage)
+	}
+	if getHistoryCmd.Parsed() {
+		if *getHistoryAddress == "" {
+			getHistoryCmd.Usage()
+			runtime.Goexit()
+		}
+		client.getHistory(*getHistoryAddress, nodeID)
 	}
 	if listAddressesCmd.Parsed() {
-		client.listAddresses(nodeID)
+		filter := wallet.AddressFilter{Label: *listAddressesLabel}
+		if *listAddressesPurpose != "" {
+			purpose, err := wallet.ParseAddressPurpose(*listAddressesPurpose)
+			if err != nil {
+				fmt.Println(err)
+				listAddressesCmd.Usage()
+				runtime.Goexit()
+			}
+			filter.Purpose = purpose
+		}
+		if *listAddressesWatchOnly {
+			filter.WatchOnly = listAddressesWatchOnly
+		}
+		if !addressSortKeys[*listAddressesSort] {
+			listAddressesCmd.Usage()
+			runtime.Goexit()
+		}
+		client.listAddresses(nodeID, filter, *listAddressesSort)
+	}
+	if setLabelCmd.Parsed() {
+		if *setLabelAddress == "" {
+			setLabelCmd.Usage()
+			runtime.Goexit()
+		}
+		client.setLabel(nodeID, *setLabelAddress, *setLabelLabel)
+	}
+	if addressBookCmd.Parsed() {
+		if *addressBookAdd != "" && *addressBookRemove != "" {
+			addressBookCmd.Usage()
+			runtime.Goexit()
+		}
+		client.addressBook(nodeID, *addressBookAdd, *addressBookRemove, *addressBookLabel)
 	}
 	if reindexUTXOCmd.Parsed() {
 		client.reindexUTXO(nodeID)
 	}
 
 	if sendCmd.Parsed() {
-		if *sendFrom == "" || *sendTo == "" || *sendAmount <= 0 {
+		if *sendURI != "" {
+			request, err := wallet.ParsePaymentURI(*sendURI)
+			if err != nil {
+				fmt.Println(err)
+				runtime.Goexit()
+			}
+			// URI与命令行参数同时给出且不一致时，无法判断哪一个才是付款方的本意
+			if (*sendTo != "" && *sendTo != request.Address) || (*sendAmount > 0 && request.Amount > 0 && *sendAmount != request.Amount) {
+				fmt.Println("The -to/-amount flags conflict with the payment request URI")
+				runtime.Goexit()
+			}
+
+			*sendTo = request.Address
+			if request.Amount > 0 {
+				*sendAmount = request.Amount
+			}
+			if request.Label != "" {
+				fmt.Printf("Paying %s\n", request.Label)
+			}
+			if request.Message != "" {
+				fmt.Printf("Message: %s\n", request.Message)
+			}
+		}
+		if *sendTo == "" || *sendAmount <= 0 {
+			sendCmd.Usage()
+			runtime.Goexit()
+		}
+
+		cc, err := sendCoinControl()
+		if err != nil {
+			fmt.Println(err)
 			sendCmd.Usage()
 			runtime.Goexit()
 		}
 
-		client.send(*sendFrom, *sendTo, *sendAmount, nodeID, *sendMine)
+		client.send(*sendFrom, *sendTo, *sendAmount, nodeID, *sendMine, *sendUnsigned, cc)
+	}
+
+	if sendManyCmd.Parsed() {
+		if *sendManyFile == "" {
+			sendManyCmd.Usage()
+			runtime.Goexit()
+		}
+		cc, err := sendManyCoinControl()
+		if err != nil {
+			fmt.Println(err)
+			sendManyCmd.Usage()
+			runtime.Goexit()
+		}
+
+		client.sendMany(*sendManyFrom, *sendManyFile, nodeID, *sendManyMine, *sendManyUnsigned, cc)
+	}
+
+	if createPSBTCmd.Parsed() {
+		payments := []blockchain.Payment{{Address: *createPSBTTo, Amount: *createPSBTAmount}}
+		if *createPSBTFile != "" {
+			var err error
+			if payments, err = blockchain.ReadPaymentsFile(*createPSBTFile); err != nil {
+				fmt.Println(err)
+				runtime.Goexit()
+			}
+		} else if *createPSBTTo == "" || *createPSBTAmount <= 0 {
+			createPSBTCmd.Usage()
+			runtime.Goexit()
+		}
+		cc, err := createPSBTCoinControl()
+		if err != nil {
+			fmt.Println(err)
+			createPSBTCmd.Usage()
+			runtime.Goexit()
+		}
+
+		client.createPSBT(*createPSBTFrom, payments, nodeID, *createPSBTOut, cc)
+	}
+
+	if inspectPSBTCmd.Parsed() {
+		if *inspectPSBTPSBT == "" {
+			inspectPSBTCmd.Usage()
+			runtime.Goexit()
+		}
+		client.inspectPSBT(*inspectPSBTPSBT)
+	}
+
+	if signPSBTCmd.Parsed() {
+		if *signPSBTPSBT == "" {
+			signPSBTCmd.Usage()
+			runtime.Goexit()
+		}
+		client.signPSBT(*signPSBTPSBT, *signPSBTSigHash, nodeID, *signPSBTOut)
+	}
+
+	if combinePSBTCmd.Parsed() {
+		if len(combinePSBTPSBTs) < 2 {
+			combinePSBTCmd.Usage()
+			runtime.Goexit()
+		}
+		client.combinePSBT(combinePSBTPSBTs, *combinePSBTOut)
+	}
+
+	if finalizePSBTCmd.Parsed() {
+		if *finalizePSBTPSBT == "" {
+			finalizePSBTCmd.Usage()
+			runtime.Goexit()
+		}
+		client.finalizePSBT(*finalizePSBTPSBT)
+	}
+
+	if broadcastPSBTCmd.Parsed() {
+		if *broadcastPSBTPSBT == "" {
+			broadcastPSBTCmd.Usage()
+			runtime.Goexit()
+		}
+		client.broadcastPSBT(*broadcastPSBTPSBT, nodeID, *broadcastPSBTMiner)
+	}
+
+	if validateAddressCmd.Parsed() {
+		if *validateAddressAddress == "" {
+			validateAddressCmd.Usage()
+			runtime.Goexit()
+		}
+		client.validateAddress(*validateAddressAddress)
+	}
+
+	if timestampCmd.Parsed() {
+		if *timestampFile == "" || *timestampFrom == "" {
+			timestampCmd.Usage()
+			runtime.Goexit()
+		}
+		client.timestamp(*timestampFile, *timestampFrom, nodeID, *timestampMine)
+	}
+
+	if verifyTimestampCmd.Parsed() {
+		if *verifyTimestampFile == "" {
+			verifyTimestampCmd.Usage()
+			runtime.Goexit()
+		}
+		client.verifyTimestamp(*verifyTimestampFile, nodeID)
 	}
 
 	if startNodeCmd.Parsed() {
@@ -310,7 +1484,19 @@ func (client *CommandLine) Run() {
 			startNodeCmd.Usage()
 			runtime.Goexit()
 		}
-		client.StartNode(nodeID, *startNodeMiner)
+		if *startNodeMineTxs < 0 || *startNodeMineBytes < 0 || *startNodeMaxWait < 0 || *startNodeBlockMaxSize <= 0 || *startNodeBlockMaxSigOps <= 0 {
+			startNodeCmd.Usage()
+			runtime.Goexit()
+		}
+		policy := network.DefaultMiningPolicy
+		policy.MinTxs, policy.MinBytes = *startNodeMineTxs, *startNodeMineBytes
+		policy.MaxWait = time.Duration(*startNodeMaxWait) * time.Second
+		policy.Template.MaxBlockSize, policy.Template.MaxBlockSigOps = *startNodeBlockMaxSize, *startNodeBlockMaxSigOps
+		client.StartNode(nodeID, *startNodeMiner, policy)
+	}
+
+	if saveMempoolCmd.Parsed() {
+		client.saveMempool(nodeID)
 	}
 
 }
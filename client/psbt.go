@@ -0,0 +1,233 @@
+package client
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"Golang_Bitcoin_Sample/network"
+	"Golang_Bitcoin_Sample/wallet"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// psbtList 可重复使用的部分签名交易参数
+type psbtList []string
+
+func (l *psbtList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *psbtList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// readPSBT 解析部分签名交易参数，参数可以是Base64文本，也可以是保存它的文件路径
+func readPSBT(arg string) (*blockchain.PSBT, error) {
+	if content, err := ioutil.ReadFile(arg); err == nil {
+		arg = string(content)
+	}
+
+	return blockchain.DecodePSBT(strings.TrimSpace(arg))
+}
+
+// writePSBT 输出部分签名交易，指定out时写入文件，否则打印到标准输出
+func writePSBT(p *blockchain.PSBT, out string) {
+	if out == "" {
+		fmt.Println(p.String())
+		return
+	}
+
+	if err := ioutil.WriteFile(out, []byte(p.String()+"\n"), 0600); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("Partially signed transaction written to %s\n", out)
+}
+
+// createPSBT 构造未签名的交易，并附带被花费的输出，生成可离线签名的部分签名交易
+func (cli *CommandLine) createPSBT(from string, payments []blockchain.Payment, nodeID, out string, cc *blockchain.CoinControl) {
+	if err := blockchain.ValidatePayments(payments); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if from != "" && !wallet.ValidateAddress(from) {
+		fmt.Println("From-Address is not Valid")
+		return
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+	defer chain.Database.Close()
+
+	var tx *blockchain.Transaction
+	var err error
+	if from == "" {
+		// 从整个钱包选币，找零地址需要保存到钱包文件中
+		wallets, _ := wallet.CreateWallets(nodeID)
+		if tx, err = blockchain.FundWalletTransaction(wallets, payments, &UTXOSet, cc); err == nil {
+			wallets.SaveFile(nodeID)
+		}
+	} else {
+		tx, err = blockchain.NewUnsignedMultiOutputTransaction(from, nil, payments, &UTXOSet, cc)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	p, err := chain.CreatePSBT(tx)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	writePSBT(p, out)
+}
+
+// inspectPSBT 展示部分签名交易的输入、输出、手续费与签名进度
+func (cli *CommandLine) inspectPSBT(arg string) {
+	p, err := readPSBT(arg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("Transaction %x\n", p.Tx.ID)
+	for i, in := range p.Tx.Inputs {
+		input := p.Inputs[i]
+		fmt.Printf("  Input %d: %x:%d\n", i, in.ID, in.Out)
+		fmt.Printf("    Spends:     %d (type %d, pubkey hash %x)\n", input.PrevOut.Value, input.PrevOut.Type, input.PrevOut.PubKeyHash)
+		for pubKey, sig := range input.PartialSigs {
+			if len(sig) == 0 {
+				continue
+			}
+			hashType := blockchain.SigHashType(sig[len(sig)-1])
+			fmt.Printf("    Signature:  %s by %s\n", hashType, pubKey)
+		}
+	}
+	for i, out := range p.Tx.Outputs {
+		if out.IsDataCarrier() {
+			fmt.Printf("  Output %d: data %x\n", i, out.Data)
+			continue
+		}
+		fmt.Printf("  Output %d: %d to pubkey hash %x\n", i, out.Value, out.PubKeyHash)
+	}
+	fmt.Printf("Fee:      %d\n", p.Fee())
+	fmt.Printf("Complete: %t\n", p.IsComplete())
+}
+
+// signPSBT 使用钱包中的私钥签署部分签名交易，不需要区块链数据，可以在离线机器上执行
+func (cli *CommandLine) signPSBT(arg, sigHash, nodeID, out string) {
+	p, err := readPSBT(arg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	hashType, err := blockchain.ParseSigHashType(sigHash)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	signed, err := p.SignWithWallets(wallets, hashType)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// 输出到标准输出时只打印部分签名交易本身，便于重定向到文件
+	if out != "" {
+		fmt.Printf("Signed %d of %d inputs, complete: %t\n", signed, len(p.Inputs), p.IsComplete())
+	}
+
+	writePSBT(p, out)
+}
+
+// combinePSBT 合并同一笔交易的多份部分签名
+func (cli *CommandLine) combinePSBT(args []string, out string) {
+	var combined *blockchain.PSBT
+	for _, arg := range args {
+		p, err := readPSBT(arg)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		if combined == nil {
+			combined = p
+			continue
+		}
+		if err := combined.Combine(p); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	writePSBT(combined, out)
+}
+
+// finalizePSBT 生成完整的交易并以十六进制输出
+func (cli *CommandLine) finalizePSBT(arg string) {
+	p, err := readPSBT(arg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	tx, err := p.Finalize()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("Transaction %x:\n%s\n", tx.ID, hex.EncodeToString(tx.Serialize()))
+}
+
+// broadcastPSBT 完成部分签名交易，对照本地区块链验证后广播，指定minerAddress时直接在本节点挖矿打包
+func (cli *CommandLine) broadcastPSBT(arg, nodeID, minerAddress string) {
+	p, err := readPSBT(arg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	tx, err := p.Finalize()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	chain := blockchain.ContinueBlockChain(nodeID)
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+	defer chain.Database.Close()
+
+	// 部分签名交易中携带的输出由创建方提供，广播前确认引用的交易存在，并以区块链中的数据为准再验证一次
+	if _, err := chain.CreatePSBT(tx); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if !chain.VerifyTransaction(tx) {
+		fmt.Println("Transaction is not valid against the local chain")
+		return
+	}
+
+	if minerAddress != "" {
+		cbTx := blockchain.CoinbaseTx(minerAddress, "")
+		block := chain.MineBlock([]*blockchain.Transaction{cbTx, tx})
+
+		UTXOSet.Update(block)
+		syncWalletTxs(chain, nodeID)
+	} else {
+		network.SendTx(network.KnownNodes[0], tx)
+		recordPendingTx(chain, nodeID, tx)
+	}
+
+	fmt.Printf("Transaction %x broadcast\n", tx.ID)
+}
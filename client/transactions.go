@@ -0,0 +1,265 @@
+package client
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"Golang_Bitcoin_Sample/mempool"
+	"Golang_Bitcoin_Sample/network"
+	"Golang_Bitcoin_Sample/wallet"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// syncWalletTxs 将钱包交易记录同步到当前主链，有区块连接或回滚时保存钱包文件
+// 节点进程不写钱包文件，每次命令行读取交易记录前先补齐节点期间收到的区块
+func syncWalletTxs(chain *blockchain.BlockChain, nodeID string) *wallet.Wallets {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		return wallets
+	}
+
+	if disconnected, connected := chain.SyncWallet(wallets); disconnected > 0 || connected > 0 {
+		wallets.SaveFile(nodeID)
+	}
+
+	return wallets
+}
+
+// recordPendingTx 记录钱包广播的未确认交易
+func recordPendingTx(chain *blockchain.BlockChain, nodeID string, tx *blockchain.Transaction) {
+	wallets := syncWalletTxs(chain, nodeID)
+	if chain.AddPendingWalletTx(wallets, tx) {
+		wallets.SaveFile(nodeID)
+	}
+}
+
+// useUnconfirmed 同步钱包交易记录，并将钱包广播但尚未打包的交易加入UTXO集合的视图：
+// 它们花费的输出不再被选用，支付给钱包的输出可以继续花费
+func useUnconfirmed(chain *blockchain.BlockChain, wallets *wallet.Wallets, UTXOSet *blockchain.UTXOSet) {
+	chain.SyncWallet(wallets)
+	UTXOSet.Pending = blockchain.PendingWalletTxs(wallets)
+}
+
+// getWalletBalance 由钱包交易记录计算整个钱包的已确认余额与未确认金额
+func (cli *CommandLine) getWalletBalance(nodeID string) {
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	wallets := syncWalletTxs(chain, nodeID)
+	confirmed, pending := wallets.Transactions().Balance()
+
+	fmt.Printf("Confirmed balance:   %d\n", confirmed)
+	fmt.Printf("Unconfirmed balance: %d\n", pending)
+}
+
+// listTransactions 输出钱包的交易流水：时间、高度、确认数、类别、金额、手续费、累计余额与状态
+// 冲突与已放弃的交易照常列出，但不计入累计余额；count大于0时只输出最近的count笔
+func (cli *CommandLine) listTransactions(nodeID string, count int) {
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	wallets := syncWalletTxs(chain, nodeID)
+	store := wallets.Transactions()
+	bestHeight := store.TipHeight
+
+	txs := store.List()
+	balances := make([]int, len(txs))
+	balance := 0
+	for i, tx := range txs {
+		if tx.Status == wallet.TxConfirmed || tx.Status == wallet.TxPending {
+			balance += tx.Net()
+		}
+		balances[i] = balance
+	}
+
+	start := 0
+	if count > 0 && count < len(txs) {
+		start = len(txs) - count
+	}
+
+	fmt.Printf("%-19s %6s %5s  %-8s %8s %5s %8s  %-10s  %-64s  %s\n", "Date", "Height", "Conf", "Category", "Amount", "Fee", "Balance", "Status", "TxID", "Addresses")
+	for i := start; i < len(txs); i++ {
+		tx := txs[i]
+
+		height := "-"
+		if tx.Height >= 0 {
+			height = fmt.Sprint(tx.Height)
+		}
+		status := string(tx.Status)
+		switch tx.Status {
+		case wallet.TxConflicted:
+			status += " with " + tx.ConflictedBy
+		case wallet.TxReplaced:
+			status += " by " + tx.ReplacedBy
+		}
+
+		var addresses []string
+		for _, address := range tx.Addresses {
+			if label := wallets.GetMeta(address).Label; label != "" {
+				address = fmt.Sprintf("%s (%s)", address, label)
+			}
+			addresses = append(addresses, address)
+		}
+
+		fmt.Printf("%-19s %6s %5d  %-8s %+8d %5d %8d  %-10s  %-64s  %s\n",
+			time.Unix(tx.Timestamp, 0).Format("2006-01-02 15:04:05"), height, tx.Confirmations(bestHeight),
+			tx.Category(), tx.Net(), tx.Fee, balances[i], status, tx.TxID, strings.Join(addresses, ", "))
+	}
+}
+
+// rescanBlockchain 重新扫描主链中指定高度区间的区块，重建钱包交易记录
+// 导入私钥或只读地址后，需要重新扫描才能找回它们之前的交易
+func (cli *CommandLine) rescanBlockchain(nodeID string, from, to int) {
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	found, err := chain.RescanWallet(wallets, from, to)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	wallets.SaveFile(nodeID)
+
+	if to < 0 {
+		to = wallets.Transactions().TipHeight
+	}
+	fmt.Printf("Rescanned blocks %d-%d, found %d wallet transactions\n", from, to, found)
+}
+
+// abandonTransaction 放弃未确认或冲突的交易
+func (cli *CommandLine) abandonTransaction(nodeID, txID string) {
+	wallets, err := wallet.CreateWallets(nodeID)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if err := wallets.Transactions().Abandon(txID); err != nil {
+		fmt.Println(err)
+		return
+	}
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("Transaction %s abandoned\n", txID)
+}
+
+// bumpFee 提高未确认交易的手续费：花费相同的输入构造替换交易，增加的手续费从找零中扣除
+// 钱包中花费原交易输出的未打包交易会被一并替换，替换交易须覆盖它们的手续费
+// fee为0时使用交易池接受替换所需的最低手续费
+func (cli *CommandLine) bumpFee(nodeID, txID string, fee int) {
+	chain := blockchain.ContinueBlockChain(nodeID)
+	defer chain.Database.Close()
+
+	wallets := syncWalletTxs(chain, nodeID)
+	store := wallets.Transactions()
+	wtx, ok := store.Txs[txID]
+	if !ok {
+		fmt.Println(wallet.ErrTxNotFound)
+		return
+	}
+	if wtx.Status != wallet.TxPending || len(wtx.Raw) == 0 {
+		fmt.Printf("Transaction %s is %s or was not broadcast by this wallet, it cannot be replaced\n", txID, wtx.Status)
+		return
+	}
+	orig := blockchain.DeserializeTransaction(wtx.Raw)
+
+	// 原交易尚未打包，它花费的输出仍未被花费，其中可能有钱包中其他未打包交易的输出
+	// 花费原交易输出的子孙交易会随原交易一起被替换
+	pending := blockchain.PendingWalletTxs(wallets)
+	descendants := blockchain.PendingDescendants(pending, orig.ID)
+	replaced := map[string]*blockchain.Transaction{txID: &orig}
+	for _, descendant := range descendants {
+		replaced[hex.EncodeToString(descendant.ID)] = descendant
+	}
+	UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+	for _, tx := range pending {
+		if replaced[hex.EncodeToString(tx.ID)] == nil {
+			UTXOSet.Pending = append(UTXOSet.Pending, tx)
+		}
+	}
+	prevOuts := make([]blockchain.TxOutput, len(orig.Inputs))
+	oldFee := 0
+	for i, in := range orig.Inputs {
+		out, ok := UTXOSet.FindOutput(in.ID, in.Out)
+		if !ok {
+			fmt.Printf("Input %x:%d is no longer unspent\n", in.ID, in.Out)
+			return
+		}
+		prevOuts[i] = out
+		oldFee += out.Value
+	}
+	for _, out := range orig.Outputs {
+		oldFee -= out.Value
+	}
+
+	// 交易池要求替换交易的手续费覆盖原交易及其全部子孙交易
+	replacedFees := oldFee
+	for _, descendant := range descendants {
+		for _, in := range descendant.Inputs {
+			if parent, ok := replaced[hex.EncodeToString(in.ID)]; ok {
+				replacedFees += parent.Outputs[in.Out].Value
+			} else if out, ok := UTXOSet.FindOutput(in.ID, in.Out); ok {
+				replacedFees += out.Value
+			} else {
+				fmt.Printf("Input %x:%d of descendant %x is no longer unspent\n", in.ID, in.Out, descendant.ID)
+				return
+			}
+		}
+		for _, out := range descendant.Outputs {
+			replacedFees -= out.Value
+		}
+	}
+
+	policy := mempool.DefaultPolicy
+	oldSize := len(orig.Serialize())
+	newFee := fee
+	if newFee == 0 {
+		newFee = policy.MinReplacementFee(replacedFees, oldSize)
+	}
+
+	// 重新签名后交易大小可能变化，默认手续费不满足替换规则时逐步提高
+	var tx *blockchain.Transaction
+	for {
+		var err error
+		tx, err = blockchain.BumpFeeTransaction(wallets, &orig, prevOuts, newFee)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+
+		size := len(tx.Serialize())
+		minFee := policy.MinReplacementFee(replacedFees, size)
+		if newFee >= minFee && newFee*oldSize > oldFee*size {
+			break
+		}
+		if fee > 0 {
+			fmt.Printf("Fee %d is too low to replace the transaction, at least %d is required\n", fee, minFee)
+			return
+		}
+		newFee++
+	}
+
+	network.SendTx(network.KnownNodes[0], tx)
+
+	replacement := hex.EncodeToString(tx.ID)
+	for id := range replaced {
+		if err := store.MarkReplaced(id, replacement); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	chain.AddPendingWalletTx(wallets, tx)
+	wallets.SaveFile(nodeID)
+
+	fmt.Printf("Replaced %s with %s, fee %d -> %d\n", txID, replacement, oldFee, newFee)
+	if len(descendants) > 0 {
+		fmt.Printf("Also replaced %d descendant transactions with a total fee of %d\n", len(descendants), replacedFees-oldFee)
+	}
+}
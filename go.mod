@@ -3,9 +3,11 @@ module Golang_Bitcoin_Sample
 go 1.18
 
 require (
+	github.com/btcsuite/btcd/btcec/v2 v2.3.2
 	github.com/dgraph-io/badger v1.5.4
 	github.com/mr-tron/base58 v1.2.0
 	github.com/natefinch/lumberjack v2.0.0+incompatible
+	github.com/tyler-smith/go-bip39 v1.1.0
 	github.com/vrecan/death/v3 v3.0.3
 	go.uber.org/zap v1.24.0
 	golang.org/x/crypto v0.6.0
@@ -14,6 +16,9 @@ require (
 require (
 	github.com/AndreasBriese/bbloom v0.0.0-20190825152654-46b345b51c96 // indirect
 	github.com/BurntSushi/toml v1.2.1 // indirect
+	github.com/btcsuite/btcd/chaincfg/chainhash v1.0.1 // indirect
+	github.com/decred/dcrd/crypto/blake256 v1.0.0 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
 	github.com/dgryski/go-farm v0.0.0-20190423205320-6a90982ecee2 // indirect
 	github.com/golang/protobuf v1.3.1 // indirect
 	github.com/pkg/errors v0.8.1 // indirect
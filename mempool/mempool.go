@@ -0,0 +1,533 @@
+package mempool
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 交易被拒绝的原因，通过 errors.Is 判断
+var (
+	ErrAlreadyInPool     = errors.New("transaction already in mempool")
+	ErrCoinbase          = errors.New("coinbase transactions are only valid in blocks")
+	ErrMalformed         = errors.New("malformed transaction")
+	ErrMissingInputs     = errors.New("spends an unknown or already spent output")
+	ErrDoubleSpend       = errors.New("spends an output already spent by a mempool transaction")
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrInsufficientInput = errors.New("outputs exceed inputs")
+	ErrTxTooLarge        = errors.New("transaction too large")
+	ErrPoolFull          = errors.New("mempool is full")
+
+	ErrReplacementFee      = errors.New("replacement fee too low")
+	ErrReplacementInputs   = errors.New("replacement spends new unconfirmed outputs")
+	ErrTooManyReplacements = errors.New("replacement evicts too many transactions")
+
+	ErrTooManyAncestors   = errors.New("too many unconfirmed ancestors")
+	ErrTooManyDescendants = errors.New("too many unconfirmed descendants")
+)
+
+// Policy 交易池的准入限制
+type Policy struct {
+	MaxTxs    int // 交易数量上限
+	MaxBytes  int // 全部交易序列化后的总字节数上限
+	MaxTxSize int // 单笔交易序列化后的字节数上限
+
+	IncrementalRelayFee     int // 替换交易按自身大小每千字节至少多付的手续费
+	MaxReplacementEvictions int // 一笔替换交易最多移出交易池的交易数（含子孙交易）
+
+	MaxAncestors   int // 交易池中一笔交易的祖先交易数上限（含自身）
+	MaxDescendants int // 交易池中一笔交易的子孙交易数上限（含自身）
+
+	Expiry time.Duration // 交易在交易池中的最长停留时间，超时的交易在新交易加入、区块连接或重新加载交易池文件时被移出，0表示不过期
+}
+
+// DefaultPolicy 默认的准入限制
+var DefaultPolicy = Policy{
+	MaxTxs:    5000,
+	MaxBytes:  5 << 20,
+	MaxTxSize: 100 << 10,
+
+	IncrementalRelayFee:     1,
+	MaxReplacementEvictions: 100,
+
+	MaxAncestors:   25,
+	MaxDescendants: 25,
+
+	Expiry: 14 * 24 * time.Hour,
+}
+
+// MinReplacementFee 替换交易至少需要支付的手续费：被替换交易的手续费总额，加上为自身大小支付的增量手续费
+func (p Policy) MinReplacementFee(replacedFees, size int) int {
+	return replacedFees + (p.IncrementalRelayFee*size+999)/1000
+}
+
+// UTXOView 验证交易时查询已确认的未花费输出
+type UTXOView interface {
+	FindOutput(txID []byte, index int) (blockchain.TxOutput, bool)
+}
+
+// TxDesc 交易池中的一笔交易
+type TxDesc struct {
+	Tx       *blockchain.Transaction
+	Size     int       // 序列化后的字节数
+	Fee      int       // 手续费：输入总额减去输出总额
+	SigOps   int       // 签名检查次数
+	Added    time.Time // 进入交易池的时间
+	Depends  []string  // 仍在交易池中的父交易ID
+	Replaces []string  // 被该交易替换而移出交易池的交易ID
+	seq      uint64    // 进入交易池的顺序，父交易总是先于子交易
+}
+
+// Mempool 并发安全的交易池
+type Mempool struct {
+	mu     sync.RWMutex
+	policy Policy
+	txs    map[string]*TxDesc // 交易ID -> 交易
+	spent  map[string]string  // 被交易池中交易花费的输出 txid:vout -> 花费它的交易ID
+	bytes  int
+	seq    uint64
+}
+
+// New 创建交易池
+func New(policy Policy) *Mempool {
+	return &Mempool{
+		policy: policy,
+		txs:    make(map[string]*TxDesc),
+		spent:  make(map[string]string),
+	}
+}
+
+// Add 验证交易并加入交易池
+// 输入可以引用已确认的未花费输出，也可以引用交易池中其他交易的输出；任何一项检查失败时交易池保持不变
+func (mp *Mempool) Add(tx *blockchain.Transaction, view UTXOView) (*TxDesc, error) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.expire()
+	desc, err := mp.check(tx, view)
+	if err != nil {
+		return nil, fmt.Errorf("reject transaction %x: %w", tx.ID, err)
+	}
+
+	for _, id := range desc.Replaces {
+		mp.remove(id)
+	}
+	mp.insert(desc)
+
+	return desc, nil
+}
+
+// check 按准入规则检查交易，返回待加入的交易信息
+func (mp *Mempool) check(tx *blockchain.Transaction, view UTXOView) (*TxDesc, error) {
+	id := hex.EncodeToString(tx.ID)
+	if _, ok := mp.txs[id]; ok {
+		return nil, ErrAlreadyInPool
+	}
+	if tx.IsCoinbaseTx() {
+		return nil, ErrCoinbase
+	}
+	if len(tx.Inputs) == 0 || len(tx.Outputs) == 0 {
+		return nil, ErrMalformed
+	}
+
+	size := len(tx.Serialize())
+	if size > mp.policy.MaxTxSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTxTooLarge, size)
+	}
+
+	outTotal := 0
+	for _, out := range tx.Outputs {
+		if out.Value < 0 {
+			return nil, fmt.Errorf("%w: negative output value", ErrMalformed)
+		}
+		outTotal += out.Value
+	}
+
+	// 查找每个输入花费的输出：先查交易池中的父交易，再查已确认的UTXO集合
+	prevOuts := make([]blockchain.TxOutput, len(tx.Inputs))
+	seen := make(map[string]bool, len(tx.Inputs))
+	depends := make(map[string]bool)
+	conflicts := make(map[string]bool)
+	inTotal := 0
+	for i, in := range tx.Inputs {
+		outPoint := outPointKey(in.ID, in.Out)
+		if seen[outPoint] {
+			return nil, fmt.Errorf("%w: duplicate input %s", ErrMalformed, outPoint)
+		}
+		seen[outPoint] = true
+
+		if spender, ok := mp.spent[outPoint]; ok {
+			if !mp.txs[spender].Tx.SignalsReplacement() {
+				return nil, fmt.Errorf("%w: %s is spent by %s, which does not signal replaceability", ErrDoubleSpend, outPoint, spender)
+			}
+			conflicts[spender] = true
+		}
+
+		parentID := hex.EncodeToString(in.ID)
+		if parent, ok := mp.txs[parentID]; ok {
+			if in.Out < 0 || in.Out >= len(parent.Tx.Outputs) || parent.Tx.Outputs[in.Out].IsDataCarrier() {
+				return nil, fmt.Errorf("%w: %s", ErrMissingInputs, outPoint)
+			}
+			prevOuts[i] = parent.Tx.Outputs[in.Out]
+			depends[parentID] = true
+		} else {
+			out, ok := view.FindOutput(in.ID, in.Out)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrMissingInputs, outPoint)
+			}
+			prevOuts[i] = out
+		}
+		inTotal += prevOuts[i].Value
+	}
+
+	if inTotal < outTotal {
+		return nil, fmt.Errorf("%w: inputs %d, outputs %d", ErrInsufficientInput, inTotal, outTotal)
+	}
+	if !tx.VerifyPrevOuts(prevOuts) {
+		return nil, ErrInvalidSignature
+	}
+
+	desc := &TxDesc{Tx: tx, Size: size, Fee: inTotal - outTotal, SigOps: tx.SigOpCount(), Added: time.Now()}
+	for parentID := range depends {
+		desc.Depends = append(desc.Depends, parentID)
+	}
+
+	if len(conflicts) > 0 {
+		replaced, err := mp.checkReplacement(desc, conflicts)
+		if err != nil {
+			return nil, err
+		}
+		desc.Replaces = replaced
+	}
+	if err := mp.checkChainLimits(desc); err != nil {
+		return nil, err
+	}
+
+	count, bytes := len(mp.txs)+1, mp.bytes+size
+	for _, id := range desc.Replaces {
+		count--
+		bytes -= mp.txs[id].Size
+	}
+	if count > mp.policy.MaxTxs || bytes > mp.policy.MaxBytes {
+		return nil, ErrPoolFull
+	}
+
+	return desc, nil
+}
+
+// checkReplacement 按BIP125规则检查替换交易，返回将被移出交易池的交易：直接冲突的交易及其全部子孙交易
+// 直接冲突的交易都已发出可替换信号；替换交易的手续费率须高于每一笔直接冲突的交易，
+// 手续费总额须覆盖全部被移出的交易并为自身大小支付增量手续费，且不能引入新的未确认输入
+func (mp *Mempool) checkReplacement(desc *TxDesc, conflicts map[string]bool) ([]string, error) {
+	replaced := make(map[string]bool)
+	allowedParents := make(map[string]bool)
+	for id := range conflicts {
+		conflict := mp.txs[id]
+		if !higherFeeRate(desc.Fee, desc.Size, conflict.Fee, conflict.Size) {
+			return nil, fmt.Errorf("%w: fee rate %d/%d is not higher than %d/%d of %s",
+				ErrReplacementFee, desc.Fee, desc.Size, conflict.Fee, conflict.Size, id)
+		}
+		for _, parentID := range conflict.Depends {
+			allowedParents[parentID] = true
+		}
+
+		mp.descendants(id, replaced)
+		if len(replaced) > mp.policy.MaxReplacementEvictions {
+			return nil, fmt.Errorf("%w: more than %d", ErrTooManyReplacements, mp.policy.MaxReplacementEvictions)
+		}
+	}
+
+	for _, parentID := range desc.Depends {
+		if replaced[parentID] || !allowedParents[parentID] {
+			return nil, fmt.Errorf("%w: %s", ErrReplacementInputs, parentID)
+		}
+	}
+
+	replacedFees := 0
+	for id := range replaced {
+		replacedFees += mp.txs[id].Fee
+	}
+	if minFee := mp.policy.MinReplacementFee(replacedFees, desc.Size); desc.Fee < minFee {
+		return nil, fmt.Errorf("%w: %d < %d", ErrReplacementFee, desc.Fee, minFee)
+	}
+
+	descs := make([]*TxDesc, 0, len(replaced))
+	for id := range replaced {
+		descs = append(descs, mp.txs[id])
+	}
+	sortBySeq(descs)
+
+	ids := make([]string, len(descs))
+	for i, replacedDesc := range descs {
+		ids[i] = hex.EncodeToString(replacedDesc.Tx.ID)
+	}
+
+	return ids, nil
+}
+
+// checkChainLimits 检查未确认交易链的长度：新交易的祖先交易数，以及加入后每个祖先交易的子孙交易数
+// 将被替换交易移出的交易不计入
+func (mp *Mempool) checkChainLimits(desc *TxDesc) error {
+	replaced := make(map[string]bool, len(desc.Replaces))
+	for _, id := range desc.Replaces {
+		replaced[id] = true
+	}
+
+	ancestors := make(map[string]bool)
+	for _, parentID := range desc.Depends {
+		mp.ancestors(parentID, ancestors)
+	}
+	if len(ancestors)+1 > mp.policy.MaxAncestors {
+		return fmt.Errorf("%w: %d, limit %d", ErrTooManyAncestors, len(ancestors)+1, mp.policy.MaxAncestors)
+	}
+
+	for ancestorID := range ancestors {
+		set := make(map[string]bool)
+		mp.descendants(ancestorID, set)
+
+		count := 1
+		for id := range set {
+			if !replaced[id] {
+				count++
+			}
+		}
+		if count > mp.policy.MaxDescendants {
+			return fmt.Errorf("%w: %s would have %d, limit %d", ErrTooManyDescendants, ancestorID, count, mp.policy.MaxDescendants)
+		}
+	}
+
+	return nil
+}
+
+// insert 将已通过检查的交易加入交易池
+func (mp *Mempool) insert(desc *TxDesc) {
+	mp.seq++
+	desc.seq = mp.seq
+
+	id := hex.EncodeToString(desc.Tx.ID)
+	mp.txs[id] = desc
+	for _, in := range desc.Tx.Inputs {
+		mp.spent[outPointKey(in.ID, in.Out)] = id
+	}
+	mp.bytes += desc.Size
+}
+
+// Has 交易是否在交易池中
+func (mp *Mempool) Has(txID []byte) bool {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	_, ok := mp.txs[hex.EncodeToString(txID)]
+
+	return ok
+}
+
+// Get 获取交易池中的交易
+func (mp *Mempool) Get(txID []byte) (*blockchain.Transaction, bool) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	desc, ok := mp.txs[hex.EncodeToString(txID)]
+	if !ok {
+		return nil, false
+	}
+
+	return desc.Tx, true
+}
+
+// Count 交易池中的交易数量
+func (mp *Mempool) Count() int {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	return len(mp.txs)
+}
+
+// Bytes 交易池中全部交易序列化后的总字节数
+func (mp *Mempool) Bytes() int {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	return mp.bytes
+}
+
+// Transactions 按进入交易池的顺序列出全部交易，父交易总是排在子交易之前
+func (mp *Mempool) Transactions() []*blockchain.Transaction {
+	descs := mp.Descs()
+
+	txs := make([]*blockchain.Transaction, len(descs))
+	for i, desc := range descs {
+		txs[i] = desc.Tx
+	}
+
+	return txs
+}
+
+// Descs 按进入交易池的顺序列出全部交易的信息
+func (mp *Mempool) Descs() []*TxDesc {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	descs := make([]*TxDesc, 0, len(mp.txs))
+	for _, desc := range mp.txs {
+		descs = append(descs, desc)
+	}
+	sortBySeq(descs)
+
+	return descs
+}
+
+// Remove 从交易池中删除交易，withDescendants为真时一并删除花费其输出的子孙交易
+// 返回被删除的交易ID
+func (mp *Mempool) Remove(txID []byte, withDescendants bool) []string {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	id := hex.EncodeToString(txID)
+	if !withDescendants {
+		if _, ok := mp.txs[id]; !ok {
+			return nil
+		}
+		mp.remove(id)
+		return []string{id}
+	}
+
+	return mp.removeWithDescendants(id)
+}
+
+// BlockConnected 区块连接到主链后，删除已被打包的交易，以及与区块中的交易花费同一输出的冲突交易及其子孙交易
+// 返回因冲突而被删除的交易ID
+func (mp *Mempool) BlockConnected(block *blockchain.Block) []string {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	var conflicts []string
+	for _, tx := range block.Transactions {
+		id := hex.EncodeToString(tx.ID)
+		if _, ok := mp.txs[id]; ok {
+			// 已打包的交易不再是其子交易的未确认父交易
+			mp.remove(id)
+			for _, desc := range mp.txs {
+				desc.Depends = removeString(desc.Depends, id)
+			}
+		}
+
+		if tx.IsCoinbaseTx() {
+			continue
+		}
+		for _, in := range tx.Inputs {
+			if spender, ok := mp.spent[outPointKey(in.ID, in.Out)]; ok && spender != id {
+				conflicts = append(conflicts, mp.removeWithDescendants(spender)...)
+			}
+		}
+	}
+	mp.expire()
+
+	return conflicts
+}
+
+// expire 删除停留时间超过 Policy.Expiry 的交易及其子孙交易，返回被删除的交易ID
+func (mp *Mempool) expire() []string {
+	if mp.policy.Expiry <= 0 {
+		return nil
+	}
+
+	var expired []string
+	for id, desc := range mp.txs {
+		if time.Since(desc.Added) > mp.policy.Expiry {
+			expired = append(expired, mp.removeWithDescendants(id)...)
+		}
+	}
+
+	return expired
+}
+
+// remove 删除单笔交易，不处理子孙交易
+func (mp *Mempool) remove(id string) {
+	desc, ok := mp.txs[id]
+	if !ok {
+		return
+	}
+
+	for _, in := range desc.Tx.Inputs {
+		outPoint := outPointKey(in.ID, in.Out)
+		if mp.spent[outPoint] == id {
+			delete(mp.spent, outPoint)
+		}
+	}
+	mp.bytes -= desc.Size
+	delete(mp.txs, id)
+}
+
+// removeWithDescendants 删除交易及所有花费其输出的子孙交易
+func (mp *Mempool) removeWithDescendants(id string) []string {
+	desc, ok := mp.txs[id]
+	if !ok {
+		return nil
+	}
+
+	removed := []string{id}
+	for outIdx := range desc.Tx.Outputs {
+		if child, ok := mp.spent[fmt.Sprintf("%s:%d", id, outIdx)]; ok {
+			removed = append(removed, mp.removeWithDescendants(child)...)
+		}
+	}
+	mp.remove(id)
+
+	return removed
+}
+
+// descendants 将交易及所有花费其输出的子孙交易加入集合
+func (mp *Mempool) descendants(id string, set map[string]bool) {
+	desc, ok := mp.txs[id]
+	if !ok || set[id] {
+		return
+	}
+	set[id] = true
+
+	for outIdx := range desc.Tx.Outputs {
+		if child, ok := mp.spent[fmt.Sprintf("%s:%d", id, outIdx)]; ok {
+			mp.descendants(child, set)
+		}
+	}
+}
+
+// ancestors 将交易及其仍在交易池中的全部祖先交易加入集合
+func (mp *Mempool) ancestors(id string, set map[string]bool) {
+	desc, ok := mp.txs[id]
+	if !ok || set[id] {
+		return
+	}
+	set[id] = true
+
+	for _, parentID := range desc.Depends {
+		mp.ancestors(parentID, set)
+	}
+}
+
+// outPointKey 输出的索引键 txid:vout
+func outPointKey(txID []byte, index int) string {
+	return fmt.Sprintf("%x:%d", txID, index)
+}
+
+// sortBySeq 按进入交易池的顺序排序
+func sortBySeq(descs []*TxDesc) {
+	sort.Slice(descs, func(i, j int) bool {
+		return descs[i].seq < descs[j].seq
+	})
+}
+
+// removeString 从切片中删除指定字符串
+func removeString(list []string, s string) []string {
+	for i, item := range list {
+		if item == s {
+			return append(list[:i:i], list[i+1:]...)
+		}
+	}
+
+	return list
+}
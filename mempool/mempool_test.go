@@ -0,0 +1,301 @@
+package mempool
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"Golang_Bitcoin_Sample/wallet"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testView 内存中的UTXO集合
+type testView map[string]blockchain.TxOutput
+
+func (v testView) FindOutput(txID []byte, index int) (blockchain.TxOutput, bool) {
+	out, ok := v[fmt.Sprintf("%x:%d", txID, index)]
+	return out, ok
+}
+
+// spend 构造并签名一笔花费 prev 第 index 个输出的交易，amounts 为各输出金额
+func spend(t *testing.T, w *wallet.Wallet, prev *blockchain.Transaction, index int, amounts ...int) *blockchain.Transaction {
+	return newSpend(t, w, prev, index, false, amounts...)
+}
+
+// spendRBF 与 spend 相同，但交易发出可替换信号
+func spendRBF(t *testing.T, w *wallet.Wallet, prev *blockchain.Transaction, index int, amounts ...int) *blockchain.Transaction {
+	return newSpend(t, w, prev, index, true, amounts...)
+}
+
+func newSpend(t *testing.T, w *wallet.Wallet, prev *blockchain.Transaction, index int, replaceable bool, amounts ...int) *blockchain.Transaction {
+	address := string(w.GenerateAddress())
+
+	tx := blockchain.Transaction{
+		Inputs:  []blockchain.TxInput{{ID: prev.ID, Out: index, Replaceable: replaceable}},
+		Witness: []blockchain.TxWitness{{PubKey: w.PublicKey}},
+	}
+	for _, amount := range amounts {
+		tx.Outputs = append(tx.Outputs, *blockchain.NewTXOutput(amount, address))
+	}
+	tx.ID = tx.Hash()
+
+	if err := tx.SignInput(0, w.PrivateKey, []blockchain.TxOutput{prev.Outputs[index]}, blockchain.SigHashAll); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+
+	return &tx
+}
+
+func TestMempoolAccept(t *testing.T) {
+	w := wallet.NewWallet()
+	coinbase := blockchain.CoinbaseTx(string(w.GenerateAddress()), "")
+	view := testView{fmt.Sprintf("%x:0", coinbase.ID): coinbase.Outputs[0]}
+	mp := New(DefaultPolicy)
+
+	parent := spend(t, w, coinbase, 0, 15, 4)
+	desc, err := mp.Add(parent, view)
+	if err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if desc.Fee != 1 || mp.Count() != 1 || mp.Bytes() != desc.Size {
+		t.Errorf("Add error: 手续费 %d，交易数 %d，字节数 %d", desc.Fee, mp.Count(), mp.Bytes())
+	}
+	if _, err := mp.Add(parent, view); !errors.Is(err, ErrAlreadyInPool) {
+		t.Errorf("Add error: 重复交易未被拒绝: %v", err)
+	}
+
+	// 花费同一输出的另一笔交易被拒绝
+	if _, err := mp.Add(spend(t, w, coinbase, 0, 10), view); !errors.Is(err, ErrDoubleSpend) {
+		t.Errorf("Add error: 双花交易未被拒绝: %v", err)
+	}
+
+	// 可以花费交易池中父交易的输出
+	child := spend(t, w, parent, 0, 14)
+	desc, err = mp.Add(child, view)
+	if err != nil {
+		t.Fatalf("Add error: 花费未确认输出的交易被拒绝: %v", err)
+	}
+	if len(desc.Depends) != 1 {
+		t.Errorf("Add error: 父交易 %v", desc.Depends)
+	}
+	if txs := mp.Transactions(); len(txs) != 2 || txs[0] != parent || txs[1] != child {
+		t.Error("Transactions error: 父交易应排在子交易之前")
+	}
+
+	// 不存在的输出、输出超过输入、签名错误
+	missing := spend(t, w, parent, 1, 1)
+	missing.Inputs[0].Out = 5
+	if _, err := mp.Add(missing, view); !errors.Is(err, ErrMissingInputs) {
+		t.Errorf("Add error: 花费不存在输出的交易未被拒绝: %v", err)
+	}
+	if _, err := mp.Add(spend(t, w, parent, 1, 5), view); !errors.Is(err, ErrInsufficientInput) {
+		t.Errorf("Add error: 输出超过输入的交易未被拒绝: %v", err)
+	}
+	forged := spend(t, wallet.NewWallet(), parent, 1, 4)
+	forged.Witness[0].PubKey = w.PublicKey
+	if _, err := mp.Add(forged, view); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Add error: 签名错误的交易未被拒绝: %v", err)
+	}
+	if _, err := mp.Add(coinbase, view); !errors.Is(err, ErrCoinbase) {
+		t.Errorf("Add error: 币基交易未被拒绝: %v", err)
+	}
+	if mp.Count() != 2 {
+		t.Errorf("Add error: 被拒绝的交易改变了交易池，交易数 %d", mp.Count())
+	}
+}
+
+func TestMempoolReplacement(t *testing.T) {
+	w := wallet.NewWallet()
+	coinbase := blockchain.CoinbaseTx(string(w.GenerateAddress()), "")
+	view := testView{fmt.Sprintf("%x:0", coinbase.ID): coinbase.Outputs[0]}
+
+	// 原交易手续费2并发出可替换信号，其子交易手续费1
+	newPool := func(policy Policy) (*Mempool, *blockchain.Transaction, *blockchain.Transaction) {
+		mp := New(policy)
+		orig := spendRBF(t, w, coinbase, 0, 18)
+		child := spend(t, w, orig, 0, 17)
+		for _, tx := range []*blockchain.Transaction{orig, child} {
+			if _, err := mp.Add(tx, view); err != nil {
+				t.Fatalf("Add error: %v", err)
+			}
+		}
+		return mp, orig, child
+	}
+
+	// 手续费须覆盖被移出的两笔交易（共3）并多付增量手续费
+	mp, orig, child := newPool(DefaultPolicy)
+	if _, err := mp.Add(spend(t, w, coinbase, 0, 17), view); !errors.Is(err, ErrReplacementFee) {
+		t.Errorf("Add error: 手续费不足的替换交易未被拒绝: %v", err)
+	}
+	replacement := spend(t, w, coinbase, 0, 16)
+	desc, err := mp.Add(replacement, view)
+	if err != nil {
+		t.Fatalf("Add error: 替换交易被拒绝: %v", err)
+	}
+	if len(desc.Replaces) != 2 || desc.Replaces[0] != fmt.Sprintf("%x", orig.ID) || desc.Replaces[1] != fmt.Sprintf("%x", child.ID) {
+		t.Errorf("Add error: 被替换的交易 %v", desc.Replaces)
+	}
+	if mp.Count() != 1 || !mp.Has(replacement.ID) || mp.Bytes() != desc.Size {
+		t.Error("Add error: 被替换的交易仍在交易池中")
+	}
+
+	// 没有发出可替换信号的交易不能被替换
+	if _, err := mp.Add(spend(t, w, coinbase, 0, 10), view); !errors.Is(err, ErrDoubleSpend) {
+		t.Errorf("Add error: 替换了未发出信号的交易: %v", err)
+	}
+
+	// 移出的交易数超过上限
+	policy := DefaultPolicy
+	policy.MaxReplacementEvictions = 1
+	mp, _, _ = newPool(policy)
+	if _, err := mp.Add(spend(t, w, coinbase, 0, 16), view); !errors.Is(err, ErrTooManyReplacements) {
+		t.Errorf("Add error: 超过移出交易数上限: %v", err)
+	}
+}
+
+func TestMempoolLimits(t *testing.T) {
+	w := wallet.NewWallet()
+	coinbase := blockchain.CoinbaseTx(string(w.GenerateAddress()), "")
+	view := testView{fmt.Sprintf("%x:0", coinbase.ID): coinbase.Outputs[0]}
+
+	policy := DefaultPolicy
+	policy.MaxTxs = 1
+	mp := New(policy)
+	parent := spend(t, w, coinbase, 0, 10, 10)
+	if _, err := mp.Add(parent, view); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if _, err := mp.Add(spend(t, w, parent, 0, 10), view); !errors.Is(err, ErrPoolFull) {
+		t.Errorf("Add error: 超过交易数上限: %v", err)
+	}
+
+	policy = DefaultPolicy
+	policy.MaxTxSize = 10
+	mp = New(policy)
+	if _, err := mp.Add(parent, view); !errors.Is(err, ErrTxTooLarge) {
+		t.Errorf("Add error: 超过单笔交易大小上限: %v", err)
+	}
+
+	// 未确认交易链：parent <- child <- grandchild，以及 parent 的另一个子交易
+	policy = DefaultPolicy
+	policy.MaxAncestors, policy.MaxDescendants = 2, 3
+	mp = New(policy)
+	child := spend(t, w, parent, 0, 10)
+	for _, tx := range []*blockchain.Transaction{parent, child} {
+		if _, err := mp.Add(tx, view); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	if _, err := mp.Add(spend(t, w, child, 0, 10), view); !errors.Is(err, ErrTooManyAncestors) {
+		t.Errorf("Add error: 超过祖先交易数上限: %v", err)
+	}
+	if _, err := mp.Add(spend(t, w, parent, 1, 10), view); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+
+	policy.MaxDescendants = 2
+	mp = New(policy)
+	for _, tx := range []*blockchain.Transaction{parent, child} {
+		if _, err := mp.Add(tx, view); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	if _, err := mp.Add(spend(t, w, parent, 1, 10), view); !errors.Is(err, ErrTooManyDescendants) {
+		t.Errorf("Add error: 超过子孙交易数上限: %v", err)
+	}
+}
+
+func TestMempoolBlockConnected(t *testing.T) {
+	w := wallet.NewWallet()
+	coinbase := blockchain.CoinbaseTx(string(w.GenerateAddress()), "")
+	view := testView{fmt.Sprintf("%x:0", coinbase.ID): coinbase.Outputs[0]}
+	mp := New(DefaultPolicy)
+
+	parent := spend(t, w, coinbase, 0, 10, 10)
+	child := spend(t, w, parent, 0, 10)
+	grandchild := spend(t, w, child, 0, 10)
+	sibling := spend(t, w, parent, 1, 10)
+	for _, tx := range []*blockchain.Transaction{parent, child, grandchild, sibling} {
+		if _, err := mp.Add(tx, view); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+
+	// 区块打包了父交易，以及花费 child 同一输出的另一笔交易
+	double := spend(t, w, parent, 0, 9)
+	block := &blockchain.Block{Transactions: []*blockchain.Transaction{parent, double}}
+	conflicts := mp.BlockConnected(block)
+	if len(conflicts) != 2 {
+		t.Errorf("BlockConnected error: 冲突交易 %v", conflicts)
+	}
+	if mp.Has(parent.ID) || mp.Has(child.ID) || mp.Has(grandchild.ID) {
+		t.Error("BlockConnected error: 已打包或冲突的交易仍在交易池中")
+	}
+	desc := mp.Descs()
+	if len(desc) != 1 || desc[0].Tx != sibling || len(desc[0].Depends) != 0 {
+		t.Error("BlockConnected error: 未受影响的交易应保留，且不再依赖已打包的父交易")
+	}
+	if mp.Bytes() != desc[0].Size {
+		t.Errorf("BlockConnected error: 字节数 %d", mp.Bytes())
+	}
+}
+
+func TestMempoolConcurrentAdd(t *testing.T) {
+	w := wallet.NewWallet()
+	coinbase := blockchain.CoinbaseTx(string(w.GenerateAddress()), "")
+	view := testView{fmt.Sprintf("%x:0", coinbase.ID): coinbase.Outputs[0]}
+	mp := New(DefaultPolicy)
+
+	// 多个节点连接同时提交花费同一输出的交易，只有一笔能进入交易池
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 1; i <= 8; i++ {
+		tx := spend(t, w, coinbase, 0, i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := mp.Add(tx, view); err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+			mp.Transactions()
+		}()
+	}
+	wg.Wait()
+
+	if accepted != 1 || mp.Count() != 1 {
+		t.Errorf("Add error: 并发提交的双花交易有 %d 笔被接受", accepted)
+	}
+}
+
+func TestMempoolExpiry(t *testing.T) {
+	w := wallet.NewWallet()
+	view := testView{}
+	coinbase := func() *blockchain.Transaction {
+		tx := blockchain.CoinbaseTx(string(w.GenerateAddress()), "")
+		view[fmt.Sprintf("%x:0", tx.ID)] = tx.Outputs[0]
+		return tx
+	}
+	mp := New(DefaultPolicy)
+
+	parent := spend(t, w, coinbase(), 0, 19)
+	child := spend(t, w, parent, 0, 18)
+	for _, tx := range []*blockchain.Transaction{parent, child} {
+		if _, err := mp.Add(tx, view); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	mp.txs[fmt.Sprintf("%x", parent.ID)].Added = time.Now().Add(-DefaultPolicy.Expiry - time.Hour)
+
+	// 新交易加入时，超时的交易及其子孙交易被移出交易池
+	fresh := spend(t, w, coinbase(), 0, 19)
+	if _, err := mp.Add(fresh, view); err != nil {
+		t.Fatalf("Add error: %v", err)
+	}
+	if mp.Count() != 1 || !mp.Has(fresh.ID) {
+		t.Errorf("Add error: 超时的交易仍在交易池中，交易数 %d", mp.Count())
+	}
+}
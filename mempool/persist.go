@@ -0,0 +1,111 @@
+package mempool
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 交易池文件格式版本
+const dumpVersion = 1
+
+// savedTx 交易池文件中的一笔交易
+type savedTx struct {
+	Tx    []byte    // 序列化的交易
+	Added time.Time // 进入交易池的时间
+}
+
+// dumpFile 交易池文件
+type dumpFile struct {
+	Version int
+	Txs     []savedTx // 按进入交易池的顺序排列，父交易总是排在子交易之前
+}
+
+// LoadResult 加载交易池文件的结果
+type LoadResult struct {
+	Accepted int // 重新通过验证加入交易池的交易数
+	Expired  int // 停留时间超过 Policy.Expiry 而丢弃的交易数
+	Rejected int // 已被打包、与其他交易冲突或不再有效而丢弃的交易数
+}
+
+// Dump 将交易池中的全部交易写入文件，返回写入的交易数
+// 先写入临时文件再重命名，中途退出不会损坏已有的文件
+func (mp *Mempool) Dump(path string) (int, error) {
+	descs := mp.Descs()
+
+	file := dumpFile{Version: dumpVersion, Txs: make([]savedTx, len(descs))}
+	for i, desc := range descs {
+		file.Txs[i] = savedTx{desc.Tx.Serialize(), desc.Added}
+	}
+
+	var content bytes.Buffer
+	if err := gob.NewEncoder(&content).Encode(file); err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+
+	tmp := path + ".new"
+	if err := ioutil.WriteFile(tmp, content.Bytes(), 0600); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, err
+	}
+
+	return len(descs), nil
+}
+
+// Load 读取交易池文件，按原来的顺序重新验证每笔交易，文件不存在时不做任何事
+// 交易保留原来进入交易池的时间；超过 Policy.Expiry 的交易被丢弃，花费其输出的交易随之因缺少输入而被丢弃
+func (mp *Mempool) Load(path string, view UTXOView) (LoadResult, error) {
+	var result LoadResult
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	var file dumpFile
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&file); err != nil {
+		return result, err
+	}
+	if file.Version != dumpVersion {
+		return result, fmt.Errorf("unsupported mempool file version %d", file.Version)
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	for _, saved := range file.Txs {
+		if mp.policy.Expiry > 0 && time.Since(saved.Added) > mp.policy.Expiry {
+			result.Expired++
+			continue
+		}
+
+		tx := blockchain.DeserializeTransaction(saved.Tx)
+		desc, err := mp.check(&tx, view)
+		if err != nil {
+			result.Rejected++
+			continue
+		}
+		desc.Added = saved.Added
+
+		for _, id := range desc.Replaces {
+			mp.remove(id)
+		}
+		mp.insert(desc)
+		result.Accepted++
+	}
+
+	return result, nil
+}
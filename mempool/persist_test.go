@@ -0,0 +1,70 @@
+package mempool
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"Golang_Bitcoin_Sample/wallet"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMempoolDumpLoad(t *testing.T) {
+	dir, err := os.MkdirTemp("", "mempool_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "mempool.dat")
+
+	w := wallet.NewWallet()
+	view := testView{}
+	coinbase := func() *blockchain.Transaction {
+		tx := blockchain.CoinbaseTx(string(w.GenerateAddress()), "")
+		view[fmt.Sprintf("%x:0", tx.ID)] = tx.Outputs[0]
+		return tx
+	}
+
+	mp := New(DefaultPolicy)
+	parent := spend(t, w, coinbase(), 0, 19)
+	child := spend(t, w, parent, 0, 18)
+	stale := spend(t, w, coinbase(), 0, 20)
+	spentCoinbase := coinbase()
+	confirmed := spend(t, w, spentCoinbase, 0, 20)
+	for _, tx := range []*blockchain.Transaction{parent, child, stale, confirmed} {
+		if _, err := mp.Add(tx, view); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+	added := mp.Descs()[0].Added
+	mp.txs[fmt.Sprintf("%x", stale.ID)].Added = time.Now().Add(-DefaultPolicy.Expiry - time.Hour)
+
+	if n, err := mp.Dump(path); err != nil || n != 4 {
+		t.Fatalf("Dump error: 写入 %d 笔交易: %v", n, err)
+	}
+
+	// 重启期间 confirmed 已被打包，它花费的输出不再在UTXO集合中
+	delete(view, fmt.Sprintf("%x:0", spentCoinbase.ID))
+
+	loaded := New(DefaultPolicy)
+	result, err := loaded.Load(path, view)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if result != (LoadResult{Accepted: 2, Expired: 1, Rejected: 1}) {
+		t.Errorf("Load error: %+v", result)
+	}
+	descs := loaded.Descs()
+	if len(descs) != 2 || !loaded.Has(parent.ID) || !loaded.Has(child.ID) {
+		t.Fatal("Load error: 父交易与子交易应重新加入交易池")
+	}
+	if !descs[0].Added.Equal(added) || len(descs[1].Depends) != 1 {
+		t.Error("Load error: 应保留进入交易池的时间与依赖关系")
+	}
+
+	// 文件不存在时交易池为空
+	if result, err := New(DefaultPolicy).Load(filepath.Join(dir, "missing.dat"), view); err != nil || result != (LoadResult{}) {
+		t.Errorf("Load error: 文件不存在时 %+v: %v", result, err)
+	}
+}
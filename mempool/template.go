@@ -0,0 +1,157 @@
+package mempool
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"container/heap"
+	"encoding/hex"
+)
+
+// 为币基交易预留的区块空间
+const coinbaseReserve = 1000
+
+// TemplateLimits 区块模板的容量限制
+type TemplateLimits struct {
+	MaxBlockSize   int // 区块中全部交易序列化后的总字节数上限
+	MaxBlockSigOps int // 区块中签名检查次数上限
+}
+
+// DefaultTemplateLimits 默认的区块容量限制
+var DefaultTemplateLimits = TemplateLimits{
+	MaxBlockSize:   1 << 20,
+	MaxBlockSigOps: 20000,
+}
+
+// Template 矿工打包的区块模板，不含币基交易
+type Template struct {
+	Txs    []*blockchain.Transaction // 按打包顺序排列，父交易总是排在子交易之前
+	Fees   int                       // 手续费总额，由币基交易领取
+	Size   int                       // 交易序列化后的总字节数
+	SigOps int                       // 签名检查次数
+}
+
+// templateEntry 构造区块模板时的一笔交易，记录它与尚未打包的祖先交易组成的交易包的合计值
+// 祖先交易被打包后从其子孙交易的合计值中扣除，无需每一轮重新遍历祖先交易
+type templateEntry struct {
+	desc        *TxDesc
+	ancestors   map[string]*templateEntry // 尚未打包的祖先交易，不含自身
+	descendants []*templateEntry          // 交易池中的全部子孙交易
+	fee         int
+	size        int
+	sigOps      int
+	index       int // 在堆中的位置，已打包或被跳过时为-1
+}
+
+// templateHeap 按交易包手续费率从高到低排列的堆，手续费率相同时先进入交易池的交易优先
+type templateHeap []*templateEntry
+
+func (h templateHeap) Len() int { return len(h) }
+
+func (h templateHeap) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	if higherFeeRate(a.fee, a.size, b.fee, b.size) {
+		return true
+	}
+	return !higherFeeRate(b.fee, b.size, a.fee, a.size) && a.desc.seq < b.desc.seq
+}
+
+func (h templateHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *templateHeap) Push(x interface{}) {
+	entry := x.(*templateEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *templateHeap) Pop() interface{} {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	entry.index = -1
+
+	return entry
+}
+
+// higherFeeRate 判断手续费率 feeA/sizeA 是否高于 feeB/sizeB
+func higherFeeRate(feeA, sizeA, feeB, sizeB int) bool {
+	return feeA*sizeB > feeB*sizeA
+}
+
+// BlockTemplate 按祖先交易包的手续费率从高到低选择交易，构造区块模板
+// 每笔交易与其尚未打包的祖先交易作为一个整体计算手续费率，因此高手续费的子交易可以带动低手续费的父交易被打包
+// 放不下的交易包被跳过，继续尝试更小的交易包
+func (mp *Mempool) BlockTemplate(limits TemplateLimits) *Template {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	entries := make(map[string]*templateEntry, len(mp.txs))
+	for id, desc := range mp.txs {
+		entries[id] = &templateEntry{desc: desc, ancestors: make(map[string]*templateEntry)}
+	}
+
+	// 计算每笔交易的祖先交易与交易包合计值，并记录反向的子孙关系
+	h := make(templateHeap, 0, len(entries))
+	for id, entry := range entries {
+		ancestors := make(map[string]bool)
+		mp.ancestors(id, ancestors)
+
+		for ancestorID := range ancestors {
+			ancestor := entries[ancestorID]
+			entry.fee += ancestor.desc.Fee
+			entry.size += ancestor.desc.Size
+			entry.sigOps += ancestor.desc.SigOps
+			if ancestorID != id {
+				entry.ancestors[ancestorID] = ancestor
+				ancestor.descendants = append(ancestor.descendants, entry)
+			}
+		}
+		h = append(h, entry)
+	}
+	for i, entry := range h {
+		entry.index = i
+	}
+	heap.Init(&h)
+
+	template := &Template{Size: coinbaseReserve}
+	for h.Len() > 0 {
+		best := heap.Pop(&h).(*templateEntry)
+		if template.Size+best.size > limits.MaxBlockSize || template.SigOps+best.sigOps > limits.MaxBlockSigOps {
+			continue
+		}
+
+		pkg := []*TxDesc{best.desc}
+		for _, ancestor := range best.ancestors {
+			pkg = append(pkg, ancestor.desc)
+		}
+		sortBySeq(pkg)
+
+		template.Fees += best.fee
+		template.Size += best.size
+		template.SigOps += best.sigOps
+
+		for _, desc := range pkg {
+			entry := entries[hex.EncodeToString(desc.Tx.ID)]
+			if entry.index >= 0 {
+				heap.Remove(&h, entry.index)
+			}
+			template.Txs = append(template.Txs, desc.Tx)
+
+			// 已打包的交易不再计入子孙交易的交易包
+			for _, descendant := range entry.descendants {
+				delete(descendant.ancestors, hex.EncodeToString(desc.Tx.ID))
+				descendant.fee -= desc.Fee
+				descendant.size -= desc.Size
+				descendant.sigOps -= desc.SigOps
+				if descendant.index >= 0 {
+					heap.Fix(&h, descendant.index)
+				}
+			}
+		}
+	}
+	template.Size -= coinbaseReserve
+
+	return template
+}
@@ -0,0 +1,89 @@
+package mempool
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"Golang_Bitcoin_Sample/wallet"
+	"fmt"
+	"testing"
+)
+
+func TestBlockTemplate(t *testing.T) {
+	w := wallet.NewWallet()
+	view := testView{}
+	coinbase := func() *blockchain.Transaction {
+		tx := blockchain.CoinbaseTx(string(w.GenerateAddress()), "")
+		view[fmt.Sprintf("%x:0", tx.ID)] = tx.Outputs[0]
+		return tx
+	}
+	mp := New(DefaultPolicy)
+	add := func(tx *blockchain.Transaction) *TxDesc {
+		desc, err := mp.Add(tx, view)
+		if err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+		return desc
+	}
+
+	// 低手续费的父交易与高手续费的子交易，以及一笔中等手续费的独立交易
+	parent := add(spend(t, w, coinbase(), 0, 20))
+	child := add(spend(t, w, parent.Tx, 0, 10))
+	middle := add(spend(t, w, coinbase(), 0, 16))
+	low := add(spend(t, w, coinbase(), 0, 19))
+
+	template := mp.BlockTemplate(DefaultTemplateLimits)
+	if len(template.Txs) != 4 {
+		t.Fatalf("BlockTemplate error: 打包了 %d 笔交易", len(template.Txs))
+	}
+	// 子交易带动父交易，交易包手续费率最高，且父交易排在子交易之前
+	if template.Txs[0] != parent.Tx || template.Txs[1] != child.Tx || template.Txs[2] != middle.Tx || template.Txs[3] != low.Tx {
+		t.Error("BlockTemplate error: 交易未按交易包手续费率排序")
+	}
+	if template.Fees != 15 || template.SigOps != 4 || template.Size != parent.Size+child.Size+middle.Size+low.Size {
+		t.Errorf("BlockTemplate error: 手续费 %d，签名检查 %d，字节数 %d", template.Fees, template.SigOps, template.Size)
+	}
+
+	// 签名检查次数上限只够一笔交易时，放不下的父子交易包被跳过，只打包一笔独立交易
+	template = mp.BlockTemplate(TemplateLimits{MaxBlockSize: DefaultTemplateLimits.MaxBlockSize, MaxBlockSigOps: 1})
+	if len(template.Txs) != 1 || template.Txs[0] != middle.Tx {
+		t.Error("BlockTemplate error: 超过签名检查次数上限")
+	}
+
+	// 区块空间只够一笔交易
+	template = mp.BlockTemplate(TemplateLimits{MaxBlockSize: coinbaseReserve + middle.Size, MaxBlockSigOps: 100})
+	if len(template.Txs) != 1 || template.Size > middle.Size {
+		t.Error("BlockTemplate error: 超过区块大小上限")
+	}
+}
+
+func TestBlockTemplateAncestorUpdate(t *testing.T) {
+	w := wallet.NewWallet()
+	view := testView{}
+	coinbase := func() *blockchain.Transaction {
+		tx := blockchain.CoinbaseTx(string(w.GenerateAddress()), "")
+		view[fmt.Sprintf("%x:0", tx.ID)] = tx.Outputs[0]
+		return tx
+	}
+	mp := New(DefaultPolicy)
+	add := func(tx *blockchain.Transaction) *TxDesc {
+		desc, err := mp.Add(tx, view)
+		if err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+		return desc
+	}
+
+	// 父交易没有手续费，两笔子交易分别花费它的两个输出
+	parent := add(spend(t, w, coinbase(), 0, 10, 10))
+	first := add(spend(t, w, parent.Tx, 0, 1))
+	second := add(spend(t, w, parent.Tx, 1, 5))
+	other := add(spend(t, w, coinbase(), 0, 16))
+
+	// 父交易随第一笔子交易打包后，第二笔子交易单独计算手续费率，排在独立交易之前
+	template := mp.BlockTemplate(DefaultTemplateLimits)
+	if len(template.Txs) != 4 || template.Txs[0] != parent.Tx || template.Txs[1] != first.Tx || template.Txs[2] != second.Tx || template.Txs[3] != other.Tx {
+		t.Error("BlockTemplate error: 祖先交易打包后未更新子孙交易的交易包手续费率")
+	}
+	if template.Fees != 18 || template.Size != parent.Size+first.Size+second.Size+other.Size {
+		t.Errorf("BlockTemplate error: 手续费 %d，字节数 %d", template.Fees, template.Size)
+	}
+}
@@ -0,0 +1,98 @@
+package network
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// 本地控制命令的凭据文件，节点启动时随机生成，只有能读取该文件的本机用户可以向节点发送控制命令
+const controlCookieFile = "./tmp/control_%s.cookie"
+
+// 凭据长度
+const controlCookieLen = 32
+
+var (
+	controlCookie     []byte
+	controlCookiePath string
+)
+
+// writeControlCookie 生成本次运行的控制命令凭据并写入文件
+func writeControlCookie(nodeID string) error {
+	cookie := make([]byte, controlCookieLen)
+	if _, err := rand.Read(cookie); err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf(controlCookieFile, nodeID)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, cookie, 0600); err != nil {
+		return err
+	}
+
+	controlCookie, controlCookiePath = cookie, path
+
+	return nil
+}
+
+// removeControlCookie 节点关闭时删除凭据文件
+func removeControlCookie() {
+	if controlCookiePath != "" {
+		os.Remove(controlCookiePath)
+	}
+}
+
+// SendSaveMempool 通知本机运行的节点将交易池写入文件，节点不可用时返回错误
+func SendSaveMempool(nodeID string) error {
+	cookie, err := ioutil.ReadFile(fmt.Sprintf(controlCookieFile, nodeID))
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.Dial(protocol, fmt.Sprintf("localhost:%s", nodeID))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(append(CmdToBytes("savemempool"), cookie...))
+
+	return err
+}
+
+// handleControl 处理本地控制命令，返回command是否为控制命令；控制命令不经过节点间消息的分发
+// 只接受来自本机回环地址且携带本次运行凭据的请求
+func handleControl(conn net.Conn, command string, req []byte) bool {
+	switch command {
+	case "savemempool":
+	default:
+		return false
+	}
+
+	if !isLoopback(conn.RemoteAddr()) || controlCookie == nil ||
+		subtle.ConstantTimeCompare(req[commandLength:], controlCookie) != 1 {
+		fmt.Printf("Rejected %s command from %s\n", command, conn.RemoteAddr())
+		return true
+	}
+
+	saveMempool()
+
+	return true
+}
+
+// isLoopback 判断连接是否来自本机回环地址
+func isLoopback(addr net.Addr) bool {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,40 @@
+package network
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"fmt"
+)
+
+// 节点的交易池文件，关闭节点时写入，启动节点时重新加载
+const mempoolFile = "./tmp/mempool_%s.dat"
+
+var mempoolPath string
+
+// loadMempool 重新加载上次关闭节点时保存的交易，逐笔验证，丢弃已过期、已被打包或相互冲突的交易
+func loadMempool(chain *blockchain.BlockChain) {
+	result, err := pool.Load(mempoolPath, blockchain.UTXOSet{Blockchain: chain})
+	if err != nil {
+		fmt.Printf("Failed to load mempool from %s: %v\n", mempoolPath, err)
+		return
+	}
+
+	if result.Accepted > 0 || result.Expired > 0 || result.Rejected > 0 {
+		fmt.Printf("Loaded %d transactions from %s, dropped %d expired and %d invalid\n",
+			result.Accepted, mempoolPath, result.Expired, result.Rejected)
+	}
+}
+
+// saveMempool 将交易池写入文件
+func saveMempool() {
+	if mempoolPath == "" {
+		return
+	}
+
+	n, err := pool.Dump(mempoolPath)
+	if err != nil {
+		fmt.Printf("Failed to save mempool to %s: %v\n", mempoolPath, err)
+		return
+	}
+
+	fmt.Printf("Saved %d transactions to %s\n", n, mempoolPath)
+}
@@ -0,0 +1,116 @@
+package network
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"Golang_Bitcoin_Sample/mempool"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MiningPolicy 矿工节点的出块条件，满足任一条件即开始打包
+type MiningPolicy struct {
+	MinTxs   int                    // 交易池中的交易数达到该值时出块，0表示不按交易数出块
+	MinBytes int                    // 交易池中交易的总字节数达到该值时出块，0表示不按字节数出块
+	MaxWait  time.Duration          // 最早进入交易池的交易等待超过该时长时出块，0表示不按时间出块
+	Template mempool.TemplateLimits // 区块容量限制
+}
+
+// DefaultMiningPolicy 默认交易池中有两笔交易时出块
+var DefaultMiningPolicy = MiningPolicy{
+	MinTxs:   2,
+	Template: mempool.DefaultTemplateLimits,
+}
+
+var (
+	miningPolicy = DefaultMiningPolicy
+	miningMutex  sync.Mutex // 同一时间只打包一个区块
+)
+
+// ready 交易池是否满足出块条件
+func (p MiningPolicy) ready() bool {
+	descs := pool.Descs()
+	if len(descs) == 0 {
+		return false
+	}
+
+	if p.MinTxs > 0 && len(descs) >= p.MinTxs {
+		return true
+	}
+	if p.MinBytes > 0 && pool.Bytes() >= p.MinBytes {
+		return true
+	}
+
+	return p.MaxWait > 0 && time.Since(descs[0].Added) >= p.MaxWait
+}
+
+// MineOnSchedule 定时检查交易池，交易等待过久时即使数量不足也出块
+func MineOnSchedule(chain *blockchain.BlockChain) {
+	interval := time.Second
+	if miningPolicy.MaxWait < interval {
+		interval = miningPolicy.MaxWait
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if miningPolicy.ready() {
+			MineTx(chain)
+		}
+	}
+}
+
+// MineTx 将交易池进行打包挖矿
+// 按手续费率选择交易构造区块模板，矿工通过币基交易领取手续费；打包后交易池仍满足出块条件时继续出块
+func MineTx(chain *blockchain.BlockChain) {
+	miningMutex.Lock()
+	defer miningMutex.Unlock()
+
+	for {
+		template := pool.BlockTemplate(miningPolicy.Template)
+		if len(template.Txs) == 0 {
+			fmt.Println("No transactions to mine")
+			return
+		}
+
+		for _, tx := range template.Txs {
+			fmt.Printf("tx: %x\n", tx.ID)
+		}
+		fmt.Printf("Block template: %d transactions, %d bytes, %d sigops, fees %d\n", len(template.Txs), template.Size, template.SigOps, template.Fees)
+
+		// 区块验证失败时，找出不合法的交易，将它与其子孙交易移出交易池后重新构造模板
+		if !chain.VerifyTransactions(template.Txs) {
+			i := chain.FindInvalidTransaction(template.Txs)
+			if i < 0 {
+				fmt.Println("Block template contains invalid transactions")
+				return
+			}
+
+			removed := pool.Remove(template.Txs[i].ID, true)
+			fmt.Printf("Removed invalid transaction %x and %d descendants from the mempool\n", template.Txs[i].ID, len(removed)-1)
+			continue
+		}
+
+		cbTx := blockchain.CoinbaseTxWithFees(mineAddress, "", template.Fees)
+		txs := append(template.Txs, cbTx)
+
+		newBlock := chain.MineBlock(txs)
+		UTXOSet := blockchain.UTXOSet{Blockchain: chain}
+		UTXOSet.Reindex()
+
+		fmt.Println("New Block mined")
+
+		pool.BlockConnected(newBlock)
+
+		for _, node := range KnownNodes {
+			if node != nodeAddress {
+				SendInv(node, "block", [][]byte{newBlock.Hash})
+			}
+		}
+
+		if !miningPolicy.ready() {
+			return
+		}
+	}
+}
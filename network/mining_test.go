@@ -0,0 +1,87 @@
+package network
+
+import (
+	"Golang_Bitcoin_Sample/blockchain"
+	"Golang_Bitcoin_Sample/mempool"
+	"Golang_Bitcoin_Sample/wallet"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// orphanView 在UTXO集合之外额外提供一些不在链上的输出，模拟交易池接受交易后其父交易从链上消失的情况
+type orphanView struct {
+	blockchain.UTXOSet
+	orphans map[string]blockchain.TxOutput
+}
+
+func (v orphanView) FindOutput(txID []byte, index int) (blockchain.TxOutput, bool) {
+	if out, ok := v.orphans[fmt.Sprintf("%x:%d", txID, index)]; ok {
+		return out, true
+	}
+
+	return v.UTXOSet.FindOutput(txID, index)
+}
+
+// spendOutput 构造并签名一笔花费 prev 第0个输出的交易
+func spendOutput(t *testing.T, w *wallet.Wallet, prev *blockchain.Transaction, amount int) *blockchain.Transaction {
+	tx := blockchain.Transaction{
+		Inputs:  []blockchain.TxInput{{ID: prev.ID, Out: 0}},
+		Outputs: []blockchain.TxOutput{*blockchain.NewTXOutput(amount, string(w.GenerateAddress()))},
+	}
+	tx.ID = tx.Hash()
+	if err := tx.SignInput(0, w.PrivateKey, []blockchain.TxOutput{prev.Outputs[0]}, blockchain.SigHashAll); err != nil {
+		t.Fatalf("SignInput error: %v", err)
+	}
+	tx.Witness[0].PubKey = w.PublicKey
+
+	return &tx
+}
+
+func TestMineTxDropsInvalidTransactions(t *testing.T) {
+	nodeId := "mining_test"
+	if err := os.MkdirAll(fmt.Sprintf("./tmp/blocks_%s", nodeId), 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll("./tmp")
+
+	w := wallet.NewWallet()
+	address := string(w.GenerateAddress())
+	chain := blockchain.InitBlockChain(address, nodeId)
+	defer chain.Database.Close()
+	UTXO := blockchain.UTXOSet{Blockchain: chain}
+	UTXO.Reindex()
+
+	genesis, err := chain.GetBlock(chain.LastHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphan := blockchain.CoinbaseTx(address, "")
+	view := orphanView{UTXO, map[string]blockchain.TxOutput{fmt.Sprintf("%x:0", orphan.ID): orphan.Outputs[0]}}
+
+	// 合法交易，以及一笔父交易不在链上、无法通过区块验证的交易及其子交易
+	pool = mempool.New(mempool.DefaultPolicy)
+	valid := spendOutput(t, w, genesis.Transactions[0], blockchain.Subsidy-1)
+	invalid := spendOutput(t, w, orphan, blockchain.Subsidy-1)
+	child := spendOutput(t, w, invalid, blockchain.Subsidy-2)
+	for _, tx := range []*blockchain.Transaction{invalid, child, valid} {
+		if _, err := pool.Add(tx, view); err != nil {
+			t.Fatalf("Add error: %v", err)
+		}
+	}
+
+	mineAddress, nodeAddress = address, KnownNodes[0]
+	MineTx(chain)
+
+	// 不合法的交易与其子交易被移出交易池，合法交易被打包
+	if pool.Count() != 0 {
+		t.Errorf("MineTx error: 交易池中仍有 %d 笔交易", pool.Count())
+	}
+	if chain.GetBestHeight() != 1 {
+		t.Fatalf("MineTx error: 区块高度为 %d", chain.GetBestHeight())
+	}
+	tip, _ := chain.GetBlock(chain.LastHash)
+	if len(tip.Transactions) != 2 || string(tip.Transactions[0].ID) != string(valid.ID) {
+		t.Error("MineTx error: 新区块应只包含合法交易与币基交易")
+	}
+}
@@ -3,7 +3,6 @@ package network
 import (
 	"bytes"
 	"encoding/gob"
-	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -15,6 +14,8 @@ import (
 	"time"
 
 	"Golang_Bitcoin_Sample/blockchain"
+	"Golang_Bitcoin_Sample/mempool"
+	"Golang_Bitcoin_Sample/wallet"
 	"github.com/vrecan/death/v3"
 )
 
@@ -29,7 +30,7 @@ var (
 	mineAddress     string
 	KnownNodes      = []string{"localhost:3000"}
 	blocksInTransit = [][]byte{}
-	memoryPool      = make(map[string]blockchain.Transaction)
+	pool            = mempool.New(mempool.DefaultPolicy)
 )
 
 type Addr struct {
@@ -230,13 +231,17 @@ func HandleBlock(request []byte, chain *blockchain.BlockChain) {
 
 	fmt.Printf("Added block %x\n", block.Hash)
 
+	for _, id := range pool.BlockConnected(block) {
+		fmt.Printf("Removed conflicting transaction %s from mempool\n", id)
+	}
+
 	if len(blocksInTransit) > 0 {
 		blockHash := blocksInTransit[0]
 		SendGetData(payload.AddrFrom, "block", blockHash)
 
 		blocksInTransit = blocksInTransit[1:]
 	} else {
-		UTXOSet := blockchain.UTXOSet{chain}
+		UTXOSet := blockchain.UTXOSet{Blockchain: chain}
 		UTXOSet.Reindex()
 	}
 }
@@ -273,7 +278,7 @@ func HandleInv(request []byte, chain *blockchain.BlockChain) {
 	if payload.Type == "tx" {
 		txID := payload.Items[0]
 
-		if memoryPool[hex.EncodeToString(txID)].ID == nil {
+		if !pool.Has(txID) {
 			SendGetData(payload.AddrFrom, "tx", txID)
 		}
 	}
@@ -317,10 +322,12 @@ func HandleGetData(request []byte, chain *blockchain.BlockChain) {
 	}
 
 	if payload.Type == "tx" {
-		txID := hex.EncodeToString(payload.ID)
-		tx := memoryPool[txID]
+		tx, ok := pool.Get(payload.ID)
+		if !ok {
+			return
+		}
 
-		SendTx(payload.AddrFrom, &tx)
+		SendTx(payload.AddrFrom, tx)
 	}
 }
 
@@ -338,9 +345,16 @@ func HandleTx(request []byte, chain *blockchain.BlockChain) {
 
 	txData := payload.Transaction
 	tx := blockchain.DeserializeTransaction(txData)
-	memoryPool[hex.EncodeToString(tx.ID)] = tx
+	desc, err := pool.Add(&tx, blockchain.UTXOSet{Blockchain: chain})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, id := range desc.Replaces {
+		fmt.Printf("Replaced transaction %s\n", id)
+	}
 
-	fmt.Printf("%s, %d", nodeAddress, len(memoryPool))
+	fmt.Printf("%s, %d\n", nodeAddress, pool.Count())
 
 	if nodeAddress == KnownNodes[0] {
 		for _, node := range KnownNodes {
@@ -349,54 +363,12 @@ func HandleTx(request []byte, chain *blockchain.BlockChain) {
 			}
 		}
 	} else {
-		if len(memoryPool) >= 2 && len(mineAddress) > 0 {
+		if len(mineAddress) > 0 && miningPolicy.ready() {
 			MineTx(chain)
 		}
 	}
 }
 
-// MineTx 将交易池进行打包挖矿
-func MineTx(chain *blockchain.BlockChain) {
-	var txs []*blockchain.Transaction
-
-	for id := range memoryPool {
-		fmt.Printf("tx: %s\n", memoryPool[id].ID)
-		tx := memoryPool[id]
-		if chain.VerifyTransaction(&tx) {
-			txs = append(txs, &tx)
-		}
-	}
-
-	if len(txs) == 0 {
-		fmt.Println("All Transactions are invalid")
-		return
-	}
-
-	cbTx := blockchain.CoinbaseTx(mineAddress, "")
-	txs = append(txs, cbTx)
-
-	newBlock := chain.MineBlock(txs)
-	UTXOSet := blockchain.UTXOSet{chain}
-	UTXOSet.Reindex()
-
-	fmt.Println("New Block mined")
-
-	for _, tx := range txs {
-		txID := hex.EncodeToString(tx.ID)
-		delete(memoryPool, txID)
-	}
-
-	for _, node := range KnownNodes {
-		if node != nodeAddress {
-			SendInv(node, "block", [][]byte{newBlock.Hash})
-		}
-	}
-
-	if len(memoryPool) > 0 {
-		MineTx(chain)
-	}
-}
-
 // HandleVersion 处理Version消息
 func HandleVersion(request []byte, chain *blockchain.BlockChain) {
 	var buff bytes.Buffer
@@ -439,6 +411,10 @@ func HandleConnection(conn net.Conn, chain *blockchain.BlockChain) {
 	command := BytesToCmd(req[:commandLength])
 	fmt.Printf("Received %s command\n", command)
 
+	if handleControl(conn, command, req) {
+		return
+	}
+
 	switch command {
 	case "addr":
 		HandleAddr(req)
@@ -461,9 +437,10 @@ func HandleConnection(conn net.Conn, chain *blockchain.BlockChain) {
 }
 
 // StartServer 全节点启动
-func StartServer(nodeID, minerAddress string) {
+func StartServer(nodeID, minerAddress string, policy MiningPolicy) {
 	nodeAddress = fmt.Sprintf("localhost:%s", nodeID)
 	mineAddress = minerAddress
+	miningPolicy = policy
 
 	//绑定节点地址并侦听连接请求
 	ln, err := net.Listen(protocol, nodeAddress)
@@ -475,7 +452,16 @@ func StartServer(nodeID, minerAddress string) {
 	// 获取对应节点的区块链对象
 	chain := blockchain.ContinueBlockChain(nodeID)
 	defer chain.Database.Close()
+	mempoolPath = fmt.Sprintf(mempoolFile, nodeID)
+	loadMempool(chain)
+	if err := writeControlCookie(nodeID); err != nil {
+		log.Panic(err)
+	}
 	go CloseDB(chain)
+	go ExpireUnlockSession(nodeID)
+	if len(mineAddress) > 0 && miningPolicy.MaxWait > 0 {
+		go MineOnSchedule(chain)
+	}
 
 	// 向已知节点建立连接，发送当前节点的版本信息
 	if nodeAddress != KnownNodes[0] {
@@ -520,13 +506,24 @@ func NodeIsKnown(addr string) bool {
 	return false
 }
 
-// CloseDB 安全关闭数据库
+// ExpireUnlockSession 节点运行期间每秒检查钱包解锁会话，过期后立即删除会话密钥文件
+func ExpireUnlockSession(nodeID string) {
+	for range time.Tick(time.Second) {
+		if err := wallet.ExpireUnlockSession(nodeID); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// CloseDB 安全关闭数据库，关闭前保存交易池
 func CloseDB(chain *blockchain.BlockChain) {
 	d := death.NewDeath(syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 
 	d.WaitForDeathWithFunc(func() {
 		defer os.Exit(1)
 		defer runtime.Goexit()
+		saveMempool()
+		removeControlCookie()
 		chain.Database.Close()
 	})
 }
@@ -0,0 +1,196 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/mr-tron/base58"
+	"strings"
+)
+
+// 地址解析错误，通过 errors.Is 判断具体类型
+var (
+	ErrAddressFormat    = errors.New("unrecognized address format")
+	ErrInvalidCharacter = errors.New("invalid character")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrMixedCase        = errors.New("mixed upper and lower case")
+	ErrInvalidLength    = errors.New("invalid length")
+	ErrUnknownVersion   = errors.New("unknown address version")
+	ErrWrongNetwork     = errors.New("address is for a different network")
+)
+
+// 公钥哈希长度（RIPEMD160）
+const pubKeyHashLen = 20
+
+// Base58字符集，不含容易混淆的 0、O、I、l
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// 见证版本号：0为ECDSA地址（Bech32），1为Schnorr地址（Bech32m）
+const (
+	witnessVersionECDSA   = 0
+	witnessVersionSchnorr = 1
+)
+
+// AddressError 地址解析错误，Pos为出错字符的位置，无法定位时为-1
+type AddressError struct {
+	Address string
+	Pos     int
+	Err     error
+}
+
+func (e *AddressError) Error() string {
+	if e.Pos >= 0 {
+		return fmt.Sprintf("invalid address %q: %v at position %d", e.Address, e.Err, e.Pos)
+	}
+
+	return fmt.Sprintf("invalid address %q: %v", e.Address, e.Err)
+}
+
+func (e *AddressError) Unwrap() error {
+	return e.Err
+}
+
+// AddressEncoding 地址的编码方式
+type AddressEncoding int
+
+const (
+	EncodingBase58 AddressEncoding = iota // Base58Check，版本号0x00或SchnorrVersion
+	EncodingBech32                        // Bech32/Bech32m，带网络前缀
+)
+
+// Address 解析后的地址
+type Address struct {
+	PubKeyHash []byte          // 公钥哈希
+	Schnorr    bool            // 是否为需要Schnorr签名花费的地址
+	Encoding   AddressEncoding // 编码方式
+}
+
+// DecodeAddress 解析Base58Check或Bech32/Bech32m地址，错误时返回 *AddressError 而不会中断程序
+func DecodeAddress(address string, net *NetParams) (*Address, error) {
+	if isBech32Candidate(address) {
+		return decodeBech32Address(address, net)
+	}
+
+	return decodeBase58Address(address)
+}
+
+// NormalizeAddress 将任意编码的地址转换为Base58Check形式，钱包中的地址统一以该形式保存
+func NormalizeAddress(address string) (string, error) {
+	decoded, err := DecodeAddress(address, ActiveNetParams)
+	if err != nil {
+		return "", err
+	}
+
+	return decoded.Base58(), nil
+}
+
+// Base58 地址的Base58Check编码
+func (a *Address) Base58() string {
+	addrVersion := version
+	if a.Schnorr {
+		addrVersion = SchnorrVersion
+	}
+
+	payload := append([]byte{addrVersion}, a.PubKeyHash...)
+
+	return string(Base58Encode(append(payload, Checksum(payload)...)))
+}
+
+// Bech32 地址的Bech32编码，ECDSA地址使用见证版本0与Bech32，Schnorr地址使用见证版本1与Bech32m
+func (a *Address) Bech32(net *NetParams) string {
+	witnessVersion, encoding := byte(witnessVersionECDSA), Bech32
+	if a.Schnorr {
+		witnessVersion, encoding = witnessVersionSchnorr, Bech32m
+	}
+
+	program, _ := ConvertBits(a.PubKeyHash, 8, 5, true)
+	encoded, err := Bech32Encode(net.Bech32HRP, append([]byte{witnessVersion}, program...), encoding)
+	if err != nil {
+		return ""
+	}
+
+	return encoded
+}
+
+// Bech32Address 钱包的Bech32地址
+func (w Wallet) Bech32Address(net *NetParams) string {
+	address := Address{PublicKeyHash(w.PublicKey), w.IsSchnorr(), EncodingBech32}
+
+	return address.Bech32(net)
+}
+
+// decodeBase58Address 解析Base58Check地址：版本号 || 公钥哈希 || 4字节校验和
+func decodeBase58Address(address string) (*Address, error) {
+	for i, c := range address {
+		if !strings.ContainsRune(base58Alphabet, c) {
+			return nil, &AddressError{address, i, ErrInvalidCharacter}
+		}
+	}
+
+	decoded, err := base58.Decode(address)
+	if err != nil {
+		return nil, &AddressError{address, -1, ErrAddressFormat}
+	}
+	if len(decoded) != 1+pubKeyHashLen+ChecksumLen {
+		return nil, &AddressError{address, -1, ErrInvalidLength}
+	}
+
+	payload, checksum := decoded[:len(decoded)-ChecksumLen], decoded[len(decoded)-ChecksumLen:]
+	if !bytes.Equal(Checksum(payload), checksum) {
+		return nil, &AddressError{address, -1, ErrChecksumMismatch}
+	}
+
+	switch payload[0] {
+	case version:
+		return &Address{payload[1:], false, EncodingBase58}, nil
+	case SchnorrVersion:
+		return &Address{payload[1:], true, EncodingBase58}, nil
+	default:
+		return nil, &AddressError{address, 0, ErrUnknownVersion}
+	}
+}
+
+// decodeBech32Address 解析Bech32地址：hrp || "1" || 见证版本 || 公钥哈希 || 校验和
+func decodeBech32Address(address string, net *NetParams) (*Address, error) {
+	hrp, data, encoding, err := Bech32Decode(address)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != net.Bech32HRP {
+		return nil, &AddressError{address, 0, ErrWrongNetwork}
+	}
+	if len(data) == 0 {
+		return nil, &AddressError{address, -1, ErrInvalidLength}
+	}
+
+	program, err := ConvertBits(data[1:], 5, 8, false)
+	if err != nil || len(program) != pubKeyHashLen {
+		return nil, &AddressError{address, -1, ErrInvalidLength}
+	}
+
+	// 见证版本决定签名算法，且必须与编码变体匹配
+	pos := len(hrp) + 1
+	switch {
+	case data[0] == witnessVersionECDSA && encoding == Bech32:
+		return &Address{program, false, EncodingBech32}, nil
+	case data[0] == witnessVersionSchnorr && encoding == Bech32m:
+		return &Address{program, true, EncodingBech32}, nil
+	case data[0] <= witnessVersionSchnorr:
+		return nil, &AddressError{address, -1, ErrChecksumMismatch}
+	default:
+		return nil, &AddressError{address, pos, ErrUnknownVersion}
+	}
+}
+
+// isBech32Candidate 地址是否以已知网络的Bech32前缀开头
+// Base58Check地址的首字符由版本号决定（'1'或'Q'~'Z'之间），不会与这些前缀冲突
+func isBech32Candidate(address string) bool {
+	lower := strings.ToLower(address)
+	for _, net := range []*NetParams{&MainNetParams, &TestNetParams} {
+		if strings.HasPrefix(lower, net.Bech32HRP+"1") {
+			return true
+		}
+	}
+
+	return false
+}
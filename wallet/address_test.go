@@ -0,0 +1,82 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestBech32Vectors(t *testing.T) {
+	// BIP173 / BIP350 测试向量
+	valid := map[string]Bech32Encoding{
+		"A12UEL5L": Bech32,
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw": Bech32,
+		"A1LQFN3A": Bech32m,
+		"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx": Bech32m,
+	}
+	for s, encoding := range valid {
+		if _, _, got, err := Bech32Decode(s); err != nil || got != encoding {
+			t.Errorf("Bech32Decode(%s) error: 期望 %s，实际 %s %v", s, encoding, got, err)
+		}
+	}
+
+	if _, _, _, err := Bech32Decode("A12uEL5L"); !errors.Is(err, ErrMixedCase) {
+		t.Errorf("Bech32Decode error: 大小写混用应返回 ErrMixedCase，实际 %v", err)
+	}
+}
+
+func TestDecodeAddress(t *testing.T) {
+	pubKeyHash := "751e76e8199196d454941c45d1b3a323f1433bd6"
+	for _, address := range []string{
+		"1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH",
+		"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4",
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+	} {
+		decoded, err := DecodeAddress(address, &MainNetParams)
+		if err != nil {
+			t.Fatalf("DecodeAddress(%s) error: %v", address, err)
+		}
+		if hex.EncodeToString(decoded.PubKeyHash) != pubKeyHash || decoded.Schnorr {
+			t.Errorf("DecodeAddress(%s) error: 公钥哈希为 %x", address, decoded.PubKeyHash)
+		}
+	}
+
+	// 单个字符的输入错误能定位到具体位置
+	_, err := DecodeAddress("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", &MainNetParams)
+	var addrErr *AddressError
+	if !errors.As(err, &addrErr) || !errors.Is(err, ErrChecksumMismatch) || addrErr.Pos != 41 {
+		t.Errorf("DecodeAddress error: 期望在位置41校验失败，实际 %v", err)
+	}
+	_, err = DecodeAddress("1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAM0", &MainNetParams)
+	if !errors.As(err, &addrErr) || !errors.Is(err, ErrInvalidCharacter) || addrErr.Pos != 33 {
+		t.Errorf("DecodeAddress error: 期望在位置33出现非法字符，实际 %v", err)
+	}
+	if _, err := DecodeAddress("1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMJ", &MainNetParams); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("DecodeAddress error: 期望 ErrChecksumMismatch，实际 %v", err)
+	}
+
+	// 其他网络的地址被拒绝
+	testnet := Address{make([]byte, pubKeyHashLen), false, EncodingBech32}
+	if _, err := DecodeAddress(testnet.Bech32(&TestNetParams), &MainNetParams); !errors.Is(err, ErrWrongNetwork) {
+		t.Errorf("DecodeAddress error: 期望 ErrWrongNetwork，实际 %v", err)
+	}
+
+	for _, garbage := range []string{"", "1", "bc1", "11111111111111111111", "not an address"} {
+		if ValidateAddress(garbage) {
+			t.Errorf("ValidateAddress(%q) error: 无效地址校验通过", garbage)
+		}
+	}
+}
+
+func TestBech32AddressRoundTrip(t *testing.T) {
+	for _, w := range []*Wallet{NewWallet(), NewSchnorrWallet()} {
+		encoded := w.Bech32Address(&MainNetParams)
+		normalized, err := NormalizeAddress(encoded)
+		if err != nil {
+			t.Fatalf("NormalizeAddress(%s) error: %v", encoded, err)
+		}
+		if normalized != string(w.GenerateAddress()) {
+			t.Errorf("NormalizeAddress error: %s 对应 %s，期望 %s", encoded, normalized, w.GenerateAddress())
+		}
+	}
+}
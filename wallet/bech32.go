@@ -0,0 +1,233 @@
+package wallet
+
+import (
+	"errors"
+	"strings"
+)
+
+// Bech32字符集，每个字符表示5位数据
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Bech32编码的校验和长度与总长度上限
+const (
+	bech32ChecksumLen = 6
+	bech32MaxLen      = 90
+)
+
+// Bech32Encoding Bech32编码变体，两者只有校验和常数不同
+type Bech32Encoding uint32
+
+const (
+	Bech32  Bech32Encoding = 1          // BIP173
+	Bech32m Bech32Encoding = 0x2bc830a3 // BIP350
+)
+
+// String 编码变体的名称
+func (e Bech32Encoding) String() string {
+	switch e {
+	case Bech32:
+		return "bech32"
+	case Bech32m:
+		return "bech32m"
+	default:
+		return "unknown"
+	}
+}
+
+// Bech32Encode 将5位分组的数据编码为 hrp || "1" || data || checksum
+func Bech32Encode(hrp string, data []byte, encoding Bech32Encoding) (string, error) {
+	hrp = strings.ToLower(hrp)
+	if len(hrp) == 0 || len(hrp)+1+len(data)+bech32ChecksumLen > bech32MaxLen {
+		return "", ErrInvalidLength
+	}
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	combined := append(append([]byte{}, data...), bech32Checksum(hrp, data, encoding)...)
+	for _, d := range combined {
+		if d >= 32 {
+			return "", errors.New("bech32 data must be 5-bit groups")
+		}
+		sb.WriteByte(bech32Charset[d])
+	}
+
+	return sb.String(), nil
+}
+
+// Bech32Decode 解析Bech32/Bech32m字符串，返回hrp、5位分组的数据（不含校验和）与编码变体
+// 校验失败时返回的 *AddressError 会尽量指出出错字符的位置
+func Bech32Decode(s string) (string, []byte, Bech32Encoding, error) {
+	if len(s) > bech32MaxLen {
+		return "", nil, 0, &AddressError{s, -1, ErrInvalidLength}
+	}
+
+	// 不允许大小写混用
+	lower, upper := strings.ToLower(s), strings.ToUpper(s)
+	if s != lower && s != upper {
+		return "", nil, 0, &AddressError{s, mixedCasePos(s), ErrMixedCase}
+	}
+	s = lower
+
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+1+bech32ChecksumLen > len(s) {
+		return "", nil, 0, &AddressError{s, -1, ErrAddressFormat}
+	}
+
+	hrp := s[:sep]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, 0, &AddressError{s, i, ErrInvalidCharacter}
+		}
+	}
+
+	data := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		d := strings.IndexByte(bech32Charset, s[i])
+		if d < 0 {
+			return "", nil, 0, &AddressError{s, i, ErrInvalidCharacter}
+		}
+		data = append(data, byte(d))
+	}
+
+	var encoding Bech32Encoding
+	switch bech32Polymod(append(bech32HRPExpand(hrp), data...)) {
+	case uint32(Bech32):
+		encoding = Bech32
+	case uint32(Bech32m):
+		encoding = Bech32m
+	default:
+		return "", nil, 0, &AddressError{s, locateBech32Error(hrp, data, sep+1), ErrChecksumMismatch}
+	}
+
+	return hrp, data[:len(data)-bech32ChecksumLen], encoding, nil
+}
+
+// ConvertBits 在不同位宽的分组之间转换，如8位字节与Bech32的5位分组
+func ConvertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var acc uint32
+	var bits uint
+	maxValue := uint32(1)<<toBits - 1
+
+	var out []byte
+	for _, b := range data {
+		if uint32(b)>>fromBits != 0 {
+			return nil, errors.New("invalid data range")
+		}
+		acc = acc<<fromBits | uint32(b)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxValue))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxValue != 0 {
+		return nil, errors.New("invalid padding")
+	}
+
+	return out, nil
+}
+
+// bech32Polymod BCH校验码的多项式取模
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+// bech32HRPExpand hrp参与校验和计算时的展开形式
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+
+	return expanded
+}
+
+// bech32Checksum 计算6个字符的校验和
+func bech32Checksum(hrp string, data []byte, encoding Bech32Encoding) []byte {
+	values := append(bech32HRPExpand(hrp), data...)
+	values = append(values, make([]byte, bech32ChecksumLen)...)
+	mod := bech32Polymod(values) ^ uint32(encoding)
+
+	checksum := make([]byte, bech32ChecksumLen)
+	for i := range checksum {
+		checksum[i] = byte(mod >> (5 * (5 - i)) & 31)
+	}
+
+	return checksum
+}
+
+// locateBech32Error 定位单个字符的输入错误：逐个位置尝试替换，只有一种替换能通过校验时即为出错位置
+// BCH码保证单个错误一定能被检测，找不到唯一位置时返回-1
+func locateBech32Error(hrp string, data []byte, offset int) int {
+	prefix := bech32HRPExpand(hrp)
+	values := make([]byte, len(prefix)+len(data))
+	copy(values, prefix)
+
+	found := -1
+	for i := range data {
+		copy(values[len(prefix):], data)
+		for c := byte(0); c < 32; c++ {
+			if c == data[i] {
+				continue
+			}
+			values[len(prefix)+i] = c
+			if mod := bech32Polymod(values); mod == uint32(Bech32) || mod == uint32(Bech32m) {
+				if found >= 0 {
+					return -1
+				}
+				found = offset + i
+				break
+			}
+		}
+	}
+
+	return found
+}
+
+// mixedCasePos 第一个与首个字母大小写不一致的字符位置
+func mixedCasePos(s string) int {
+	firstUpper := -1
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 'A' || (c > 'Z' && c < 'a') || c > 'z' {
+			continue
+		}
+		isUpper := c <= 'Z'
+		if firstUpper < 0 {
+			if isUpper {
+				firstUpper = 1
+			} else {
+				firstUpper = 0
+			}
+			continue
+		}
+		if isUpper != (firstUpper == 1) {
+			return i
+		}
+	}
+
+	return -1
+}
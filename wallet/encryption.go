@@ -0,0 +1,459 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 解锁会话密钥文件，保存会话的过期时间与每次解锁时随机生成的会话密钥
+const sessionKeyFile = "./tmp/wallet_session_%s"
+
+// scrypt默认参数
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// 钱包加密相关错误
+var (
+	ErrWalletLocked        = errors.New("wallet is locked, unlock it with walletpassphrase first")
+	ErrWalletNotEncrypted  = errors.New("wallet is not encrypted")
+	ErrWalletEncrypted     = errors.New("wallet is already encrypted")
+	ErrIncorrectPassphrase = errors.New("incorrect wallet passphrase")
+	ErrPassphraseRequired  = errors.New("wallet unlocked by a session can only sign, this operation needs the wallet passphrase")
+)
+
+// 用于校验口令的明文，加密后保存在钱包文件中
+var passphraseCheck = []byte("wallet passphrase check")
+
+// WalletCrypto 钱包加密参数：scrypt派生密钥，XChaCha20-Poly1305加密私钥与种子
+type WalletCrypto struct {
+	KDF   string // 密钥派生算法
+	Salt  []byte // 盐值
+	N     int    // scrypt参数
+	R     int
+	P     int
+	Check []byte // passphraseCheck的密文，用于校验口令
+}
+
+// UnlockSession 解锁会话，命令行每条命令都是独立进程，解锁状态需要保存在文件中
+// 每次解锁随机生成会话密钥，用它加密一份私钥与种子的副本写入钱包文件，会话密钥单独写入权限为0600的文件
+// 口令派生的密钥从不写入磁盘；过期时间作为附加数据参与认证，修改过期时间会使副本无法解密
+// 超时只是建议性的：会话密钥文件在过期后的下一条命令或运行中的节点巡检时才被删除，
+// 此前能同时读取钱包文件与会话密钥文件的人仍可解密副本，会话期间应保护好钱包目录
+type UnlockSession struct {
+	Expires time.Time
+	Secrets []byte // sessionSecrets的密文
+}
+
+// sessionSecrets 解锁会话中的私钥与种子副本
+type sessionSecrets struct {
+	Keys map[string][]byte // 地址到私钥的映射
+	Seed []byte
+}
+
+// IsEncrypted 钱包是否已加密
+func (ws *Wallets) IsEncrypted() bool {
+	return ws.Crypto != nil
+}
+
+// IsLocked 钱包是否处于锁定状态，锁定时私钥与种子不可用
+func (ws *Wallets) IsLocked() bool {
+	return ws.Crypto != nil && ws.key == nil && !ws.session
+}
+
+// requireKey 检查钱包是否可以加密新的私钥与种子，由解锁会话解锁的钱包没有口令派生的密钥
+func (ws *Wallets) requireKey() error {
+	if ws.IsLocked() {
+		return ErrWalletLocked
+	}
+	if ws.Crypto != nil && ws.key == nil {
+		return ErrPassphraseRequired
+	}
+
+	return nil
+}
+
+// Encrypt 使用口令加密钱包，加密后钱包处于解锁状态，保存文件时私钥与种子只以密文形式写入
+func (ws *Wallets) Encrypt(passphrase string) error {
+	if ws.Crypto != nil {
+		return ErrWalletEncrypted
+	}
+	if passphrase == "" {
+		return errors.New("passphrase must not be empty")
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	params := &WalletCrypto{"scrypt", salt, scryptN, scryptR, scryptP, nil}
+	key, err := params.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+	if params.Check, err = seal(key, passphraseCheck, nil); err != nil {
+		return err
+	}
+
+	ws.Crypto = params
+	ws.key = key
+
+	return ws.encryptSecrets()
+}
+
+// Unlock 使用口令解锁钱包
+func (ws *Wallets) Unlock(passphrase string) error {
+	if ws.Crypto == nil {
+		return ErrWalletNotEncrypted
+	}
+
+	key, err := ws.Crypto.deriveKey(passphrase)
+	if err != nil {
+		return err
+	}
+
+	return ws.unlockWithKey(key)
+}
+
+// Lock 清除内存中的私钥、种子与密钥，并结束解锁会话
+func (ws *Wallets) Lock() {
+	if ws.Crypto == nil {
+		return
+	}
+
+	for _, w := range ws.Wallets {
+		w.PrivateKey.D = nil
+	}
+	if ws.HD != nil && ws.HD.EncryptedSeed != nil {
+		ws.HD.Seed = nil
+	}
+	ws.key = nil
+	ws.session = false
+	ws.Session = nil
+}
+
+// unlockWithKey 使用派生密钥解密所有私钥与种子
+func (ws *Wallets) unlockWithKey(key []byte) error {
+	if _, err := open(key, ws.Crypto.Check, nil); err != nil {
+		return ErrIncorrectPassphrase
+	}
+
+	for address, w := range ws.Wallets {
+		if w.encryptedKey == nil {
+			continue
+		}
+
+		keyBytes, err := open(key, w.encryptedKey, w.PublicKey)
+		if err != nil {
+			return fmt.Errorf("decrypt key of %s: %w", address, err)
+		}
+		w.setPrivateKey(keyBytes)
+	}
+
+	if ws.HD != nil && ws.HD.EncryptedSeed != nil {
+		seed, err := open(key, ws.HD.EncryptedSeed, nil)
+		if err != nil {
+			return fmt.Errorf("decrypt HD seed: %w", err)
+		}
+		ws.HD.Seed = seed
+	}
+
+	ws.key = key
+
+	return ws.deriveKeys()
+}
+
+// deriveKeys 由种子重新派生解锁会话期间生成的地址的私钥，这些地址在文件中只有公钥
+func (ws *Wallets) deriveKeys() error {
+	if ws.HD == nil || ws.HD.Seed == nil {
+		return nil
+	}
+
+	for address, w := range ws.Wallets {
+		path := ws.HD.Paths[address]
+		if w.PrivateKey.D != nil || path == "" {
+			continue
+		}
+
+		derived, err := ws.HD.DeriveWallet(path, strings.HasPrefix(path, fmt.Sprintf("m/%d'", PurposeBIP86)))
+		if err != nil {
+			return fmt.Errorf("derive key of %s: %w", address, err)
+		}
+		w.setPrivateKey(privateKeyBytes(&derived.PrivateKey))
+	}
+
+	return nil
+}
+
+// encryptSecrets 加密尚未加密的私钥与种子（新生成的地址在保存前加密）
+func (ws *Wallets) encryptSecrets() error {
+	if ws.Crypto == nil {
+		return nil
+	}
+
+	for address, w := range ws.Wallets {
+		if w.encryptedKey != nil || w.PrivateKey.D == nil {
+			continue
+		}
+		if ws.key == nil {
+			// 解锁会话期间派生的地址只保存公钥，下次输入口令解锁时由种子重新派生并加密
+			if ws.session && ws.GetPath(address) != "" {
+				continue
+			}
+			return ws.requireKey()
+		}
+
+		encrypted, err := seal(ws.key, privateKeyBytes(&w.PrivateKey), w.PublicKey)
+		if err != nil {
+			return err
+		}
+		w.encryptedKey = encrypted
+	}
+
+	if ws.HD != nil && ws.HD.EncryptedSeed == nil {
+		if ws.key == nil {
+			return ws.requireKey()
+		}
+
+		encrypted, err := seal(ws.key, ws.HD.Seed, nil)
+		if err != nil {
+			return err
+		}
+		ws.HD.EncryptedSeed = encrypted
+	}
+
+	return nil
+}
+
+// GetSigningWallet 获取可用于签名的钱包，钱包锁定或地址不属于本钱包时返回错误
+func (ws *Wallets) GetSigningWallet(address string) (*Wallet, error) {
+	if ws.IsWatchOnly(address) {
+		return nil, fmt.Errorf("cannot sign for %s: %w", address, ErrWatchOnly)
+	}
+
+	w, ok := ws.Wallets[address]
+	if !ok {
+		return nil, fmt.Errorf("address %s is not in this wallet", address)
+	}
+	if w.PrivateKey.D == nil {
+		return nil, ErrWalletLocked
+	}
+
+	return w, nil
+}
+
+// StartUnlockSession 开始解锁会话，在timeout时间内的后续命令无需再次输入口令，会话数据随钱包文件一起保存
+// 需要使用口令解锁的钱包，每次开始会话都会生成新的会话密钥
+func (ws *Wallets) StartUnlockSession(nodeId string, timeout time.Duration) error {
+	if ws.Crypto == nil {
+		return ErrWalletNotEncrypted
+	}
+	if err := ws.requireKey(); err != nil {
+		return err
+	}
+
+	secrets := sessionSecrets{Keys: make(map[string][]byte)}
+	for address, w := range ws.Wallets {
+		if w.PrivateKey.D != nil {
+			secrets.Keys[address] = privateKeyBytes(&w.PrivateKey)
+		}
+	}
+	if ws.HD != nil {
+		secrets.Seed = ws.HD.Seed
+	}
+
+	var content bytes.Buffer
+	if err := gob.NewEncoder(&content).Encode(secrets); err != nil {
+		return err
+	}
+
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	expires := time.Now().Add(timeout)
+	sealed, err := seal(key, content.Bytes(), sessionData(expires))
+	if err != nil {
+		return err
+	}
+
+	// 会话密钥文件中同时记录过期时间，不加载钱包也能判断会话是否过期
+	sessionFile := fmt.Sprintf(sessionKeyFile, nodeId)
+	if err := os.MkdirAll(filepath.Dir(sessionFile), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(sessionFile, append(sessionData(expires), key...), 0600); err != nil {
+		return err
+	}
+
+	ws.Session = &UnlockSession{expires, sealed}
+	ws.SaveFile(nodeId)
+
+	return nil
+}
+
+// EndUnlockSession 结束解锁会话，从钱包文件中删除会话数据并删除会话密钥，钱包恢复锁定
+func (ws *Wallets) EndUnlockSession(nodeId string) error {
+	ws.Lock()
+	ws.SaveFile(nodeId)
+
+	return RemoveUnlockSession(nodeId)
+}
+
+// RemoveUnlockSession 删除会话密钥文件
+func RemoveUnlockSession(nodeId string) error {
+	err := os.Remove(fmt.Sprintf(sessionKeyFile, nodeId))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// ExpireUnlockSession 会话已过期或会话密钥文件无效时删除会话密钥文件，不需要加载钱包
+// 没有会话密钥的会话数据无法解密，会在下次加载钱包时从钱包文件中删除
+func ExpireUnlockSession(nodeId string) error {
+	_, expires, err := readSessionKey(nodeId)
+	if os.IsNotExist(err) || err == nil && time.Now().Before(expires) {
+		return nil
+	}
+
+	return RemoveUnlockSession(nodeId)
+}
+
+// readSessionKey 读取会话密钥文件中的会话密钥与过期时间
+func readSessionKey(nodeId string) ([]byte, time.Time, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf(sessionKeyFile, nodeId))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(content) != 8+chacha20poly1305.KeySize {
+		return nil, time.Time{}, errors.New("invalid wallet session key file")
+	}
+	expires := time.Unix(0, int64(binary.BigEndian.Uint64(content[:8])))
+
+	return content[8:], expires, nil
+}
+
+// loadUnlockSession 使用会话密钥解密未过期的会话数据并解锁钱包
+// 会话过期或无法解密时，立即从钱包文件中删除会话数据并删除会话密钥
+func (ws *Wallets) loadUnlockSession(nodeId string) {
+	key, _, err := readSessionKey(nodeId)
+	if ws.Session == nil {
+		if !os.IsNotExist(err) {
+			RemoveUnlockSession(nodeId)
+		}
+		return
+	}
+
+	if err == nil && time.Now().Before(ws.Session.Expires) {
+		if err = ws.openUnlockSession(key); err == nil {
+			return
+		}
+	}
+
+	ws.EndUnlockSession(nodeId)
+}
+
+// openUnlockSession 使用会话密钥解密私钥与种子的副本
+func (ws *Wallets) openUnlockSession(key []byte) error {
+	content, err := open(key, ws.Session.Secrets, sessionData(ws.Session.Expires))
+	if err != nil {
+		return err
+	}
+
+	var secrets sessionSecrets
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&secrets); err != nil {
+		return err
+	}
+
+	for address, keyBytes := range secrets.Keys {
+		if w, ok := ws.Wallets[address]; ok {
+			w.setPrivateKey(keyBytes)
+		}
+	}
+	if ws.HD != nil {
+		ws.HD.Seed = secrets.Seed
+	}
+	ws.session = true
+
+	return ws.deriveKeys()
+}
+
+// sessionData 会话数据的附加认证数据，即过期时间
+func sessionData(expires time.Time) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(expires.UnixNano()))
+
+	return data
+}
+
+// deriveKey 由口令派生32字节的加密密钥
+func (c *WalletCrypto) deriveKey(passphrase string) ([]byte, error) {
+	if c.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported key derivation function %q", c.KDF)
+	}
+
+	return scrypt.Key([]byte(passphrase), c.Salt, c.N, c.R, c.P, chacha20poly1305.KeySize)
+}
+
+// seal 使用XChaCha20-Poly1305加密，密文格式为 nonce || ciphertext
+// additionalData（如公钥）参与认证，防止密文在不同记录之间被替换
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open 解密seal生成的密文
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, sealed, additionalData)
+}
+
+// setPrivateKey 设置私钥并重新计算公钥坐标
+func (w *Wallet) setPrivateKey(keyBytes []byte) {
+	w.PrivateKey.D = new(big.Int).SetBytes(keyBytes)
+	w.PrivateKey.PublicKey.X, w.PrivateKey.PublicKey.Y = w.PrivateKey.Curve.ScalarBaseMult(keyBytes)
+}
+
+// privateKeyBytes 私钥按曲线阶的长度定长编码
+func privateKeyBytes(privKey *ecdsa.PrivateKey) []byte {
+	keyBytes := make([]byte, orderLen(privKey.Curve.Params().N))
+	privKey.D.FillBytes(keyBytes)
+
+	return keyBytes
+}
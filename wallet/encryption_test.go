@@ -0,0 +1,162 @@
+package wallet
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWalletEncryption(t *testing.T) {
+	nodeId := "encryption_test"
+	defer os.Remove(fmt.Sprintf(walletFile, nodeId))
+	defer RemoveUnlockSession(nodeId)
+
+	wallets := Wallets{Wallets: make(map[string]*Wallet)}
+	legacy := wallets.AddWallet()
+	derived, _ := wallets.NewReceiveAddress(false)
+	privKey := privateKeyBytes(&wallets.Wallets[legacy].PrivateKey)
+
+	if err := wallets.Encrypt("correct horse"); err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	wallets.SaveFile(nodeId)
+
+	// 文件中不能出现明文私钥与种子
+	content, _ := ioutil.ReadFile(fmt.Sprintf(walletFile, nodeId))
+	if bytes.Contains(content, privKey) || bytes.Contains(content, wallets.HD.Seed) {
+		t.Fatal("SaveFile error: 加密钱包文件中包含明文私钥或种子")
+	}
+
+	// 重新加载后钱包处于锁定状态，不能签名也不能派生新地址
+	locked, err := CreateWallets(nodeId)
+	if err != nil {
+		t.Fatalf("CreateWallets error: %v", err)
+	}
+	if !locked.IsLocked() {
+		t.Fatal("LoadFile error: 加密钱包加载后未锁定")
+	}
+	if _, err := locked.GetSigningWallet(legacy); err != ErrWalletLocked {
+		t.Errorf("GetSigningWallet error: 锁定的钱包返回 %v", err)
+	}
+	if _, err := locked.NewReceiveAddress(false); err != ErrWalletLocked {
+		t.Errorf("NewReceiveAddress error: 锁定的钱包返回 %v", err)
+	}
+
+	if err := locked.Unlock("wrong"); err != ErrIncorrectPassphrase {
+		t.Errorf("Unlock error: 错误口令返回 %v", err)
+	}
+	if err := locked.Unlock("correct horse"); err != nil {
+		t.Fatalf("Unlock error: %v", err)
+	}
+	for _, address := range []string{legacy, derived} {
+		if _, err := locked.GetSigningWallet(address); err != nil {
+			t.Errorf("GetSigningWallet error: %v", err)
+		}
+	}
+	if !bytes.Equal(privateKeyBytes(&locked.Wallets[legacy].PrivateKey), privKey) {
+		t.Error("Unlock error: 解密得到的私钥不一致")
+	}
+
+	// 解锁会话在有效期内对后续加载生效，文件中不保存口令派生的密钥与明文私钥
+	if err := locked.StartUnlockSession(nodeId, time.Minute); err != nil {
+		t.Fatalf("StartUnlockSession error: %v", err)
+	}
+	for _, file := range []string{walletFile, sessionKeyFile} {
+		content, _ := ioutil.ReadFile(fmt.Sprintf(file, nodeId))
+		if bytes.Contains(content, locked.key) || bytes.Contains(content, privKey) {
+			t.Errorf("StartUnlockSession error: %s 中包含加密密钥或明文私钥", file)
+		}
+	}
+	session, _ := CreateWallets(nodeId)
+	if session.IsLocked() {
+		t.Fatal("loadUnlockSession error: 会话有效期内钱包仍然锁定")
+	}
+	if _, err := session.GetSigningWallet(legacy); err != nil {
+		t.Errorf("GetSigningWallet error: %v", err)
+	}
+
+	// 会话期间可以派生新地址，但不能导入私钥；新地址的私钥在输入口令解锁时由种子派生并加密
+	address, err := session.NewReceiveAddress(false)
+	if err != nil {
+		t.Fatalf("NewReceiveAddress error: %v", err)
+	}
+	newKey := privateKeyBytes(&session.Wallets[address].PrivateKey)
+	if _, err := session.ImportPrivateKey("", false); err != ErrPassphraseRequired {
+		t.Errorf("ImportPrivateKey error: 会话解锁的钱包返回 %v", err)
+	}
+	session.SaveFile(nodeId)
+	content, _ = ioutil.ReadFile(fmt.Sprintf(walletFile, nodeId))
+	if bytes.Contains(content, newKey) {
+		t.Error("SaveFile error: 会话期间派生的私钥以明文写入文件")
+	}
+	if session, _ := CreateWallets(nodeId); session.Wallets[address].PrivateKey.D == nil {
+		t.Error("loadUnlockSession error: 会话期间派生的地址无法签名")
+	}
+
+	unlocked, _ := CreateWallets(nodeId)
+	if err := unlocked.Unlock("correct horse"); err != nil {
+		t.Fatalf("Unlock error: %v", err)
+	}
+	if !bytes.Equal(privateKeyBytes(&unlocked.Wallets[address].PrivateKey), newKey) {
+		t.Error("Unlock error: 重新派生的私钥不一致")
+	}
+
+	// 会话过期后钱包锁定，会话数据与会话密钥立即删除
+	if err := unlocked.StartUnlockSession(nodeId, -time.Second); err != nil {
+		t.Fatalf("StartUnlockSession error: %v", err)
+	}
+	expired, _ := CreateWallets(nodeId)
+	if !expired.IsLocked() {
+		t.Error("loadUnlockSession error: 会话过期后钱包仍然解锁")
+	}
+	if _, err := os.Stat(fmt.Sprintf(sessionKeyFile, nodeId)); !os.IsNotExist(err) {
+		t.Error("loadUnlockSession error: 会话过期后会话密钥文件仍然存在")
+	}
+	if reloaded, _ := CreateWallets(nodeId); reloaded.Session != nil {
+		t.Error("loadUnlockSession error: 会话过期后钱包文件中仍有会话数据")
+	}
+}
+
+func TestExpireUnlockSession(t *testing.T) {
+	nodeId := "session_expiry_test"
+	sessionFile := fmt.Sprintf(sessionKeyFile, nodeId)
+	defer os.Remove(fmt.Sprintf(walletFile, nodeId))
+	defer RemoveUnlockSession(nodeId)
+
+	wallets := Wallets{Wallets: make(map[string]*Wallet)}
+	wallets.AddWallet()
+	if err := wallets.Encrypt("correct horse"); err != nil {
+		t.Fatalf("Encrypt error: %v", err)
+	}
+	if err := wallets.Unlock("correct horse"); err != nil {
+		t.Fatalf("Unlock error: %v", err)
+	}
+	if err := wallets.StartUnlockSession(nodeId, 100*time.Millisecond); err != nil {
+		t.Fatalf("StartUnlockSession error: %v", err)
+	}
+
+	// 会话有效期内保留会话密钥文件，过期后不加载钱包也会删除
+	if err := ExpireUnlockSession(nodeId); err != nil {
+		t.Fatalf("ExpireUnlockSession error: %v", err)
+	}
+	if _, err := os.Stat(sessionFile); err != nil {
+		t.Fatal("ExpireUnlockSession error: 会话有效期内删除了会话密钥文件")
+	}
+	time.Sleep(150 * time.Millisecond)
+	if err := ExpireUnlockSession(nodeId); err != nil {
+		t.Fatalf("ExpireUnlockSession error: %v", err)
+	}
+	if _, err := os.Stat(sessionFile); !os.IsNotExist(err) {
+		t.Error("ExpireUnlockSession error: 会话过期后会话密钥文件仍然存在")
+	}
+
+	// 无法识别的会话密钥文件同样删除
+	ioutil.WriteFile(sessionFile, []byte("invalid"), 0600)
+	ExpireUnlockSession(nodeId)
+	if _, err := os.Stat(sessionFile); !os.IsNotExist(err) {
+		t.Error("ExpireUnlockSession error: 无效的会话密钥文件未被删除")
+	}
+}
@@ -0,0 +1,293 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/mr-tron/base58"
+	"strconv"
+	"strings"
+)
+
+// 硬化派生的起始序号，序号不小于该值的子密钥只能由扩展私钥派生
+const HardenedKeyStart = uint32(0x80000000)
+
+// 种子长度范围（BIP32）
+const (
+	MinSeedBytes = 16
+	MaxSeedBytes = 64
+)
+
+// 扩展密钥序列化的版本号（主网 xprv/xpub）
+var (
+	xprvVersion = []byte{0x04, 0x88, 0xad, 0xe4}
+	xpubVersion = []byte{0x04, 0x88, 0xb2, 0x1e}
+)
+
+// 扩展密钥相关错误
+var (
+	ErrInvalidChild      = errors.New("derived key is invalid, use the next index")
+	ErrDeriveHardFromPub = errors.New("cannot derive a hardened child from an extended public key")
+	ErrInvalidSeedLen    = fmt.Errorf("seed length must be between %d and %d bytes", MinSeedBytes, MaxSeedBytes)
+	ErrInvalidExtKey     = errors.New("invalid extended key")
+)
+
+// ExtendedKey BIP32扩展密钥，由密钥与链码组成，可以逐层派生子密钥
+type ExtendedKey struct {
+	key       []byte // 私钥（32字节）或压缩公钥（33字节）
+	chainCode []byte // 链码
+	depth     uint8  // 派生深度，主密钥为0
+	parentFP  []byte // 父密钥指纹
+	childNum  uint32 // 在父密钥下的序号
+	isPrivate bool   // 是否为扩展私钥
+}
+
+// NewMasterKey 由种子生成主扩展私钥
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < MinSeedBytes || len(seed) > MaxSeedBytes {
+		return nil, ErrInvalidSeedLen
+	}
+
+	// I = HMAC-SHA512(Key = "Bitcoin seed", Data = seed)，左半部分为私钥，右半部分为链码
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	I := mac.Sum(nil)
+
+	var keyNum btcec.ModNScalar
+	if overflow := keyNum.SetByteSlice(I[:32]); overflow || keyNum.IsZero() {
+		return nil, ErrInvalidChild
+	}
+
+	return &ExtendedKey{I[:32], I[32:], 0, []byte{0, 0, 0, 0}, 0, true}, nil
+}
+
+// IsPrivate 是否为扩展私钥
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// Depth 派生深度
+func (k *ExtendedKey) Depth() uint8 {
+	return k.depth
+}
+
+// PublicKey 扩展密钥对应的33字节压缩公钥
+func (k *ExtendedKey) PublicKey() []byte {
+	if !k.isPrivate {
+		return k.key
+	}
+
+	priv, _ := btcec.PrivKeyFromBytes(k.key)
+
+	return priv.PubKey().SerializeCompressed()
+}
+
+// Child 派生第i个子密钥，i不小于HardenedKeyStart时为硬化派生
+// 返回ErrInvalidChild时（概率低于2^-127）应当跳过该序号
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	hardened := i >= HardenedKeyStart
+	if hardened && !k.isPrivate {
+		return nil, ErrDeriveHardFromPub
+	}
+
+	// 硬化派生：0x00 || 私钥 || i，普通派生：压缩公钥 || i
+	var data []byte
+	if hardened {
+		data = append([]byte{0x00}, k.key...)
+	} else {
+		data = append([]byte{}, k.PublicKey()...)
+	}
+	data = binary.BigEndian.AppendUint32(data, i)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	I := mac.Sum(nil)
+	IL, chainCode := I[:32], I[32:]
+
+	var ilNum btcec.ModNScalar
+	if overflow := ilNum.SetByteSlice(IL); overflow {
+		return nil, ErrInvalidChild
+	}
+
+	var childKey []byte
+	if k.isPrivate {
+		// 子私钥 = IL + 父私钥 (mod n)
+		var keyNum btcec.ModNScalar
+		keyNum.SetByteSlice(k.key)
+		keyNum.Add(&ilNum)
+		if keyNum.IsZero() {
+			return nil, ErrInvalidChild
+		}
+		keyBytes := keyNum.Bytes()
+		childKey = keyBytes[:]
+	} else {
+		// 子公钥 = IL·G + 父公钥
+		parent, err := btcec.ParsePubKey(k.key)
+		if err != nil {
+			return nil, err
+		}
+
+		var ilPoint, parentPoint, result btcec.JacobianPoint
+		btcec.ScalarBaseMultNonConst(&ilNum, &ilPoint)
+		parent.AsJacobian(&parentPoint)
+		btcec.AddNonConst(&ilPoint, &parentPoint, &result)
+		if (result.X.IsZero() && result.Y.IsZero()) || result.Z.IsZero() {
+			return nil, ErrInvalidChild
+		}
+		result.ToAffine()
+		childKey = btcec.NewPublicKey(&result.X, &result.Y).SerializeCompressed()
+	}
+
+	parentFP := PublicKeyHash(k.PublicKey())[:4]
+
+	return &ExtendedKey{childKey, chainCode, k.depth + 1, parentFP, i, k.isPrivate}, nil
+}
+
+// Neuter 由扩展私钥得到对应的扩展公钥，扩展公钥只能进行普通派生
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.isPrivate {
+		return k
+	}
+
+	return &ExtendedKey{k.PublicKey(), k.chainCode, k.depth, k.parentFP, k.childNum, false}
+}
+
+// Derive 按派生路径派生子密钥，如 m/44'/0'/0'/0/1，硬化序号以'或h结尾
+func (k *ExtendedKey) Derive(path string) (*ExtendedKey, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := k
+	for _, i := range indexes {
+		if key, err = key.Child(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// ParseDerivationPath 解析派生路径为各层的序号
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if len(parts) == 0 || (parts[0] != "m" && parts[0] != "M") {
+		return nil, fmt.Errorf("derivation path %q must start with m", path)
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") || strings.HasSuffix(part, "H")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+
+		i, err := strconv.ParseUint(part, 10, 32)
+		if err != nil || uint32(i) >= HardenedKeyStart {
+			return nil, fmt.Errorf("invalid index %q in derivation path %q", part, path)
+		}
+		if hardened {
+			i += uint64(HardenedKeyStart)
+		}
+		indexes = append(indexes, uint32(i))
+	}
+
+	return indexes, nil
+}
+
+// Wallet 由扩展私钥生成ECDSA钱包
+func (k *ExtendedKey) Wallet() (*Wallet, error) {
+	if !k.isPrivate {
+		return nil, errors.New("extended public key has no private key")
+	}
+
+	priv, _ := btcec.PrivKeyFromBytes(k.key)
+
+	return &Wallet{*priv.ToECDSA(), priv.PubKey().SerializeCompressed(), nil}, nil
+}
+
+// SchnorrWallet 由扩展私钥生成Schnorr钱包
+func (k *ExtendedKey) SchnorrWallet() (*Wallet, error) {
+	if !k.isPrivate {
+		return nil, errors.New("extended public key has no private key")
+	}
+
+	priv, _ := btcec.PrivKeyFromBytes(k.key)
+
+	return &Wallet{*priv.ToECDSA(), schnorr.SerializePubKey(priv.PubKey()), nil}, nil
+}
+
+// String 扩展密钥的Base58Check序列化（xprv/xpub）
+func (k *ExtendedKey) String() string {
+	var payload bytes.Buffer
+
+	if k.isPrivate {
+		payload.Write(xprvVersion)
+	} else {
+		payload.Write(xpubVersion)
+	}
+	payload.WriteByte(k.depth)
+	payload.Write(k.parentFP)
+	payload.Write(binary.BigEndian.AppendUint32(nil, k.childNum))
+	payload.Write(k.chainCode)
+	if k.isPrivate {
+		payload.WriteByte(0x00)
+	}
+	payload.Write(k.key)
+
+	return string(Base58Encode(append(payload.Bytes(), Checksum(payload.Bytes())...)))
+}
+
+// ParseExtendedKey 解析Base58Check编码的扩展密钥
+func ParseExtendedKey(s string) (*ExtendedKey, error) {
+	decoded, err := base58.Decode(s)
+	if err != nil || len(decoded) != 82 {
+		return nil, ErrInvalidExtKey
+	}
+
+	payload, checksum := decoded[:78], decoded[78:]
+	if !bytes.Equal(Checksum(payload), checksum) {
+		return nil, ErrInvalidExtKey
+	}
+
+	var isPrivate bool
+	switch {
+	case bytes.Equal(payload[:4], xprvVersion):
+		isPrivate = true
+	case bytes.Equal(payload[:4], xpubVersion):
+		isPrivate = false
+	default:
+		return nil, ErrInvalidExtKey
+	}
+
+	depth := payload[4]
+	parentFP := payload[5:9]
+	childNum := binary.BigEndian.Uint32(payload[9:13])
+	chainCode := payload[13:45]
+	keyData := payload[45:78]
+
+	var key []byte
+	if isPrivate {
+		if keyData[0] != 0x00 {
+			return nil, ErrInvalidExtKey
+		}
+		var keyNum btcec.ModNScalar
+		if overflow := keyNum.SetByteSlice(keyData[1:]); overflow || keyNum.IsZero() {
+			return nil, ErrInvalidExtKey
+		}
+		key = keyData[1:]
+	} else {
+		if _, err := btcec.ParsePubKey(keyData); err != nil {
+			return nil, ErrInvalidExtKey
+		}
+		key = keyData
+	}
+
+	return &ExtendedKey{key, chainCode, depth, parentFP, childNum, isPrivate}, nil
+}
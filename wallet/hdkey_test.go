@@ -0,0 +1,85 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestExtendedKeyBIP32(t *testing.T) {
+	// BIP32 测试向量1
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		t.Fatalf("NewMasterKey error: %v", err)
+	}
+
+	cases := []struct {
+		path, xprv, xpub string
+	}{
+		{
+			"m",
+			"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+			"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+		},
+		{
+			"m/0'/1/2'/2/1000000000",
+			"xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+			"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		},
+	}
+	for _, c := range cases {
+		key, err := master.Derive(c.path)
+		if err != nil {
+			t.Fatalf("Derive(%s) error: %v", c.path, err)
+		}
+		if key.String() != c.xprv {
+			t.Errorf("Derive(%s) error: xprv = %s", c.path, key)
+		}
+		if key.Neuter().String() != c.xpub {
+			t.Errorf("Derive(%s) error: xpub = %s", c.path, key.Neuter())
+		}
+
+		parsed, err := ParseExtendedKey(c.xprv)
+		if err != nil || parsed.String() != c.xprv {
+			t.Errorf("ParseExtendedKey(%s) error: %v", c.path, err)
+		}
+	}
+
+	// 扩展公钥的普通派生与扩展私钥派生得到相同的公钥
+	account, _ := master.Derive("m/44'/0'/0'")
+	fromPriv, _ := account.Derive("m/0/7")
+	fromPub, err := account.Neuter().Derive("m/0/7")
+	if err != nil || fromPub.String() != fromPriv.Neuter().String() {
+		t.Errorf("Derive error: 扩展公钥派生结果不一致 %v", err)
+	}
+	if _, err := account.Neuter().Child(HardenedKeyStart); err != ErrDeriveHardFromPub {
+		t.Error("Child error: 扩展公钥不应允许硬化派生")
+	}
+}
+
+func TestHDWalletAddresses(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	wallets := Wallets{Wallets: make(map[string]*Wallet)}
+	if err := wallets.InitHD(seed); err != nil {
+		t.Fatalf("InitHD error: %v", err)
+	}
+
+	first, _ := wallets.NewReceiveAddress(false)
+	second, _ := wallets.NewReceiveAddress(false)
+	change, _ := wallets.NewChangeAddress(false)
+	if first == second || first == change {
+		t.Fatal("NewReceiveAddress error: 派生出了重复的地址")
+	}
+	if wallets.GetPath(second) != "m/44'/0'/0'/0/1" || wallets.GetPath(change) != "m/44'/0'/0'/1/0" {
+		t.Errorf("GetPath error: %s, %s", wallets.GetPath(second), wallets.GetPath(change))
+	}
+
+	// 相同种子重新派生得到相同的地址与私钥
+	w, err := wallets.HD.DeriveWallet("m/44'/0'/0'/0/0", false)
+	if err != nil || string(w.GenerateAddress()) != first {
+		t.Errorf("DeriveWallet error: 重新派生的地址不一致 %v", err)
+	}
+	if wallets.Wallets[first].PrivateKey.D.Cmp(w.PrivateKey.D) != 0 {
+		t.Error("DeriveWallet error: 重新派生的私钥不一致")
+	}
+}
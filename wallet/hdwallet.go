@@ -0,0 +1,180 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// BIP44/BIP86派生路径中的用途编号
+const (
+	PurposeBIP44 = 44 // ECDSA地址
+	PurposeBIP86 = 86 // Schnorr地址
+)
+
+// 派生路径中的币种编号
+const CoinType = 0
+
+// 派生路径中的链编号
+const (
+	ExternalChain = 0 // 对外收款地址
+	InternalChain = 1 // 找零地址
+)
+
+// HDChain 分层确定性钱包状态，所有地址都由同一个种子按 m/purpose'/coin'/account'/change/index 派生
+// 备份一次种子即可恢复之后生成的全部地址
+type HDChain struct {
+	Seed          []byte            // 主种子，钱包锁定时为空
+	EncryptedSeed []byte            // 加密后的主种子，钱包未加密时为空
+	Account       uint32            // 当前使用的账户编号
+	NextIndex     map[string]uint32 // 各条派生链下一个未分配的序号，键为链路径，如 m/44'/0'/0'/0
+	Paths         map[string]string // 地址到派生路径的映射
+}
+
+// NewHDChain 由种子创建分层确定性钱包状态
+func NewHDChain(seed []byte) (*HDChain, error) {
+	if _, err := NewMasterKey(seed); err != nil {
+		return nil, err
+	}
+
+	return &HDChain{seed, nil, 0, make(map[string]uint32), make(map[string]string)}, nil
+}
+
+// MasterKey 主扩展私钥
+func (hd *HDChain) MasterKey() (*ExtendedKey, error) {
+	if hd.Seed == nil {
+		return nil, ErrWalletLocked
+	}
+
+	return NewMasterKey(hd.Seed)
+}
+
+// ChainPath 指定用途与链编号的派生链路径
+func (hd *HDChain) ChainPath(purpose, change uint32) string {
+	return fmt.Sprintf("m/%d'/%d'/%d'/%d", purpose, CoinType, hd.Account, change)
+}
+
+// DeriveWallet 按派生路径生成钱包，Schnorr钱包使用x-only公钥
+func (hd *HDChain) DeriveWallet(path string, schnorr bool) (*Wallet, error) {
+	master, err := hd.MasterKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := master.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if schnorr {
+		return key.SchnorrWallet()
+	}
+
+	return key.Wallet()
+}
+
+// nextWallet 在指定派生链上生成下一个钱包，并推进该链的序号
+func (hd *HDChain) nextWallet(purpose, change uint32) (*Wallet, string, error) {
+	chainPath := hd.ChainPath(purpose, change)
+
+	for {
+		index := hd.NextIndex[chainPath]
+		if index >= HardenedKeyStart {
+			return nil, "", errors.New("derivation chain exhausted")
+		}
+		hd.NextIndex[chainPath] = index + 1
+
+		path := fmt.Sprintf("%s/%d", chainPath, index)
+		w, err := hd.DeriveWallet(path, purpose == PurposeBIP86)
+		if errors.Is(err, ErrInvalidChild) {
+			// 派生结果无效时跳过该序号
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		return w, path, nil
+	}
+}
+
+// InitHD 使用指定种子初始化分层确定性钱包，已初始化时返回错误
+func (ws *Wallets) InitHD(seed []byte) error {
+	if ws.HD != nil {
+		return errors.New("wallet already has an HD seed")
+	}
+	if err := ws.requireKey(); err != nil {
+		return err
+	}
+
+	hd, err := NewHDChain(seed)
+	if err != nil {
+		return err
+	}
+	ws.HD = hd
+
+	return nil
+}
+
+// ensureHD 钱包尚未初始化种子时，随机生成一个新种子
+func (ws *Wallets) ensureHD() error {
+	if ws.HD != nil {
+		return nil
+	}
+
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return err
+	}
+
+	return ws.InitHD(seed)
+}
+
+// NewReceiveAddress 从派生树中取出下一个收款地址
+func (ws *Wallets) NewReceiveAddress(schnorr bool) (string, error) {
+	return ws.newHDAddress(ExternalChain, schnorr)
+}
+
+// NewChangeAddress 从派生树中取出下一个找零地址
+func (ws *Wallets) NewChangeAddress(schnorr bool) (string, error) {
+	return ws.newHDAddress(InternalChain, schnorr)
+}
+
+// newHDAddress 在指定链上派生新地址，并加入钱包集合
+func (ws *Wallets) newHDAddress(change uint32, schnorr bool) (string, error) {
+	if err := ws.ensureHD(); err != nil {
+		return "", err
+	}
+
+	purpose := uint32(PurposeBIP44)
+	if schnorr {
+		purpose = PurposeBIP86
+	}
+
+	w, path, err := ws.HD.nextWallet(purpose, change)
+	if err != nil {
+		return "", err
+	}
+
+	address := string(w.GenerateAddress())
+	ws.Wallets[address] = w
+	ws.HD.Paths[address] = path
+	delete(ws.WatchOnly, address)
+
+	addressPurpose := PurposeReceive
+	if change == InternalChain {
+		addressPurpose = PurposeChange
+	}
+	ws.recordAddress(address, addressPurpose)
+
+	return address, nil
+}
+
+// GetPath 获取地址的派生路径，非派生地址返回空字符串
+func (ws Wallets) GetPath(address string) string {
+	if ws.HD == nil {
+		return ""
+	}
+
+	return ws.HD.Paths[address]
+}
@@ -0,0 +1,67 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/gob"
+	"math/big"
+)
+
+// 旧版本的钱包文件直接对 ecdsa.PrivateKey 进行Gob编码，椭圆曲线以接口形式写入，类型名为注册时的P256曲线类型
+// 当前Go版本的P256曲线类型已经改变，因此以相同的名称注册一个只读取曲线参数的结构
+const legacyP256Name = "crypto/elliptic.p256Curve"
+
+func init() {
+	gob.RegisterName(legacyP256Name, legacyP256Curve{})
+}
+
+// legacyP256Curve 旧钱包文件中的P256曲线，只保存曲线参数
+type legacyP256Curve struct {
+	CurveParams *elliptic.CurveParams
+}
+
+// legacyWallets 旧版本的钱包文件结构
+type legacyWallets struct {
+	Wallets map[string]*legacyWallet
+}
+
+// legacyWallet 旧版本的钱包结构
+type legacyWallet struct {
+	PrivateKey legacyPrivateKey
+	PublicKey  []byte
+}
+
+// legacyPrivateKey 与 ecdsa.PrivateKey 的Gob编码对应
+type legacyPrivateKey struct {
+	PublicKey legacyPublicKey
+	D         *big.Int
+}
+
+// legacyPublicKey 与 ecdsa.PublicKey 的Gob编码对应
+type legacyPublicKey struct {
+	Curve interface{}
+	X, Y  *big.Int
+}
+
+// decodeLegacyWallets 解码旧版本的钱包文件，旧钱包只使用P256曲线
+func decodeLegacyWallets(content []byte) (*Wallets, error) {
+	var legacy legacyWallets
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&legacy); err != nil {
+		return nil, err
+	}
+
+	wallets := Wallets{Wallets: make(map[string]*Wallet, len(legacy.Wallets))}
+	for address, w := range legacy.Wallets {
+		if _, ok := w.PrivateKey.PublicKey.Curve.(legacyP256Curve); !ok || w.PrivateKey.D == nil {
+			continue
+		}
+
+		privKey := ecdsa.PrivateKey{D: w.PrivateKey.D}
+		privKey.Curve = elliptic.P256()
+		privKey.X, privKey.Y = privKey.Curve.ScalarBaseMult(w.PrivateKey.D.Bytes())
+		wallets.Wallets[address] = &Wallet{PrivateKey: privKey, PublicKey: w.PublicKey}
+	}
+
+	return &wallets, nil
+}
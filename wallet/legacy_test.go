@@ -0,0 +1,67 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// 基线版本写入的钱包文件，只有一个P256地址
+const legacyAddress = "168hBF8S2S6Jp89goTuYQrJJumdBJbPCJh"
+
+// copyLegacyWalletFile 将基线版本的钱包文件复制为指定节点的钱包文件
+func copyLegacyWalletFile(t *testing.T, nodeId string) {
+	content, err := ioutil.ReadFile("testdata/wallets_baseline.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll("./tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(fmt.Sprintf(walletFile, nodeId), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadLegacyWalletFile(t *testing.T) {
+	nodeId := "legacy_test"
+	copyLegacyWalletFile(t, nodeId)
+	defer os.Remove(fmt.Sprintf(walletFile, nodeId))
+
+	wallets, err := CreateWallets(nodeId)
+	if err != nil {
+		t.Fatalf("CreateWallets error: %v", err)
+	}
+	w, err := wallets.GetSigningWallet(legacyAddress)
+	if err != nil {
+		t.Fatalf("GetSigningWallet error: %v", err)
+	}
+	if w.PrivateKey.Curve != elliptic.P256() || string(w.GenerateAddress()) != legacyAddress {
+		t.Fatal("LoadFile error: 旧钱包的曲线或地址恢复错误")
+	}
+
+	// 旧钱包的私钥仍可签名，签名可以用地址对应的公钥验证
+	hash := sha256.Sum256([]byte("legacy wallet"))
+	sig, err := Sign(&w.PrivateKey, hash[:])
+	if err != nil {
+		t.Fatalf("Sign error: %v", err)
+	}
+	pubKey, err := ParsePublicKey(w.PublicKey)
+	if err != nil || !VerifySignature(pubKey, hash[:], sig) {
+		t.Errorf("VerifySignature error: 旧钱包的签名验证失败 %v", err)
+	}
+
+	// 保存后以当前格式重新加载，密钥保持不变
+	wallets.SaveFile(nodeId)
+	reloaded, err := CreateWallets(nodeId)
+	if err != nil {
+		t.Fatalf("CreateWallets error: %v", err)
+	}
+	if r := reloaded.Wallets[legacyAddress]; r == nil || r.PrivateKey.D.Cmp(w.PrivateKey.D) != 0 || !bytes.Equal(r.PublicKey, w.PublicKey) {
+		t.Error("SaveFile error: 重新保存后旧钱包的密钥发生变化")
+	}
+}
@@ -0,0 +1,99 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+)
+
+// 消息签名的域分隔前缀，保证签名的消息不可能同时是一笔有效交易的签名摘要
+const messageMagic = "Bitcoin Signed Message:\n"
+
+// ErrMessageSignature 签名格式错误，无法从中恢复公钥
+var ErrMessageSignature = errors.New("malformed message signature")
+
+// MessageHash 计算带域分隔前缀的消息摘要：
+// SHA256(SHA256(len(magic) || magic || len(message) || message))
+func MessageHash(message string) []byte {
+	var buf bytes.Buffer
+	writeVarString(&buf, messageMagic)
+	writeVarString(&buf, message)
+
+	first := sha256.Sum256(buf.Bytes())
+	second := sha256.Sum256(first[:])
+
+	return second[:]
+}
+
+// SignMessage 使用钱包私钥对消息签名，返回Base64编码的65字节可恢复签名
+// 签名首字节记录了恢复公钥所需的信息，验证方只需要地址、消息与签名
+func (w Wallet) SignMessage(message string) (string, error) {
+	if w.PrivateKey.D == nil {
+		return "", ErrWalletLocked
+	}
+	if w.PrivateKey.Curve != btcec.S256() {
+		return "", errors.New("message signing requires a secp256k1 key")
+	}
+
+	sig, err := ecdsa.SignCompact(toBTCECPrivateKey(&w.PrivateKey), MessageHash(message), true)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// VerifyMessage 从签名中恢复公钥，并检查其公钥哈希是否与地址一致
+// 地址或签名格式错误时返回错误，签名有效但不属于该地址时返回false
+func VerifyMessage(address, signature, message string) (bool, error) {
+	decoded, err := DecodeAddress(address, ActiveNetParams)
+	if err != nil {
+		return false, err
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, ErrMessageSignature
+	}
+
+	pubKey, compressed, err := ecdsa.RecoverCompact(sig, MessageHash(message))
+	if err != nil {
+		return false, ErrMessageSignature
+	}
+
+	// 地址只承诺公钥哈希，按地址类型还原出生成地址时使用的公钥格式
+	var serialized []byte
+	switch {
+	case decoded.Schnorr:
+		serialized = schnorr.SerializePubKey(pubKey)
+	case compressed:
+		serialized = pubKey.SerializeCompressed()
+	default:
+		serialized = pubKey.SerializeUncompressed()
+	}
+
+	return bytes.Equal(PublicKeyHash(serialized), decoded.PubKeyHash), nil
+}
+
+// writeVarString 写入带变长整数长度前缀的字符串
+func writeVarString(buf *bytes.Buffer, s string) {
+	n := uint64(len(s))
+	switch {
+	case n < 0xfd:
+		buf.WriteByte(byte(n))
+	case n <= 0xffff:
+		buf.Write([]byte{0xfd, byte(n), byte(n >> 8)})
+	case n <= 0xffffffff:
+		buf.Write([]byte{0xfe, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)})
+	default:
+		buf.WriteByte(0xff)
+		for i := 0; i < 8; i++ {
+			buf.WriteByte(byte(n >> (8 * i)))
+		}
+	}
+	buf.WriteString(s)
+}
@@ -0,0 +1,40 @@
+package wallet
+
+import (
+	"testing"
+)
+
+func TestSignVerifyMessage(t *testing.T) {
+	message := "I control this address"
+	for _, w := range []*Wallet{NewWallet(), NewSchnorrWallet()} {
+		address := string(w.GenerateAddress())
+		signature, err := w.SignMessage(message)
+		if err != nil {
+			t.Fatalf("SignMessage error: %v", err)
+		}
+
+		// Base58Check与Bech32形式的地址都可以验证
+		for _, addr := range []string{address, w.Bech32Address(ActiveNetParams)} {
+			if valid, err := VerifyMessage(addr, signature, message); !valid || err != nil {
+				t.Errorf("VerifyMessage(%s) error: 有效签名验证失败 %v", addr, err)
+			}
+		}
+
+		if valid, _ := VerifyMessage(address, signature, message+"!"); valid {
+			t.Error("VerifyMessage error: 消息被篡改后签名仍然有效")
+		}
+		if valid, _ := VerifyMessage(string(NewWallet().GenerateAddress()), signature, message); valid {
+			t.Error("VerifyMessage error: 其他地址验证通过")
+		}
+	}
+
+	if _, err := VerifyMessage(string(NewWallet().GenerateAddress()), "bm90IGEgc2lnbmF0dXJl", message); err != ErrMessageSignature {
+		t.Errorf("VerifyMessage error: 期望 ErrMessageSignature，实际 %v", err)
+	}
+
+	// 没有私钥（如锁定后的钱包）时不能签名
+	w := NewWallet()
+	if _, err := (Wallet{PublicKey: w.PublicKey}).SignMessage(message); err != ErrWalletLocked {
+		t.Errorf("SignMessage error: 没有私钥时期望 ErrWalletLocked，实际 %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 钱包文件格式版本，每次不兼容的结构调整时递增
+// 版本0为没有版本号的旧文件，加载时补齐地址元数据
+const WalletFileVersion = 1
+
+// AddressPurpose 地址用途
+type AddressPurpose string
+
+const (
+	PurposeReceive AddressPurpose = "receive" // 对外收款地址
+	PurposeChange  AddressPurpose = "change"  // 找零地址
+)
+
+// AddressMeta 钱包地址的元数据
+type AddressMeta struct {
+	Label   string         // 标签
+	Created int64          // 创建时间（Unix秒），旧钱包文件中的地址为0
+	Purpose AddressPurpose // 用途
+}
+
+// Contact 地址簿中的交易对手地址，钱包不持有它们的私钥
+type Contact struct {
+	Label   string // 标签
+	Created int64  // 加入地址簿的时间（Unix秒）
+}
+
+// AddressInfo 列出钱包地址时使用的汇总信息
+type AddressInfo struct {
+	Address   string
+	Label     string
+	Created   int64
+	Purpose   AddressPurpose
+	Path      string // 派生路径，非派生地址为空
+	WatchOnly bool
+}
+
+// AddressFilter 列出钱包地址时的过滤条件，零值表示不过滤
+type AddressFilter struct {
+	Label     string         // 标签包含该字符串（不区分大小写）
+	Purpose   AddressPurpose // 用途
+	WatchOnly *bool          // 是否为只读地址
+}
+
+// Match 判断地址是否满足过滤条件
+func (f AddressFilter) Match(info AddressInfo) bool {
+	if f.Label != "" && !strings.Contains(strings.ToLower(info.Label), strings.ToLower(f.Label)) {
+		return false
+	}
+	if f.Purpose != "" && info.Purpose != f.Purpose {
+		return false
+	}
+	if f.WatchOnly != nil && info.WatchOnly != *f.WatchOnly {
+		return false
+	}
+
+	return true
+}
+
+// ParseAddressPurpose 解析地址用途
+func ParseAddressPurpose(s string) (AddressPurpose, error) {
+	switch purpose := AddressPurpose(strings.ToLower(s)); purpose {
+	case PurposeReceive, PurposeChange:
+		return purpose, nil
+	default:
+		return "", fmt.Errorf("unknown address purpose %q", s)
+	}
+}
+
+// recordAddress 记录新加入钱包的地址的元数据，已有的元数据（如标签）保持不变
+func (ws *Wallets) recordAddress(address string, purpose AddressPurpose) {
+	if ws.Meta == nil {
+		ws.Meta = make(map[string]*AddressMeta)
+	}
+	if _, ok := ws.Meta[address]; ok {
+		return
+	}
+
+	ws.Meta[address] = &AddressMeta{"", time.Now().Unix(), purpose}
+}
+
+// GetMeta 获取地址的元数据，钱包中没有记录时返回零值
+func (ws *Wallets) GetMeta(address string) AddressMeta {
+	if meta, ok := ws.Meta[address]; ok {
+		return *meta
+	}
+
+	return AddressMeta{}
+}
+
+// SetLabel 设置钱包地址或地址簿地址的标签，空字符串清除标签
+func (ws *Wallets) SetLabel(address, label string) error {
+	if contact, ok := ws.AddressBook[address]; ok {
+		contact.Label = label
+		return nil
+	}
+	if _, ok := ws.Wallets[address]; !ok && !ws.IsWatchOnly(address) {
+		return fmt.Errorf("address %s is not in this wallet or its address book", address)
+	}
+
+	ws.recordAddress(address, PurposeReceive)
+	ws.Meta[address].Label = label
+
+	return nil
+}
+
+// ListAddresses 列出满足过滤条件的钱包地址（包括只读地址）
+// 按创建时间排序，时间相同时按地址排序，保证每次输出的顺序一致
+func (ws *Wallets) ListAddresses(filter AddressFilter) []AddressInfo {
+	var infos []AddressInfo
+	add := func(address string, watchOnly bool) {
+		meta := ws.GetMeta(address)
+		info := AddressInfo{address, meta.Label, meta.Created, meta.Purpose, ws.GetPath(address), watchOnly}
+		if info.Purpose == "" {
+			info.Purpose = PurposeReceive
+		}
+		if filter.Match(info) {
+			infos = append(infos, info)
+		}
+	}
+
+	for address := range ws.Wallets {
+		add(address, false)
+	}
+	for address := range ws.WatchOnly {
+		add(address, true)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Created != infos[j].Created {
+			return infos[i].Created < infos[j].Created
+		}
+		return infos[i].Address < infos[j].Address
+	})
+
+	return infos
+}
+
+// AddContact 向地址簿中添加交易对手地址，地址已存在时更新标签
+func (ws *Wallets) AddContact(address, label string) (string, error) {
+	normalized, err := NormalizeAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := ws.Wallets[normalized]; ok {
+		return "", fmt.Errorf("address %s belongs to this wallet, use setlabel instead", normalized)
+	}
+	if ws.AddressBook == nil {
+		ws.AddressBook = make(map[string]*Contact)
+	}
+
+	if contact, ok := ws.AddressBook[normalized]; ok {
+		contact.Label = label
+		return normalized, nil
+	}
+	ws.AddressBook[normalized] = &Contact{label, time.Now().Unix()}
+
+	return normalized, nil
+}
+
+// RemoveContact 从地址簿中删除地址
+func (ws *Wallets) RemoveContact(address string) error {
+	normalized, err := NormalizeAddress(address)
+	if err != nil {
+		return err
+	}
+	if _, ok := ws.AddressBook[normalized]; !ok {
+		return fmt.Errorf("address %s is not in the address book", normalized)
+	}
+
+	delete(ws.AddressBook, normalized)
+
+	return nil
+}
+
+// GetContacts 按标签排序列出地址簿，标签相同时按地址排序
+func (ws *Wallets) GetContacts() []string {
+	var addresses []string
+	for address := range ws.AddressBook {
+		addresses = append(addresses, address)
+	}
+
+	sort.Slice(addresses, func(i, j int) bool {
+		a, b := ws.AddressBook[addresses[i]], ws.AddressBook[addresses[j]]
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		return addresses[i] < addresses[j]
+	})
+
+	return addresses
+}
+
+// decodeWallets 解码钱包文件并升级到当前版本
+// 先按当前格式解码，失败时按基线版本的钱包文件格式解码，基线版本的文件版本号为0
+func decodeWallets(content []byte) (*Wallets, error) {
+	var wallets Wallets
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&wallets); err != nil {
+		legacy, legacyErr := decodeLegacyWallets(content)
+		if legacyErr != nil {
+			return nil, err
+		}
+		wallets = *legacy
+	}
+
+	// 旧版本的钱包文件升级到当前版本，无法识别的新版本文件拒绝加载
+	if err := wallets.migrate(); err != nil {
+		return nil, err
+	}
+
+	return &wallets, nil
+}
+
+// migrate 将旧版本的钱包文件升级到当前版本
+func (ws *Wallets) migrate() error {
+	if ws.Version > WalletFileVersion {
+		return fmt.Errorf("wallet file version %d is newer than the supported version %d", ws.Version, WalletFileVersion)
+	}
+
+	// 版本0没有地址元数据，用途根据派生路径推断，创建时间未知
+	if ws.Version < 1 {
+		if ws.Meta == nil {
+			ws.Meta = make(map[string]*AddressMeta)
+		}
+		for address := range ws.Wallets {
+			if _, ok := ws.Meta[address]; !ok {
+				ws.Meta[address] = &AddressMeta{"", 0, purposeFromPath(ws.GetPath(address))}
+			}
+		}
+		for address := range ws.WatchOnly {
+			if _, ok := ws.Meta[address]; !ok {
+				ws.Meta[address] = &AddressMeta{"", 0, PurposeReceive}
+			}
+		}
+	}
+
+	ws.Version = WalletFileVersion
+
+	return nil
+}
+
+// purposeFromPath 根据派生路径中的链编号推断地址用途
+func purposeFromPath(path string) AddressPurpose {
+	indexes, err := ParseDerivationPath(path)
+	if err == nil && len(indexes) >= 2 && indexes[len(indexes)-2] == InternalChain {
+		return PurposeChange
+	}
+
+	return PurposeReceive
+}
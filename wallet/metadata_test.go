@@ -0,0 +1,118 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestAddressMetadata(t *testing.T) {
+	nodeId := "metadata_test"
+	defer os.Remove(fmt.Sprintf(walletFile, nodeId))
+
+	wallets := Wallets{Wallets: make(map[string]*Wallet)}
+	receive, _ := wallets.NewReceiveAddress(false)
+	change, _ := wallets.NewChangeAddress(false)
+	watched := string(NewWallet().GenerateAddress())
+	wallets.ImportAddress(watched)
+
+	if err := wallets.SetLabel(receive, "Shop"); err != nil {
+		t.Fatalf("SetLabel error: %v", err)
+	}
+	if err := wallets.SetLabel(string(NewWallet().GenerateAddress()), "x"); err == nil {
+		t.Error("SetLabel error: 不属于钱包的地址设置标签成功")
+	}
+	contact, err := wallets.AddContact(NewWallet().Bech32Address(ActiveNetParams), "Alice")
+	if err != nil {
+		t.Fatalf("AddContact error: %v", err)
+	}
+	if _, err := wallets.AddContact(receive, "self"); err == nil {
+		t.Error("AddContact error: 钱包自己的地址被加入地址簿")
+	}
+	wallets.SaveFile(nodeId)
+
+	// 重新加载后元数据与地址簿保持不变
+	loaded, err := CreateWallets(nodeId)
+	if err != nil {
+		t.Fatalf("CreateWallets error: %v", err)
+	}
+	if meta := loaded.GetMeta(receive); meta.Label != "Shop" || meta.Purpose != PurposeReceive || meta.Created == 0 {
+		t.Errorf("GetMeta error: %+v", meta)
+	}
+	if loaded.GetMeta(change).Purpose != PurposeChange {
+		t.Errorf("GetMeta error: 找零地址用途为 %s", loaded.GetMeta(change).Purpose)
+	}
+	if contacts := loaded.GetContacts(); len(contacts) != 1 || contacts[0] != contact || loaded.AddressBook[contact].Label != "Alice" {
+		t.Errorf("GetContacts error: %v", contacts)
+	}
+
+	// 过滤条件
+	if infos := loaded.ListAddresses(AddressFilter{Label: "shop"}); len(infos) != 1 || infos[0].Address != receive {
+		t.Errorf("ListAddresses error: 按标签过滤得到 %v", infos)
+	}
+	if infos := loaded.ListAddresses(AddressFilter{Purpose: PurposeChange}); len(infos) != 1 || infos[0].Address != change {
+		t.Errorf("ListAddresses error: 按用途过滤得到 %v", infos)
+	}
+	watchOnly := true
+	if infos := loaded.ListAddresses(AddressFilter{WatchOnly: &watchOnly}); len(infos) != 1 || infos[0].Address != watched {
+		t.Errorf("ListAddresses error: 按只读过滤得到 %v", infos)
+	}
+
+	// 输出顺序稳定
+	first := loaded.ListAddresses(AddressFilter{})
+	for i := 0; i < 10; i++ {
+		again := loaded.ListAddresses(AddressFilter{})
+		if fmt.Sprint(first) != fmt.Sprint(again) {
+			t.Fatal("ListAddresses error: 输出顺序不稳定")
+		}
+	}
+}
+
+func TestWalletFileMigration(t *testing.T) {
+	nodeId := "migration_test"
+	defer os.Remove(fmt.Sprintf(walletFile, nodeId))
+
+	// 没有版本号与元数据的旧钱包文件
+	legacy := Wallets{Wallets: make(map[string]*Wallet)}
+	change, _ := legacy.NewChangeAddress(false)
+	legacy.Meta = nil
+	var content bytes.Buffer
+	gob.NewEncoder(&content).Encode(legacy)
+	ioutil.WriteFile(fmt.Sprintf(walletFile, nodeId), content.Bytes(), 0600)
+
+	loaded, err := CreateWallets(nodeId)
+	if err != nil {
+		t.Fatalf("CreateWallets error: %v", err)
+	}
+	if loaded.Version != WalletFileVersion || loaded.GetMeta(change).Purpose != PurposeChange {
+		t.Errorf("migrate error: 版本 %d，用途 %s", loaded.Version, loaded.GetMeta(change).Purpose)
+	}
+
+	// 基线版本写入的钱包文件：解码为当前结构后补齐元数据，保存后为当前版本
+	copyLegacyWalletFile(t, nodeId)
+	loaded, err = CreateWallets(nodeId)
+	if err != nil {
+		t.Fatalf("CreateWallets error: %v", err)
+	}
+	if loaded.Version != WalletFileVersion || loaded.GetMeta(legacyAddress).Purpose != PurposeReceive {
+		t.Errorf("migrate error: 基线钱包文件升级后版本 %d，元数据 %+v", loaded.Version, loaded.GetMeta(legacyAddress))
+	}
+	loaded.SaveFile(nodeId)
+	saved, _ := ioutil.ReadFile(fmt.Sprintf(walletFile, nodeId))
+	var current Wallets
+	if err := gob.NewDecoder(bytes.NewReader(saved)).Decode(&current); err != nil || current.Version != WalletFileVersion {
+		t.Errorf("SaveFile error: 升级后的钱包文件未以当前格式保存 %v", err)
+	}
+
+	// 更新版本的钱包文件拒绝加载
+	legacy.Version = WalletFileVersion + 1
+	content.Reset()
+	gob.NewEncoder(&content).Encode(legacy)
+	ioutil.WriteFile(fmt.Sprintf(walletFile, nodeId), content.Bytes(), 0600)
+	if _, err := CreateWallets(nodeId); err == nil {
+		t.Error("CreateWallets error: 更新版本的钱包文件加载成功")
+	}
+}
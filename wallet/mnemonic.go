@@ -0,0 +1,110 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"github.com/tyler-smith/go-bip39"
+	"strings"
+)
+
+// 恢复钱包时，每条派生链上连续未使用地址的默认上限
+const DefaultGapLimit = 20
+
+// NewMnemonic 生成BIP39助记词，words为12或24
+func NewMnemonic(words int) (string, error) {
+	var bitSize int
+	switch words {
+	case 12:
+		bitSize = 128
+	case 24:
+		bitSize = 256
+	default:
+		return "", fmt.Errorf("mnemonic must have 12 or 24 words, got %d", words)
+	}
+
+	entropy, err := bip39.NewEntropy(bitSize)
+	if err != nil {
+		return "", err
+	}
+
+	return bip39.NewMnemonic(entropy)
+}
+
+// MnemonicToSeed 校验助记词并结合可选的密码短语生成64字节种子
+// 相同助记词配合不同的密码短语会得到完全不同的钱包
+func MnemonicToSeed(mnemonic, passphrase string) ([]byte, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mnemonic: %w", err)
+	}
+
+	return seed, nil
+}
+
+// Rescan 扫描所有派生链，将已使用的地址及其之前的地址加入钱包，并推进各链的序号
+// used判断公钥哈希是否在链上被使用过，某条链连续gapLimit个地址未被使用时停止扫描
+func (ws *Wallets) Rescan(used func(pubKeyHash []byte) bool, gapLimit int) ([]string, error) {
+	if ws.HD == nil {
+		return nil, errors.New("wallet has no HD seed")
+	}
+	if gapLimit <= 0 {
+		gapLimit = DefaultGapLimit
+	}
+
+	var found []string
+	for _, purpose := range []uint32{PurposeBIP44, PurposeBIP86} {
+		for _, change := range []uint32{ExternalChain, InternalChain} {
+			chainPath := ws.HD.ChainPath(purpose, change)
+			var derived []*Wallet
+			var paths []string
+			next := 0
+
+			for index, gap := uint32(0), 0; gap < gapLimit && index < HardenedKeyStart; index++ {
+				path := fmt.Sprintf("%s/%d", chainPath, index)
+				w, err := ws.HD.DeriveWallet(path, purpose == PurposeBIP86)
+				if errors.Is(err, ErrInvalidChild) {
+					continue
+				}
+				if err != nil {
+					return nil, err
+				}
+				derived, paths = append(derived, w), append(paths, path)
+
+				if used(PublicKeyHash(w.PublicKey)) {
+					found = append(found, string(w.GenerateAddress()))
+					next, gap = len(derived), 0
+					continue
+				}
+				gap++
+			}
+
+			// 只保留最后一个已使用地址及其之前的地址，之后的地址留待重新分配
+			for i, w := range derived[:next] {
+				address := string(w.GenerateAddress())
+				ws.Wallets[address] = w
+				ws.HD.Paths[address] = paths[i]
+				ws.recordAddress(address, purposeFromPath(paths[i]))
+			}
+			if next > 0 {
+				lastIndex, _ := pathIndex(paths[next-1])
+				if lastIndex+1 > ws.HD.NextIndex[chainPath] {
+					ws.HD.NextIndex[chainPath] = lastIndex + 1
+				}
+			}
+		}
+	}
+
+	return found, nil
+}
+
+// pathIndex 派生路径最后一层的序号
+func pathIndex(path string) (uint32, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil || len(indexes) == 0 {
+		return 0, errors.New("derivation path has no index")
+	}
+
+	return indexes[len(indexes)-1], nil
+}
@@ -0,0 +1,67 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMnemonicToSeed(t *testing.T) {
+	// BIP39 测试向量（密码短语 TREZOR）
+	mnemonic := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+	seed, err := MnemonicToSeed(mnemonic, "TREZOR")
+	if err != nil {
+		t.Fatalf("MnemonicToSeed error: %v", err)
+	}
+	want := "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+	if hex.EncodeToString(seed) != want {
+		t.Errorf("MnemonicToSeed error: seed = %x", seed)
+	}
+
+	// 校验和错误的助记词必须被拒绝
+	if _, err := MnemonicToSeed(strings.Replace(mnemonic, "about", "abandon", 1), ""); err == nil {
+		t.Error("MnemonicToSeed error: 校验和错误的助记词未被拒绝")
+	}
+
+	for _, words := range []int{12, 24} {
+		m, err := NewMnemonic(words)
+		if err != nil || len(strings.Fields(m)) != words {
+			t.Errorf("NewMnemonic(%d) error: %v", words, err)
+		}
+	}
+}
+
+func TestRescanGapLimit(t *testing.T) {
+	seed, _ := MnemonicToSeed("abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", "")
+
+	// 原钱包使用了第0、3个收款地址和第0个找零地址
+	original := Wallets{Wallets: make(map[string]*Wallet)}
+	original.InitHD(seed)
+	used := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		address, _ := original.NewReceiveAddress(false)
+		if i == 0 || i == 3 {
+			used[hex.EncodeToString(PublicKeyHash(original.Wallets[address].PublicKey))] = true
+		}
+	}
+	change, _ := original.NewChangeAddress(false)
+	used[hex.EncodeToString(PublicKeyHash(original.Wallets[change].PublicKey))] = true
+
+	restored := Wallets{Wallets: make(map[string]*Wallet)}
+	restored.InitHD(seed)
+	found, err := restored.Rescan(func(pubKeyHash []byte) bool {
+		return used[hex.EncodeToString(pubKeyHash)]
+	}, 5)
+	if err != nil {
+		t.Fatalf("Rescan error: %v", err)
+	}
+	if len(found) != 3 || len(restored.Wallets) != 5 {
+		t.Errorf("Rescan error: 找到 %d 个已使用地址，钱包中有 %d 个地址", len(found), len(restored.Wallets))
+	}
+
+	// 恢复后继续派生的地址与原钱包一致
+	next, _ := restored.NewReceiveAddress(false)
+	if want, _ := original.NewReceiveAddress(false); next != want {
+		t.Errorf("Rescan error: 恢复后的下一个地址为 %s，期望 %s", next, want)
+	}
+}
@@ -0,0 +1,346 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr/musig2"
+	"log"
+)
+
+// Schnorr地址的版本号，对应的输出需要使用BIP340 Schnorr签名花费
+const SchnorrVersion = byte(0x01)
+
+// x-only公钥与Schnorr签名的长度
+const (
+	SchnorrPubKeyLen    = schnorr.PubKeyBytesLen
+	SchnorrSignatureLen = schnorr.SignatureSize
+)
+
+// NewSchnorrWallet 创建使用Schnorr签名的钱包，公钥为32字节的x-only公钥
+func NewSchnorrWallet() *Wallet {
+	privateKey, err := btcec.NewPrivateKey()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	wallet := Wallet{*privateKey.ToECDSA(), schnorr.SerializePubKey(privateKey.PubKey()), nil}
+
+	return &wallet
+}
+
+// IsSchnorr 钱包是否使用Schnorr签名
+func (w Wallet) IsSchnorr() bool {
+	return len(w.PublicKey) == SchnorrPubKeyLen
+}
+
+// SignSchnorr 使用BIP340 Schnorr算法对32字节摘要进行签名
+func SignSchnorr(privKey *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+	if privKey.Curve != btcec.S256() {
+		return nil, errors.New("schnorr signatures require a secp256k1 key")
+	}
+
+	sig, err := schnorr.Sign(toBTCECPrivateKey(privKey), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return sig.Serialize(), nil
+}
+
+// VerifySchnorr 验证BIP340 Schnorr签名，pubKey为x-only公钥
+func VerifySchnorr(pubKey, hash, sig []byte) bool {
+	key, err := schnorr.ParsePubKey(pubKey)
+	if err != nil {
+		return false
+	}
+
+	signature, err := schnorr.ParseSignature(sig)
+	if err != nil {
+		return false
+	}
+
+	return signature.Verify(hash, key)
+}
+
+// SchnorrBatch Schnorr签名批量验证集合
+type SchnorrBatch struct {
+	items []schnorrItem
+}
+
+type schnorrItem struct {
+	pubKey []byte
+	hash   []byte
+	sig    []byte
+}
+
+// Add 向批量验证集合中加入一个待验证的签名
+func (b *SchnorrBatch) Add(pubKey, hash, sig []byte) {
+	b.items = append(b.items, schnorrItem{pubKey, hash, sig})
+}
+
+// Len 批量验证集合中的签名数量
+func (b *SchnorrBatch) Len() int {
+	return len(b.items)
+}
+
+// Verify 批量验证集合中的所有签名
+// 随机系数a_i下验证 (Σa_i·s_i)·G == Σa_i·R_i + Σ(a_i·e_i)·P_i，右侧使用多标量乘法一次完成
+func (b *SchnorrBatch) Verify() bool {
+	switch len(b.items) {
+	case 0:
+		return true
+	case 1:
+		item := b.items[0]
+		return VerifySchnorr(item.pubKey, item.hash, item.sig)
+	}
+
+	var sSum btcec.ModNScalar
+	scalars := make([]btcec.ModNScalar, 0, 2*len(b.items))
+	points := make([]btcec.JacobianPoint, 0, 2*len(b.items))
+
+	for i, item := range b.items {
+		if len(item.hash) != sha256.Size || len(item.sig) != SchnorrSignatureLen {
+			return false
+		}
+
+		// P = lift_x(pk)，R = lift_x(r)，r >= p 时解析失败
+		pubKey, err := schnorr.ParsePubKey(item.pubKey)
+		if err != nil {
+			return false
+		}
+		R, err := schnorr.ParsePubKey(item.sig[:32])
+		if err != nil {
+			return false
+		}
+
+		var s btcec.ModNScalar
+		if overflow := s.SetByteSlice(item.sig[32:]); overflow {
+			return false
+		}
+
+		// e = int(tagged_hash("BIP0340/challenge", r || P || m)) mod n
+		var e btcec.ModNScalar
+		e.SetByteSlice(taggedHash("BIP0340/challenge", item.sig[:32], item.pubKey, item.hash))
+
+		// 第一个签名的系数为1，其余为128位随机数，防止构造相互抵消的无效签名
+		var a btcec.ModNScalar
+		if i == 0 {
+			a.SetInt(1)
+		} else {
+			for a.IsZero() {
+				random := make([]byte, 16)
+				if _, err := rand.Read(random); err != nil {
+					return false
+				}
+				a.SetByteSlice(random)
+			}
+		}
+
+		var as, ae btcec.ModNScalar
+		sSum.Add(as.Mul2(&a, &s))
+		ae.Mul2(&a, &e)
+
+		var RJ, PJ btcec.JacobianPoint
+		R.AsJacobian(&RJ)
+		pubKey.AsJacobian(&PJ)
+
+		scalars = append(scalars, a, ae)
+		points = append(points, RJ, PJ)
+	}
+
+	var lhs btcec.JacobianPoint
+	btcec.ScalarBaseMultNonConst(&sSum, &lhs)
+	rhs := multiScalarMult(scalars, points)
+
+	lhs.ToAffine()
+	rhs.ToAffine()
+
+	return lhs.X.Equals(&rhs.X) && lhs.Y.Equals(&rhs.Y)
+}
+
+// multiScalarMult 使用Pippenger分桶算法计算 Σk_i·P_i
+// 所有点共享同一条倍点链，签名越多，平均到每个签名的开销越小
+func multiScalarMult(scalars []btcec.ModNScalar, points []btcec.JacobianPoint) btcec.JacobianPoint {
+	// 窗口宽度约为 log2(n)-2
+	c := 2
+	for (1<<(c+2)) < len(points) && c < 12 {
+		c++
+	}
+
+	scalarBytes := make([][32]byte, len(scalars))
+	for i := range scalars {
+		scalarBytes[i] = scalars[i].Bytes()
+	}
+
+	var result, tmp btcec.JacobianPoint
+	buckets := make([]btcec.JacobianPoint, 1<<c)
+
+	for w := (256+c-1)/c - 1; w >= 0; w-- {
+		for i := 0; i < c; i++ {
+			btcec.DoubleNonConst(&result, &tmp)
+			result.Set(&tmp)
+		}
+
+		// 按当前窗口的取值将点放入对应的桶中
+		for d := range buckets {
+			buckets[d] = btcec.JacobianPoint{}
+		}
+		for i := range points {
+			if d := windowDigit(&scalarBytes[i], w, c); d != 0 {
+				btcec.AddNonConst(&buckets[d], &points[i], &tmp)
+				buckets[d].Set(&tmp)
+			}
+		}
+
+		// Σd·bucket[d] 通过前缀和计算
+		var running, sum btcec.JacobianPoint
+		for d := len(buckets) - 1; d >= 1; d-- {
+			btcec.AddNonConst(&running, &buckets[d], &tmp)
+			running.Set(&tmp)
+			btcec.AddNonConst(&sum, &running, &tmp)
+			sum.Set(&tmp)
+		}
+
+		btcec.AddNonConst(&result, &sum, &tmp)
+		result.Set(&tmp)
+	}
+
+	return result
+}
+
+// windowDigit 取出大端序标量中第w个宽度为c的窗口的值
+func windowDigit(scalar *[32]byte, w, c int) int {
+	digit := 0
+	for t := 0; t < c; t++ {
+		bit := w*c + t
+		if bit >= 256 {
+			break
+		}
+		if scalar[31-bit/8]>>(bit%8)&1 == 1 {
+			digit |= 1 << t
+		}
+	}
+
+	return digit
+}
+
+// taggedHash BIP340带标签哈希：SHA256(SHA256(tag) || SHA256(tag) || data)
+func taggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	hasher := sha256.New()
+	hasher.Write(tagHash[:])
+	hasher.Write(tagHash[:])
+	for _, d := range data {
+		hasher.Write(d)
+	}
+
+	return hasher.Sum(nil)
+}
+
+// AggregatePublicKeys MuSig2密钥聚合，pubKeys为各参与方的33字节压缩公钥，返回聚合后的x-only公钥
+func AggregatePublicKeys(pubKeys [][]byte) ([]byte, error) {
+	keys, err := parseMuSigKeys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	aggKey, _, _, err := musig2.AggregateKeys(keys, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return schnorr.SerializePubKey(aggKey.FinalKey), nil
+}
+
+// MuSigAddress 多方聚合公钥对应的Schnorr地址，链上与单密钥地址没有区别
+func MuSigAddress(pubKeys [][]byte) (string, error) {
+	aggKey, err := AggregatePublicKeys(pubKeys)
+	if err != nil {
+		return "", err
+	}
+
+	return string(Wallet{PublicKey: aggKey}.GenerateAddress()), nil
+}
+
+// MuSigSign 由全部参与方共同生成聚合公钥下的Schnorr签名
+// 各方依次完成交换公开随机数、生成部分签名两轮交互，最后合并为一个普通的BIP340签名
+func MuSigSign(signers []*Wallet, pubKeys [][]byte, hash []byte) ([]byte, error) {
+	if len(signers) != len(pubKeys) {
+		return nil, errors.New("every key holder must sign")
+	}
+	if len(hash) != sha256.Size {
+		return nil, errors.New("hash must be 32 bytes")
+	}
+
+	keys, err := parseMuSigKeys(pubKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// 第一轮：各方生成随机数并交换公开部分
+	nonces := make([]*musig2.Nonces, len(signers))
+	pubNonces := make([][musig2.PubNonceSize]byte, len(signers))
+	for i, signer := range signers {
+		privKey := toBTCECPrivateKey(&signer.PrivateKey)
+		nonces[i], err = musig2.GenNonces(musig2.WithPublicKey(privKey.PubKey()))
+		if err != nil {
+			return nil, err
+		}
+		pubNonces[i] = nonces[i].PubNonce
+	}
+
+	combinedNonce, err := musig2.AggregateNonces(pubNonces)
+	if err != nil {
+		return nil, err
+	}
+
+	// 第二轮：各方生成部分签名
+	var msg [32]byte
+	copy(msg[:], hash)
+
+	partialSigs := make([]*musig2.PartialSignature, len(signers))
+	for i, signer := range signers {
+		privKey := toBTCECPrivateKey(&signer.PrivateKey)
+		partialSigs[i], err = musig2.Sign(nonces[i].SecNonce, privKey, combinedNonce, keys, msg, musig2.WithSortedKeys())
+		if err != nil {
+			return nil, fmt.Errorf("signer %d: %w", i, err)
+		}
+	}
+
+	sig := musig2.CombineSigs(partialSigs[0].R, partialSigs)
+
+	return sig.Serialize(), nil
+}
+
+// parseMuSigKeys 解析参与密钥聚合的压缩公钥
+func parseMuSigKeys(pubKeys [][]byte) ([]*btcec.PublicKey, error) {
+	if len(pubKeys) == 0 {
+		return nil, errors.New("no public keys to aggregate")
+	}
+
+	keys := make([]*btcec.PublicKey, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		key, err := btcec.ParsePubKey(pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("public key %d: %w", i, err)
+		}
+		keys[i] = key
+	}
+
+	return keys, nil
+}
+
+// toBTCECPrivateKey 将secp256k1私钥转换为btcec私钥
+func toBTCECPrivateKey(privKey *ecdsa.PrivateKey) *btcec.PrivateKey {
+	var key [32]byte
+	privKey.D.FillBytes(key[:])
+	priv, _ := btcec.PrivKeyFromBytes(key[:])
+
+	return priv
+}
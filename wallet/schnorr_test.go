@@ -0,0 +1,103 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+// newSchnorrBatch 构造n个不同密钥、不同消息的有效签名
+func newSchnorrBatch(n int) *SchnorrBatch {
+	batch := &SchnorrBatch{}
+	for i := 0; i < n; i++ {
+		w := NewSchnorrWallet()
+		hash := sha256.Sum256([]byte(fmt.Sprintf("message %d", i)))
+		sig, err := SignSchnorr(&w.PrivateKey, hash[:])
+		if err != nil {
+			panic(err)
+		}
+		batch.Add(w.PublicKey, hash[:], sig)
+	}
+
+	return batch
+}
+
+func TestSchnorrBatchVerify(t *testing.T) {
+	batch := newSchnorrBatch(20)
+	for _, item := range batch.items {
+		if !VerifySchnorr(item.pubKey, item.hash, item.sig) {
+			t.Fatal("VerifySchnorr error: 单个签名验证失败")
+		}
+	}
+	if !batch.Verify() {
+		t.Fatal("SchnorrBatch error: 批量验证失败")
+	}
+
+	// 任意一个签名无效，整批验证失败
+	bad := batch.items[7].sig
+	batch.items[7].sig = append([]byte{}, bad...)
+	batch.items[7].sig[63] ^= 0x01
+	if batch.Verify() {
+		t.Error("SchnorrBatch error: 包含无效签名的批次验证通过")
+	}
+	batch.items[7].sig = bad
+	batch.items[3].hash = batch.items[4].hash
+	if batch.Verify() {
+		t.Error("SchnorrBatch error: 签名与消息不匹配的批次验证通过")
+	}
+}
+
+func TestMuSigSign(t *testing.T) {
+	signers := []*Wallet{NewWallet(), NewWallet(), NewWallet()}
+	pubKeys := make([][]byte, len(signers))
+	for i, signer := range signers {
+		pubKeys[i] = signer.PublicKey
+	}
+
+	aggKey, err := AggregatePublicKeys(pubKeys)
+	if err != nil {
+		t.Fatalf("AggregatePublicKeys error: %v", err)
+	}
+	if len(aggKey) != SchnorrPubKeyLen {
+		t.Fatalf("AggregatePublicKeys error: 聚合公钥长度为 %d", len(aggKey))
+	}
+
+	// 聚合公钥与参与方的顺序无关
+	reversed := [][]byte{pubKeys[2], pubKeys[1], pubKeys[0]}
+	if again, _ := AggregatePublicKeys(reversed); string(again) != string(aggKey) {
+		t.Error("AggregatePublicKeys error: 调换顺序后聚合公钥发生变化")
+	}
+
+	hash := sha256.Sum256([]byte("musig"))
+	sig, err := MuSigSign(signers, pubKeys, hash[:])
+	if err != nil {
+		t.Fatalf("MuSigSign error: %v", err)
+	}
+	if !VerifySchnorr(aggKey, hash[:], sig) {
+		t.Error("MuSigSign error: 聚合签名无法通过普通Schnorr验证")
+	}
+}
+
+func benchmarkSchnorr(b *testing.B, n int, batched bool) {
+	batch := newSchnorrBatch(n)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if batched {
+			if !batch.Verify() {
+				b.Fatal("batch verify failed")
+			}
+			continue
+		}
+		for _, item := range batch.items {
+			if !VerifySchnorr(item.pubKey, item.hash, item.sig) {
+				b.Fatal("verify failed")
+			}
+		}
+	}
+}
+
+func BenchmarkSchnorrVerify100(b *testing.B)       { benchmarkSchnorr(b, 100, false) }
+func BenchmarkSchnorrBatchVerify100(b *testing.B)  { benchmarkSchnorr(b, 100, true) }
+func BenchmarkSchnorrVerify1000(b *testing.B)      { benchmarkSchnorr(b, 1000, false) }
+func BenchmarkSchnorrBatchVerify1000(b *testing.B) { benchmarkSchnorr(b, 1000, true) }
@@ -0,0 +1,224 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"github.com/btcsuite/btcd/btcec/v2"
+	btcecdsa "github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"math/big"
+)
+
+// Sign 对摘要进行ECDSA签名，签名编码为定长的 r||s（各自补齐到曲线阶的字节长度），并规范化为低S值
+// secp256k1私钥使用btcec的常数时间实现（RFC 6979确定性随机数）；旧的P256私钥使用signLegacy
+func Sign(privKey *ecdsa.PrivateKey, hash []byte) ([]byte, error) {
+	N := privKey.Curve.Params().N
+	if privKey.D == nil || privKey.D.Sign() <= 0 || privKey.D.Cmp(N) >= 0 {
+		return nil, errors.New("invalid private key")
+	}
+	if privKey.Curve != btcec.S256() {
+		return signLegacy(privKey, hash), nil
+	}
+
+	// 紧凑签名为 恢复标识||r||s，btcec生成的S值已是低S值
+	sig, err := btcecdsa.SignCompact(toBTCECPrivateKey(privKey), hash, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return sig[1:], nil
+}
+
+// VerifySignature 验证定长编码的ECDSA签名，拒绝高S值的签名
+func VerifySignature(pubKey *ecdsa.PublicKey, hash, sig []byte) bool {
+	if pubKey.X == nil || pubKey.Y == nil || !pubKey.Curve.IsOnCurve(pubKey.X, pubKey.Y) {
+		return false
+	}
+	if pubKey.Curve != btcec.S256() {
+		return verifyLegacy(pubKey, hash, sig)
+	}
+
+	var r, s btcec.ModNScalar
+	if len(sig) != 64 || r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:]) {
+		return false
+	}
+	if r.IsZero() || s.IsZero() || s.IsOverHalfOrder() {
+		return false
+	}
+
+	var x, y btcec.FieldVal
+	x.SetByteSlice(pubKey.X.Bytes())
+	y.SetByteSlice(pubKey.Y.Bytes())
+
+	return btcecdsa.NewSignature(&r, &s).Verify(hash, btcec.NewPublicKey(&x, &y))
+}
+
+// signLegacy 旧的P256私钥的签名，使用RFC 6979确定性随机数
+// 基于math/big实现，不是常数时间的，只为兼容旧钱包保留，新地址都使用secp256k1
+func signLegacy(privKey *ecdsa.PrivateKey, hash []byte) []byte {
+	curve := privKey.Curve
+	N := curve.Params().N
+
+	e := hashToInt(hash, N)
+	nextNonce := newNonceRFC6979(N, privKey.D, hash)
+
+	for {
+		k := nextNonce()
+
+		// r = (k·G).x mod N
+		kx, _ := curve.ScalarBaseMult(k.Bytes())
+		r := new(big.Int).Mod(kx, N)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		// s = k^-1 · (e + r·d) mod N
+		s := new(big.Int).Mul(r, privKey.D)
+		s.Add(s, e)
+		s.Mul(s, new(big.Int).ModInverse(k, N))
+		s.Mod(s, N)
+		if s.Sign() == 0 {
+			continue
+		}
+
+		// (r, s) 与 (r, N-s) 同样有效，统一取较小的S值以消除签名的延展性
+		if s.Cmp(halfOrder(N)) > 0 {
+			s.Sub(N, s)
+		}
+
+		return encodeSignature(r, s, N)
+	}
+}
+
+// verifyLegacy 验证旧的P256公钥的签名
+func verifyLegacy(pubKey *ecdsa.PublicKey, hash, sig []byte) bool {
+	curve := pubKey.Curve
+	N := curve.Params().N
+
+	r, s, ok := decodeSignature(sig, N)
+	if !ok || s.Cmp(halfOrder(N)) > 0 {
+		return false
+	}
+
+	// u1 = e·s^-1, u2 = r·s^-1, 验证 (u1·G + u2·Q).x mod N == r
+	e := hashToInt(hash, N)
+	w := new(big.Int).ModInverse(s, N)
+	u1 := e.Mul(e, w)
+	u1.Mod(u1, N)
+	u2 := w.Mul(r, w)
+	u2.Mod(u2, N)
+
+	x1, y1 := curve.ScalarBaseMult(u1.Bytes())
+	x2, y2 := curve.ScalarMult(pubKey.X, pubKey.Y, u2.Bytes())
+	x, y := curve.Add(x1, y1, x2, y2)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return false
+	}
+
+	return x.Mod(x, N).Cmp(r) == 0
+}
+
+// encodeSignature 将r、s补齐为定长字节后拼接
+func encodeSignature(r, s, N *big.Int) []byte {
+	size := orderLen(N)
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+
+	return sig
+}
+
+// decodeSignature 解析定长编码的签名，r、s必须位于[1, N-1]区间
+func decodeSignature(sig []byte, N *big.Int) (*big.Int, *big.Int, bool) {
+	size := orderLen(N)
+	if len(sig) != 2*size {
+		return nil, nil, false
+	}
+
+	r := new(big.Int).SetBytes(sig[:size])
+	s := new(big.Int).SetBytes(sig[size:])
+	if r.Sign() == 0 || s.Sign() == 0 || r.Cmp(N) >= 0 || s.Cmp(N) >= 0 {
+		return nil, nil, false
+	}
+
+	return r, s, true
+}
+
+// newNonceRFC6979 按RFC 6979（HMAC-SHA256）生成确定性随机数序列
+// 相同的私钥与摘要总是得到相同的随机数，不再依赖系统随机源
+func newNonceRFC6979(N, x *big.Int, hash []byte) func() *big.Int {
+	size := orderLen(N)
+
+	// bits2octets(h) = int2octets(bits2int(h) mod N)
+	h := hashToInt(hash, N)
+	h.Mod(h, N)
+
+	key := make([]byte, 2*size)
+	x.FillBytes(key[:size])
+	h.FillBytes(key[size:])
+
+	mac := func(k []byte, data ...[]byte) []byte {
+		m := hmac.New(sha256.New, k)
+		for _, d := range data {
+			m.Write(d)
+		}
+		return m.Sum(nil)
+	}
+
+	V := make([]byte, sha256.Size)
+	K := make([]byte, sha256.Size)
+	for i := range V {
+		V[i] = 0x01
+	}
+
+	K = mac(K, V, []byte{0x00}, key)
+	V = mac(K, V)
+	K = mac(K, V, []byte{0x01}, key)
+	V = mac(K, V)
+
+	return func() *big.Int {
+		for {
+			var T []byte
+			for len(T) < size {
+				V = mac(K, V)
+				T = append(T, V...)
+			}
+
+			k := hashToInt(T, N)
+
+			// 为下一次调用（或k不合法时）更新内部状态
+			K = mac(K, V, []byte{0x00})
+			V = mac(K, V)
+
+			if k.Sign() > 0 && k.Cmp(N) < 0 {
+				return k
+			}
+		}
+	}
+}
+
+// hashToInt 将摘要截断为不超过曲线阶的比特长度后转换为整数（RFC 6979 bits2int）
+func hashToInt(hash []byte, N *big.Int) *big.Int {
+	orderBits := N.BitLen()
+	if len(hash) > orderLen(N) {
+		hash = hash[:orderLen(N)]
+	}
+
+	ret := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - orderBits; excess > 0 {
+		ret.Rsh(ret, uint(excess))
+	}
+
+	return ret
+}
+
+// orderLen 曲线阶的字节长度
+func orderLen(N *big.Int) int {
+	return (N.BitLen() + 7) / 8
+}
+
+// halfOrder 曲线阶的一半，用于低S值判断
+func halfOrder(N *big.Int) *big.Int {
+	return new(big.Int).Rsh(N, 1)
+}
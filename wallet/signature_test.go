@@ -0,0 +1,100 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"github.com/btcsuite/btcd/btcec/v2"
+	btcecdsa "github.com/btcsuite/btcd/btcec/v2/ecdsa"
+	"math/big"
+	"testing"
+)
+
+func hexInt(s string) *big.Int {
+	n, _ := new(big.Int).SetString(s, 16)
+	return n
+}
+
+func TestSignRFC6979(t *testing.T) {
+	// RFC 6979 A.2.5 测试向量：P-256、SHA-256、消息 "sample"
+	curve := elliptic.P256()
+	priv := &ecdsa.PrivateKey{D: hexInt("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721")}
+	priv.PublicKey.Curve = curve
+	priv.PublicKey.X, priv.PublicKey.Y = curve.ScalarBaseMult(priv.D.Bytes())
+	hash := sha256.Sum256([]byte("sample"))
+
+	k := newNonceRFC6979(curve.Params().N, priv.D, hash[:])()
+	if k.Cmp(hexInt("A6E3C57DD01ABE90086538398355DD4C3B17AA873382B0F24D6129493D8AAD60")) != 0 {
+		t.Fatalf("newNonceRFC6979 error: k = %X", k)
+	}
+
+	sig, err := Sign(priv, hash[:])
+	if err != nil {
+		t.Fatalf("Sign error: %v", err)
+	}
+	if len(sig) != 64 {
+		t.Fatalf("Sign error: 签名长度为 %d，期望 64", len(sig))
+	}
+
+	// 向量中的s为高S值，签名时被规范化为 N-s
+	N := curve.Params().N
+	wantR := hexInt("EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716")
+	wantS := new(big.Int).Sub(N, hexInt("F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"))
+	if new(big.Int).SetBytes(sig[:32]).Cmp(wantR) != 0 || new(big.Int).SetBytes(sig[32:]).Cmp(wantS) != 0 {
+		t.Errorf("Sign error: 签名 %X 与测试向量不一致", sig)
+	}
+
+	// 确定性签名：重复签名结果一致
+	again, _ := Sign(priv, hash[:])
+	if !bytes.Equal(sig, again) {
+		t.Error("Sign error: 相同输入得到了不同的签名")
+	}
+
+	if !VerifySignature(&priv.PublicKey, hash[:], sig) {
+		t.Error("VerifySignature error: 签名验证失败")
+	}
+
+	// 高S值的等价签名必须被拒绝
+	highS := make([]byte, 64)
+	copy(highS, sig[:32])
+	new(big.Int).Sub(N, wantS).FillBytes(highS[32:])
+	if VerifySignature(&priv.PublicKey, hash[:], highS) {
+		t.Error("VerifySignature error: 高S值签名未被拒绝")
+	}
+}
+
+func TestSignSecp256k1(t *testing.T) {
+	w := NewWallet()
+	hash := sha256.Sum256([]byte("sample"))
+
+	sig, err := Sign(&w.PrivateKey, hash[:])
+	if err != nil || len(sig) != 64 {
+		t.Fatalf("Sign error: 签名长度 %d %v", len(sig), err)
+	}
+
+	// 与btcec的RFC 6979签名一致
+	var r, s btcec.ModNScalar
+	r.SetByteSlice(sig[:32])
+	s.SetByteSlice(sig[32:])
+	if want := btcecdsa.Sign(toBTCECPrivateKey(&w.PrivateKey), hash[:]); !btcecdsa.NewSignature(&r, &s).IsEqual(want) {
+		t.Error("Sign error: 签名与btcec的确定性签名不一致")
+	}
+
+	pubKey, _ := ParsePublicKey(w.PublicKey)
+	if !VerifySignature(pubKey, hash[:], sig) {
+		t.Error("VerifySignature error: 签名验证失败")
+	}
+	other := sha256.Sum256([]byte("other"))
+	if VerifySignature(pubKey, other[:], sig) {
+		t.Error("VerifySignature error: 其他摘要的签名验证通过")
+	}
+
+	// 高S值的等价签名必须被拒绝
+	highS := make([]byte, 64)
+	copy(highS, sig[:32])
+	s.Negate().PutBytesUnchecked(highS[32:])
+	if VerifySignature(pubKey, hash[:], highS) {
+		t.Error("VerifySignature error: 高S值签名未被拒绝")
+	}
+}
@@ -0,0 +1,180 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// TxStatus 钱包交易的状态
+type TxStatus string
+
+const (
+	TxPending    TxStatus = "pending"    // 已广播，尚未打包
+	TxConfirmed  TxStatus = "confirmed"  // 已打包进主链
+	TxConflicted TxStatus = "conflicted" // 花费的输出已被主链中的另一笔交易花费
+	TxAbandoned  TxStatus = "abandoned"  // 用户放弃的未确认交易，不再计入余额
+	TxReplaced   TxStatus = "replaced"   // 已被手续费更高的交易替换
+)
+
+// WalletTx 钱包记录的一笔与自身地址相关的交易
+type WalletTx struct {
+	TxID         string         // 交易ID（十六进制）
+	Status       TxStatus       // 状态
+	BlockHash    string         // 所在区块哈希（十六进制），未确认时为空
+	Height       int            // 所在区块高度，未确认时为-1
+	Timestamp    int64          // 所在区块时间，未确认时为钱包首次记录的时间
+	Received     int            // 支付给钱包地址的金额
+	Sent         int            // 花费钱包输出的金额
+	Fee          int            // 手续费，只有全部输入都属于钱包时才能计算，否则为0
+	Coinbase     bool           // 是否为币基交易
+	Credits      map[int]Credit // 支付给钱包地址的输出，按输出序号索引
+	Spends       []string       // 花费的输出，格式为 txid:vout
+	Addresses    []string       // 涉及的钱包地址
+	ConflictedBy string         // 与之冲突并已确认的交易ID
+	ReplacedBy   string         // 替换该交易的交易ID
+	Raw          []byte         // 钱包广播的交易的序列化数据，用于提高手续费后替换
+}
+
+// Credit 支付给钱包地址的一个输出
+type Credit struct {
+	Value   int
+	Address string
+}
+
+// Net 交易对钱包余额的净影响
+func (tx *WalletTx) Net() int {
+	return tx.Received - tx.Sent
+}
+
+// Category 交易类别：挖矿所得、转出或转入
+func (tx *WalletTx) Category() string {
+	switch {
+	case tx.Coinbase:
+		return "generate"
+	case tx.Sent > 0:
+		return "send"
+	default:
+		return "receive"
+	}
+}
+
+// Confirmations 确认数，未确认、冲突或已放弃的交易为0
+func (tx *WalletTx) Confirmations(bestHeight int) int {
+	if tx.Status != TxConfirmed {
+		return 0
+	}
+
+	return bestHeight - tx.Height + 1
+}
+
+// TxStore 钱包交易记录，以及已经同步到的区块
+type TxStore struct {
+	Txs       map[string]*WalletTx // 交易ID -> 交易记录
+	TipHash   string               // 已同步的最后一个区块哈希（十六进制），从未同步时为空
+	TipHeight int                  // 已同步的最后一个区块高度
+}
+
+// ErrTxNotFound 钱包中没有该交易
+var ErrTxNotFound = errors.New("transaction is not in the wallet")
+
+// NewTxStore 创建空的交易记录
+func NewTxStore() *TxStore {
+	return &TxStore{make(map[string]*WalletTx), "", -1}
+}
+
+// Transactions 钱包的交易记录，旧钱包文件中没有时创建
+func (ws *Wallets) Transactions() *TxStore {
+	if ws.TxStore == nil {
+		ws.TxStore = NewTxStore()
+	}
+
+	return ws.TxStore
+}
+
+// List 按时间顺序列出交易：已确认的交易按区块高度排序，未确认的交易排在最后
+func (s *TxStore) List() []*WalletTx {
+	txs := make([]*WalletTx, 0, len(s.Txs))
+	for _, tx := range s.Txs {
+		txs = append(txs, tx)
+	}
+
+	sort.Slice(txs, func(i, j int) bool {
+		a, b := txs[i], txs[j]
+		if (a.Height < 0) != (b.Height < 0) {
+			return b.Height < 0
+		}
+		if a.Height != b.Height {
+			return a.Height < b.Height
+		}
+		if a.Timestamp != b.Timestamp {
+			return a.Timestamp < b.Timestamp
+		}
+		return a.TxID < b.TxID
+	})
+
+	return txs
+}
+
+// Balance 已确认余额与未确认交易的净额，冲突与已放弃的交易不计入
+func (s *TxStore) Balance() (int, int) {
+	confirmed, pending := 0, 0
+	for _, tx := range s.Txs {
+		switch tx.Status {
+		case TxConfirmed:
+			confirmed += tx.Net()
+		case TxPending:
+			pending += tx.Net()
+		}
+	}
+
+	return confirmed, pending
+}
+
+// Abandon 放弃未确认或冲突的交易，被放弃的交易不再计入余额
+// 放弃的交易之后若被打包，会重新变为已确认状态
+func (s *TxStore) Abandon(txID string) error {
+	tx, ok := s.Txs[txID]
+	if !ok {
+		return ErrTxNotFound
+	}
+	if tx.Status == TxConfirmed {
+		return fmt.Errorf("transaction %s is confirmed and cannot be abandoned", txID)
+	}
+
+	tx.Status = TxAbandoned
+
+	return nil
+}
+
+// MarkReplaced 将未确认的交易标记为已被另一笔交易替换，被替换的交易不再计入余额
+// 被替换的交易之后若仍被打包，会重新变为已确认状态，替换交易则变为冲突
+func (s *TxStore) MarkReplaced(txID, replacement string) error {
+	tx, ok := s.Txs[txID]
+	if !ok {
+		return ErrTxNotFound
+	}
+	if tx.Status != TxPending {
+		return fmt.Errorf("transaction %s is %s and cannot be replaced", txID, tx.Status)
+	}
+
+	tx.Status, tx.ReplacedBy = TxReplaced, replacement
+
+	return nil
+}
+
+// Reset 清除指定高度区间内已确认的交易，供重新扫描区块链使用
+func (s *TxStore) Reset(from, to int) {
+	for id, tx := range s.Txs {
+		if tx.Status == TxConfirmed && tx.Height >= from && tx.Height <= to {
+			delete(s.Txs, id)
+		}
+	}
+
+	// 与被清除的交易冲突的交易恢复为未确认，重新扫描时再次判断
+	for _, tx := range s.Txs {
+		if _, ok := s.Txs[tx.ConflictedBy]; tx.Status == TxConflicted && !ok {
+			tx.Status, tx.ConflictedBy = TxPending, ""
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package wallet
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// 付款请求URI的协议名
+const URIScheme = "bitcoin"
+
+// PaymentURI 付款请求：bitcoin:地址?amount=金额&label=标签&message=附言
+// 金额以最小单位的整数表示，为0时表示未指定金额
+type PaymentURI struct {
+	Address string
+	Amount  int
+	Label   string
+	Message string
+}
+
+// ParsePaymentURI 解析付款请求URI
+// 协议名不区分大小写，未知的 req- 参数表示付款方必须理解的条件，因此解析失败，其他未知参数被忽略
+func ParsePaymentURI(uri string) (*PaymentURI, error) {
+	sep := strings.IndexByte(uri, ':')
+	if sep < 0 || !strings.EqualFold(uri[:sep], URIScheme) {
+		return nil, fmt.Errorf("not a %s: URI", URIScheme)
+	}
+
+	rest := strings.TrimPrefix(uri[sep+1:], "//")
+	address, rawQuery := rest, ""
+	if i := strings.IndexByte(rest, '?'); i >= 0 {
+		address, rawQuery = rest[:i], rest[i+1:]
+	}
+
+	address, err := url.PathUnescape(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URI address: %w", err)
+	}
+	if _, err := DecodeAddress(address, ActiveNetParams); err != nil {
+		return nil, err
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URI parameters: %w", err)
+	}
+
+	p := &PaymentURI{Address: address}
+	for key, values := range query {
+		if len(values) > 1 {
+			return nil, fmt.Errorf("duplicate URI parameter %q", key)
+		}
+
+		value := values[0]
+		switch key {
+		case "amount":
+			amount, err := strconv.Atoi(value)
+			if err != nil || amount <= 0 {
+				return nil, fmt.Errorf("invalid URI amount %q", value)
+			}
+			p.Amount = amount
+		case "label":
+			p.Label = value
+		case "message":
+			p.Message = value
+		default:
+			if strings.HasPrefix(key, "req-") {
+				return nil, fmt.Errorf("unsupported required URI parameter %q", key)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+// String 生成付款请求URI，参数顺序固定为 amount、label、message
+func (p PaymentURI) String() string {
+	var params []string
+	if p.Amount > 0 {
+		params = append(params, "amount="+strconv.Itoa(p.Amount))
+	}
+	if p.Label != "" {
+		params = append(params, "label="+uriEscape(p.Label))
+	}
+	if p.Message != "" {
+		params = append(params, "message="+uriEscape(p.Message))
+	}
+
+	uri := URIScheme + ":" + p.Address
+	if len(params) > 0 {
+		uri += "?" + strings.Join(params, "&")
+	}
+
+	return uri
+}
+
+// uriEscape 对参数值进行百分号编码，空格编码为 %20 而不是 +，便于其他钱包解析
+func uriEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,38 @@
+package wallet
+
+import (
+	"testing"
+)
+
+func TestPaymentURI(t *testing.T) {
+	address := "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH"
+	request := PaymentURI{address, 50, "Luke Jr", "Donation for project xyz & co"}
+
+	uri := request.String()
+	if uri != "bitcoin:"+address+"?amount=50&label=Luke%20Jr&message=Donation%20for%20project%20xyz%20%26%20co" {
+		t.Errorf("String error: %s", uri)
+	}
+	parsed, err := ParsePaymentURI(uri)
+	if err != nil || *parsed != request {
+		t.Fatalf("ParsePaymentURI error: %+v %v", parsed, err)
+	}
+
+	// 协议名不区分大小写，未知的普通参数被忽略
+	parsed, err = ParsePaymentURI("BITCOIN:bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4?somethingyoudontunderstand=50")
+	if err != nil || parsed.Amount != 0 {
+		t.Errorf("ParsePaymentURI error: %+v %v", parsed, err)
+	}
+
+	for _, invalid := range []string{
+		"litecoin:" + address,
+		"bitcoin:" + address + "x",
+		"bitcoin:" + address + "?amount=1.5",
+		"bitcoin:" + address + "?amount=-1",
+		"bitcoin:" + address + "?amount=1&amount=2",
+		"bitcoin:" + address + "?req-somethingyoudontunderstand=50",
+	} {
+		if _, err := ParsePaymentURI(invalid); err == nil {
+			t.Errorf("ParsePaymentURI(%s) error: 无效的付款请求解析成功", invalid)
+		}
+	}
+}
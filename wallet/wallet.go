@@ -4,15 +4,19 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
 	"crypto/sha256"
 	"encoding/gob"
+	"errors"
 	"fmt"
+	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/mr-tron/base58"
 	"golang.org/x/crypto/ripemd160"
 	"io/ioutil"
 	"log"
+	"math/big"
 	"os"
+	"path/filepath"
+	"time"
 )
 
 //常量定义
@@ -26,13 +30,34 @@ const walletFile = "./tmp/wallets_%s.dat"
 
 // 钱包结构
 type Wallet struct {
-	PrivateKey ecdsa.PrivateKey //私钥
+	PrivateKey ecdsa.PrivateKey //私钥，钱包锁定时为空
 	PublicKey  []byte           //公钥
+
+	encryptedKey []byte // 加密后的私钥，钱包未加密时为空
+}
+
+// 钱包的持久化结构，椭圆曲线以名称保存
+type walletRecord struct {
+	Curve        string
+	PrivateKey   []byte
+	PublicKey    []byte
+	EncryptedKey []byte
 }
 
 // 地址与钱包结构的映射
 type Wallets struct {
-	Wallets map[string]*Wallet
+	Version     int // 钱包文件格式版本
+	Wallets     map[string]*Wallet
+	HD          *HDChain                // 分层确定性钱包状态，旧版本的钱包文件中为空
+	Crypto      *WalletCrypto           // 加密参数，钱包未加密时为空
+	WatchOnly   map[string]*WatchOnly   // 只读地址，没有私钥
+	Meta        map[string]*AddressMeta // 钱包地址（包括只读地址）的标签、创建时间与用途
+	AddressBook map[string]*Contact     // 地址簿，记录交易对手的地址
+	TxStore     *TxStore                // 交易记录，旧钱包文件中为空
+	Session     *UnlockSession          // 解锁会话，钱包未解锁时为空
+
+	key     []byte // 解锁后的加密密钥，不写入文件
+	session bool   // 钱包由解锁会话解锁，没有加密密钥
 }
 
 // 创建钱包
@@ -41,26 +66,134 @@ func NewWallet() *Wallet {
 	private, public := NewKeyPair()
 
 	// 2. 初始化新钱包
-	wallet := Wallet{private, public}
+	wallet := Wallet{private, public, nil}
 
 	return &wallet
 }
 
 // NewKeyPair 密钥对生成
 func NewKeyPair() (ecdsa.PrivateKey, []byte) {
-	//获得椭圆曲线
-	curve := elliptic.P256()
-
-	//通过曲线与随机数 生成私钥
-	privateKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+	//通过secp256k1曲线与随机数 生成私钥
+	privateKey, err := btcec.NewPrivateKey()
 	if err != nil {
 		log.Panic(err)
 	}
 
-	//利用私钥推导出公钥
-	publicKey := append(privateKey.PublicKey.X.Bytes(), privateKey.PublicKey.Y.Bytes()...) //语法糖，将多个参数合并成一个切片
+	//利用私钥推导出33字节的压缩公钥
+	publicKey := privateKey.PubKey().SerializeCompressed()
+
+	return *privateKey.ToECDSA(), publicKey
+}
+
+// SerializePublicKey 公钥序列化：secp256k1使用SEC1压缩格式，旧的P256公钥沿用 X||Y 格式
+func SerializePublicKey(pubKey *ecdsa.PublicKey) []byte {
+	if pubKey.Curve == elliptic.P256() {
+		return append(pubKey.X.Bytes(), pubKey.Y.Bytes()...)
+	}
+
+	var x, y btcec.FieldVal
+	x.SetByteSlice(pubKey.X.Bytes())
+	y.SetByteSlice(pubKey.Y.Bytes())
+
+	return btcec.NewPublicKey(&x, &y).SerializeCompressed()
+}
+
+// ParsePublicKey 解析公钥
+// 33字节且带有SEC1压缩前缀的为secp256k1公钥，其余按旧的P256 X||Y 格式解析
+func ParsePublicKey(pubKey []byte) (*ecdsa.PublicKey, error) {
+	if len(pubKey) == btcec.PubKeyBytesLenCompressed && (pubKey[0] == 0x02 || pubKey[0] == 0x03) {
+		key, err := btcec.ParsePubKey(pubKey)
+		if err != nil {
+			return nil, err
+		}
+		return key.ToECDSA(), nil
+	}
+
+	return parseLegacyPublicKey(pubKey)
+}
+
+// parseLegacyPublicKey 解析旧的P256公钥
+// 旧格式直接拼接 X、Y 且没有补齐，坐标有前导零时无法从中间切分，需要逐一尝试切分位置
+func parseLegacyPublicKey(pubKey []byte) (*ecdsa.PublicKey, error) {
+	curve := elliptic.P256()
+	size := (curve.Params().BitSize + 7) / 8
+	if len(pubKey) == 0 || len(pubKey) > 2*size {
+		return nil, errors.New("invalid public key length")
+	}
+
+	for split := len(pubKey) - size; split <= size; split++ {
+		if split <= 0 || split >= len(pubKey) {
+			continue
+		}
+
+		x := new(big.Int).SetBytes(pubKey[:split])
+		y := new(big.Int).SetBytes(pubKey[split:])
+		if curve.IsOnCurve(x, y) {
+			return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+		}
+	}
+
+	return nil, errors.New("invalid public key")
+}
+
+// GobEncode 钱包编码，避免对椭圆曲线接口进行Gob编码，加密钱包只写入私钥密文
+// 加密钱包在解锁会话期间派生的地址没有私钥密文，只写入公钥
+func (w Wallet) GobEncode() ([]byte, error) {
+	if w.PrivateKey.Curve == nil {
+		return nil, errors.New("wallet has no private key")
+	}
+
+	record := walletRecord{
+		Curve:     w.PrivateKey.Curve.Params().Name,
+		PublicKey: w.PublicKey,
+	}
+	if w.encryptedKey != nil {
+		record.EncryptedKey = w.encryptedKey
+	} else if w.PrivateKey.D != nil {
+		record.PrivateKey = w.PrivateKey.D.Bytes()
+	}
+
+	var buff bytes.Buffer
+	if err := gob.NewEncoder(&buff).Encode(record); err != nil {
+		return nil, err
+	}
+
+	return buff.Bytes(), nil
+}
+
+// GobDecode 钱包解码，根据曲线名称恢复私钥
+func (w *Wallet) GobDecode(data []byte) error {
+	var record walletRecord
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil {
+		return err
+	}
+
+	var curve elliptic.Curve
+	switch record.Curve {
+	case btcec.S256().Params().Name:
+		curve = btcec.S256()
+	case elliptic.P256().Params().Name:
+		curve = elliptic.P256()
+	default:
+		return fmt.Errorf("unsupported curve %q", record.Curve)
+	}
+
+	w.PrivateKey.Curve = curve
+	w.PublicKey = record.PublicKey
+
+	// 加密的私钥在钱包解锁时再解密，只有公钥的记录在解锁时由种子派生私钥
+	if record.EncryptedKey != nil {
+		w.encryptedKey = record.EncryptedKey
+		return nil
+	}
+	if record.PrivateKey == nil {
+		return nil
+	}
+
+	w.PrivateKey.D = new(big.Int).SetBytes(record.PrivateKey)
+	w.PrivateKey.PublicKey.X, w.PrivateKey.PublicKey.Y = curve.ScalarBaseMult(record.PrivateKey)
 
-	return *privateKey, publicKey
+	return nil
 }
 
 // 计算公钥
@@ -86,8 +219,12 @@ func (w Wallet) GenerateAddress() []byte {
 	// 1. 获得公钥哈希
 	pubHash := PublicKeyHash(w.PublicKey)
 
-	// 2. 组装版本号
-	versionedHash := append([]byte{version}, pubHash...)
+	// 2. 组装版本号，x-only公钥使用Schnorr地址版本号
+	addrVersion := version
+	if w.IsSchnorr() {
+		addrVersion = SchnorrVersion
+	}
+	versionedHash := append([]byte{addrVersion}, pubHash...)
 	// 3. 获得校验和
 	checksum := Checksum(versionedHash)
 
@@ -107,23 +244,11 @@ func Checksum(payload []byte) []byte {
 	return secondSHA[:ChecksumLen]
 }
 
-// ValidateAddress 验证地址合法性
+// ValidateAddress 验证地址合法性，支持Base58Check与当前网络的Bech32地址
 func ValidateAddress(address string) bool {
-	// 1. Base58解码
-	pubKeyHash := Base58Decode([]byte(address))
-	length := len(pubKeyHash)
-
-	// 2. 提取实际的的校验码
-	actualChecksum := pubKeyHash[length-ChecksumLen:]
-
-	// 3. 组装参数获得目标校验码
-	version := pubKeyHash[0]
-	pubKeyHash = pubKeyHash[1 : length-ChecksumLen]
+	_, err := DecodeAddress(address, ActiveNetParams)
 
-	targetChecksum := Checksum(append([]byte{version}, pubKeyHash...)) //语法糖，将多个参数合并成一个切片
-
-	// 4. 比对并返回校验码
-	return bytes.Compare(actualChecksum, targetChecksum) == 0
+	return err == nil
 }
 
 //Base58Encode Base58编码
@@ -149,17 +274,46 @@ func (ws *Wallets) SaveFile(nodeId string) {
 	var content bytes.Buffer
 	walletFile := fmt.Sprintf(walletFile, nodeId) //根据不同节点号进行钱包存储
 
-	// 2.获取椭圆曲线对象并进行加密，保证私钥文件的存储安全
-	gob.Register(elliptic.P256())
+	// 2.加密钱包先加密新生成的私钥与种子，文件中只保存密文
+	if err := ws.encryptSecrets(); err != nil {
+		log.Panic(err)
+	}
+	toSave := *ws
+	toSave.Version = WalletFileVersion
+	if ws.HD != nil && ws.HD.EncryptedSeed != nil {
+		hd := *ws.HD
+		hd.Seed = nil
+		toSave.HD = &hd
+	}
+	if ws.IsEncrypted() {
+		toSave.Wallets = make(map[string]*Wallet, len(ws.Wallets))
+		for address, w := range ws.Wallets {
+			if w.encryptedKey == nil {
+				w = &Wallet{PrivateKey: ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: w.PrivateKey.Curve}}, PublicKey: w.PublicKey}
+			}
+			toSave.Wallets[address] = w
+		}
+	}
+	if ws.Session != nil && time.Now().After(ws.Session.Expires) {
+		toSave.Session = nil
+	}
 
+	// 3.对钱包集合进行编码，钱包结构自行编码私钥与曲线名称
 	encoder := gob.NewEncoder(&content)
-	err := encoder.Encode(ws)
+	err := encoder.Encode(toSave)
 	if err != nil {
-		fmt.Println("ws is ")
+		log.Panic(err)
 	}
 
-	// 3.将加密后的数据写入文件中
-	err = ioutil.WriteFile(walletFile, content.Bytes(), 0644)
+	// 4.将编码后的数据写入文件中，仅所有者可读写
+	if err = os.MkdirAll(filepath.Dir(walletFile), 0755); err != nil {
+		log.Panic(err)
+	}
+	err = ioutil.WriteFile(walletFile, content.Bytes(), 0600)
+	if err != nil {
+		log.Panic(err)
+	}
+	err = os.Chmod(walletFile, 0600)
 	if err != nil {
 		log.Panic(err)
 	}
@@ -174,22 +328,31 @@ func (ws *Wallets) LoadFile(nodeId string) error {
 	}
 
 	// 2.打开钱包文件后，读取文件中的数据
-	var wallets Wallets
-
 	fileContent, err := ioutil.ReadFile(walletFile)
 	if err != nil {
 		return err
 	}
 
-	// 3.获取椭圆曲线对象并进行解密
-	gob.Register(elliptic.P256())
-	decoder := gob.NewDecoder(bytes.NewReader(fileContent))
-	err = decoder.Decode(&wallets)
+	// 3.对钱包数据进行解码，并升级到当前版本
+	wallets, err := decodeWallets(fileContent)
 	if err != nil {
 		return err
 	}
 
+	ws.Version = wallets.Version
 	ws.Wallets = wallets.Wallets
+	ws.HD = wallets.HD
+	ws.Crypto = wallets.Crypto
+	ws.WatchOnly = wallets.WatchOnly
+	ws.Meta = wallets.Meta
+	ws.AddressBook = wallets.AddressBook
+	ws.TxStore = wallets.TxStore
+	ws.Session = wallets.Session
+
+	// 4.加密钱包在解锁会话有效期内自动解锁
+	if ws.IsEncrypted() {
+		ws.loadUnlockSession(nodeId)
+	}
 
 	return nil
 }
@@ -212,6 +375,18 @@ func (ws *Wallets) AddWallet() string {
 
 	// 2. 映射地址与钱包结构体的关系
 	ws.Wallets[address] = wallet
+	ws.recordAddress(address, PurposeReceive)
+
+	return address
+}
+
+// AddSchnorrWallet 添加使用Schnorr签名的钱包信息
+func (ws *Wallets) AddSchnorrWallet() string {
+	wallet := NewSchnorrWallet()
+	address := fmt.Sprintf("%s", wallet.GenerateAddress())
+
+	ws.Wallets[address] = wallet
+	ws.recordAddress(address, PurposeReceive)
 
 	return address
 }
@@ -1,6 +1,12 @@
 package wallet
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/gob"
 	"fmt"
 	"os"
 	"testing"
@@ -53,3 +59,49 @@ func TestWallet(t *testing.T) {
 	}
 
 }
+
+func TestPublicKeyFormats(t *testing.T) {
+	// 新钱包使用secp256k1压缩公钥
+	wallet := NewWallet()
+	if len(wallet.PublicKey) != 33 {
+		t.Fatalf("NewWallet error: 公钥长度为 %d，期望 33", len(wallet.PublicKey))
+	}
+	pubKey, err := ParsePublicKey(wallet.PublicKey)
+	if err != nil || pubKey.X.Cmp(wallet.PrivateKey.X) != 0 || pubKey.Y.Cmp(wallet.PrivateKey.Y) != 0 {
+		t.Errorf("ParsePublicKey error: 压缩公钥解析失败 %v", err)
+	}
+
+	// 旧的P256公钥：X坐标带有前导零时，X||Y 的长度不足64字节
+	var legacy *ecdsa.PrivateKey
+	for legacy == nil || len(legacy.X.Bytes()) == 32 {
+		legacy, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	legacyPub := SerializePublicKey(&legacy.PublicKey)
+	parsed, err := ParsePublicKey(legacyPub)
+	if err != nil || parsed.X.Cmp(legacy.X) != 0 || parsed.Y.Cmp(legacy.Y) != 0 {
+		t.Errorf("ParsePublicKey error: 旧格式公钥解析失败 %v", err)
+	}
+
+	hash := sha256.Sum256([]byte("legacy"))
+	sig, err := Sign(legacy, hash[:])
+	if err != nil || !VerifySignature(parsed, hash[:], sig) {
+		t.Errorf("VerifySignature error: 旧格式公钥签名验证失败 %v", err)
+	}
+
+	// 钱包编码后保留曲线信息
+	legacyWallet := Wallet{PrivateKey: *legacy, PublicKey: legacyPub}
+	var buff bytes.Buffer
+	if err := gob.NewEncoder(&buff).Encode(map[string]*Wallet{"a": wallet, "b": &legacyWallet}); err != nil {
+		t.Fatalf("GobEncode error: %v", err)
+	}
+	var decoded map[string]*Wallet
+	if err := gob.NewDecoder(&buff).Decode(&decoded); err != nil {
+		t.Fatalf("GobDecode error: %v", err)
+	}
+	if decoded["a"].PrivateKey.Curve != wallet.PrivateKey.Curve || decoded["b"].PrivateKey.Curve != elliptic.P256() {
+		t.Error("GobDecode error: 椭圆曲线恢复错误")
+	}
+	if decoded["a"].PrivateKey.D.Cmp(wallet.PrivateKey.D) != 0 || !bytes.Equal(decoded["b"].PublicKey, legacyPub) {
+		t.Error("GobDecode error: 密钥恢复错误")
+	}
+}
@@ -0,0 +1,84 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"sort"
+)
+
+// WatchOnly 只读地址，钱包中只有地址或公钥，没有私钥，只能查询余额与构造未签名的交易
+type WatchOnly struct {
+	PublicKey []byte // 公钥，只导入地址时为空
+}
+
+// ErrWatchOnly 只读地址无法签名
+var ErrWatchOnly = errors.New("address is watch-only, the wallet holds no private key for it")
+
+// ImportAddress 导入只读地址
+func (ws *Wallets) ImportAddress(address string) error {
+	// 钱包中的地址统一以Base58Check形式保存
+	normalized, err := NormalizeAddress(address)
+	if err != nil {
+		return err
+	}
+
+	return ws.addWatchOnly(normalized, nil)
+}
+
+// ImportPublicKey 导入只读公钥，支持33字节压缩公钥与32字节x-only公钥（Schnorr地址），返回对应的地址
+func (ws *Wallets) ImportPublicKey(pubKey []byte) (string, error) {
+	switch len(pubKey) {
+	case SchnorrPubKeyLen:
+		if _, err := schnorr.ParsePubKey(pubKey); err != nil {
+			return "", fmt.Errorf("invalid x-only public key: %w", err)
+		}
+	default:
+		if _, err := btcec.ParsePubKey(pubKey); err != nil {
+			return "", fmt.Errorf("invalid public key: %w", err)
+		}
+	}
+
+	address := string(Wallet{PublicKey: pubKey}.GenerateAddress())
+
+	return address, ws.addWatchOnly(address, pubKey)
+}
+
+// addWatchOnly 记录只读地址，已经持有私钥的地址不能再作为只读地址导入
+func (ws *Wallets) addWatchOnly(address string, pubKey []byte) error {
+	if _, ok := ws.Wallets[address]; ok {
+		return fmt.Errorf("address %s already belongs to this wallet", address)
+	}
+	if ws.WatchOnly == nil {
+		ws.WatchOnly = make(map[string]*WatchOnly)
+	}
+
+	// 先导入地址、后导入公钥时，补充公钥信息
+	if existing, ok := ws.WatchOnly[address]; ok && pubKey == nil {
+		pubKey = existing.PublicKey
+	}
+	ws.WatchOnly[address] = &WatchOnly{pubKey}
+	ws.recordAddress(address, PurposeReceive)
+
+	return nil
+}
+
+// IsWatchOnly 判断地址是否为只读地址
+func (ws *Wallets) IsWatchOnly(address string) bool {
+	_, ok := ws.WatchOnly[address]
+
+	return ok
+}
+
+// GetWatchOnlyAddresses 获取所有只读地址
+func (ws *Wallets) GetWatchOnlyAddresses() []string {
+	var addresses []string
+
+	for address := range ws.WatchOnly {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	return addresses
+}
@@ -0,0 +1,46 @@
+package wallet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWatchOnly(t *testing.T) {
+	cold := NewWallet()
+	coldAddress := string(cold.GenerateAddress())
+	schnorrCold := NewSchnorrWallet()
+
+	wallets := Wallets{Wallets: make(map[string]*Wallet)}
+	owned := wallets.AddWallet()
+
+	if err := wallets.ImportAddress(coldAddress); err != nil {
+		t.Fatalf("ImportAddress error: %v", err)
+	}
+	address, err := wallets.ImportPublicKey(schnorrCold.PublicKey)
+	if err != nil || address != string(schnorrCold.GenerateAddress()) {
+		t.Fatalf("ImportPublicKey error: %s, %v", address, err)
+	}
+
+	// 先导入地址、再导入公钥，公钥被补充到只读记录中
+	if _, err := wallets.ImportPublicKey(cold.PublicKey); err != nil || wallets.WatchOnly[coldAddress].PublicKey == nil {
+		t.Errorf("ImportPublicKey error: 未补充只读地址的公钥 %v", err)
+	}
+
+	if !wallets.IsWatchOnly(coldAddress) || wallets.IsWatchOnly(owned) {
+		t.Error("IsWatchOnly error: 只读标记不正确")
+	}
+	if len(wallets.GetWatchOnlyAddresses()) != 2 {
+		t.Errorf("GetWatchOnlyAddresses error: 只读地址数量为 %d", len(wallets.GetWatchOnlyAddresses()))
+	}
+
+	// 只读地址不能签名，已持有私钥的地址不能作为只读地址导入
+	if _, err := wallets.GetSigningWallet(coldAddress); !errors.Is(err, ErrWatchOnly) {
+		t.Errorf("GetSigningWallet error: 只读地址返回 %v", err)
+	}
+	if err := wallets.ImportAddress(owned); err == nil {
+		t.Error("ImportAddress error: 已持有私钥的地址被导入为只读地址")
+	}
+	if _, err := wallets.ImportPublicKey([]byte{0x02, 0x01}); err == nil {
+		t.Error("ImportPublicKey error: 非法公钥未被拒绝")
+	}
+}
@@ -0,0 +1,126 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcec/v2/schnorr"
+	"github.com/mr-tron/base58"
+)
+
+// NetParams 网络参数，决定私钥、Bech32地址等编码使用的前缀
+type NetParams struct {
+	Name         string // 网络名称
+	PrivateKeyID byte   // WIF私钥前缀
+	Bech32HRP    string // Bech32地址的人类可读部分
+}
+
+// 主网与测试网参数
+var (
+	MainNetParams = NetParams{"mainnet", 0x80, "bc"}
+	TestNetParams = NetParams{"testnet", 0xef, "tb"}
+)
+
+// ActiveNetParams 当前节点使用的网络参数
+var ActiveNetParams = &MainNetParams
+
+// WIF中压缩公钥标记
+const compressMagic = 0x01
+
+// EncodeWIF 将私钥编码为WIF格式：Base58Check(前缀 || 32字节私钥 || [0x01])
+func EncodeWIF(privKey *ecdsa.PrivateKey, compress bool, net *NetParams) (string, error) {
+	if privKey.Curve != btcec.S256() {
+		return "", errors.New("only secp256k1 keys can be exported as WIF")
+	}
+	if privKey.D == nil {
+		return "", ErrWalletLocked
+	}
+
+	payload := append([]byte{net.PrivateKeyID}, privateKeyBytes(privKey)...)
+	if compress {
+		payload = append(payload, compressMagic)
+	}
+
+	return string(Base58Encode(append(payload, Checksum(payload)...))), nil
+}
+
+// DecodeWIF 解析WIF格式的私钥，返回私钥以及是否对应压缩公钥，前缀与网络不一致时返回错误
+func DecodeWIF(wif string, net *NetParams) (*ecdsa.PrivateKey, bool, error) {
+	decoded, err := base58.Decode(wif)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid WIF encoding: %w", err)
+	}
+
+	var compress bool
+	switch len(decoded) {
+	case 1 + 32 + 1 + ChecksumLen:
+		if decoded[33] != compressMagic {
+			return nil, false, errors.New("invalid WIF compression flag")
+		}
+		compress = true
+	case 1 + 32 + ChecksumLen:
+	default:
+		return nil, false, errors.New("invalid WIF length")
+	}
+
+	payload, checksum := decoded[:len(decoded)-ChecksumLen], decoded[len(decoded)-ChecksumLen:]
+	if !bytes.Equal(Checksum(payload), checksum) {
+		return nil, false, errors.New("invalid WIF checksum")
+	}
+	if payload[0] != net.PrivateKeyID {
+		return nil, false, fmt.Errorf("WIF key is not for %s", net.Name)
+	}
+
+	var keyNum btcec.ModNScalar
+	if overflow := keyNum.SetByteSlice(payload[1:33]); overflow || keyNum.IsZero() {
+		return nil, false, errors.New("invalid WIF private key")
+	}
+	priv, _ := btcec.PrivKeyFromBytes(payload[1:33])
+
+	return priv.ToECDSA(), compress, nil
+}
+
+// DumpPrivateKey 导出地址对应私钥的WIF编码
+func (ws *Wallets) DumpPrivateKey(address string) (string, error) {
+	w, err := ws.GetSigningWallet(address)
+	if err != nil {
+		return "", err
+	}
+
+	return EncodeWIF(&w.PrivateKey, true, ActiveNetParams)
+}
+
+// ImportPrivateKey 导入WIF格式的私钥，schnorr为真时生成Schnorr地址，返回对应的地址
+func (ws *Wallets) ImportPrivateKey(wif string, schnorrKey bool) (string, error) {
+	if err := ws.requireKey(); err != nil {
+		return "", err
+	}
+
+	privKey, compress, err := DecodeWIF(wif, ActiveNetParams)
+	if err != nil {
+		return "", err
+	}
+	if !compress && !schnorrKey {
+		return "", errors.New("uncompressed public keys are not supported")
+	}
+
+	priv, _ := btcec.PrivKeyFromBytes(privateKeyBytes(privKey))
+	w := &Wallet{*privKey, priv.PubKey().SerializeCompressed(), nil}
+	if schnorrKey {
+		w.PublicKey = schnorr.SerializePubKey(priv.PubKey())
+	}
+
+	address := string(w.GenerateAddress())
+	if _, ok := ws.Wallets[address]; ok {
+		return address, fmt.Errorf("address %s already belongs to this wallet", address)
+	}
+
+	// 导入私钥后，原有的只读地址升级为可签名的地址
+	ws.Wallets[address] = w
+	delete(ws.WatchOnly, address)
+	ws.recordAddress(address, PurposeReceive)
+
+	return address, nil
+}
@@ -0,0 +1,47 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestWIF(t *testing.T) {
+	keyBytes, _ := hex.DecodeString("0c28fca386c7a227600b2fe50b7cae11ec86d3bf1fbe471be89827e19d72aa1d")
+	privKey, _, err := DecodeWIF("KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617", &MainNetParams)
+	if err != nil {
+		t.Fatalf("DecodeWIF error: %v", err)
+	}
+	if privKey.D.Cmp(new(big.Int).SetBytes(keyBytes)) != 0 {
+		t.Errorf("DecodeWIF error: 私钥为 %x", privKey.D)
+	}
+	if wif, _ := EncodeWIF(privKey, true, &MainNetParams); wif != "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617" {
+		t.Errorf("EncodeWIF error: %s", wif)
+	}
+	if wif, _ := EncodeWIF(privKey, false, &MainNetParams); wif != "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ" {
+		t.Errorf("EncodeWIF error: 非压缩格式为 %s", wif)
+	}
+
+	// 网络前缀不一致的私钥必须被拒绝
+	testnetWIF, _ := EncodeWIF(privKey, true, &TestNetParams)
+	if _, _, err := DecodeWIF(testnetWIF, &MainNetParams); err == nil {
+		t.Error("DecodeWIF error: 测试网私钥被主网接受")
+	}
+
+	// 导出后在另一个钱包中导入，得到相同的地址
+	source := Wallets{Wallets: make(map[string]*Wallet)}
+	address := source.AddWallet()
+	wif, err := source.DumpPrivateKey(address)
+	if err != nil {
+		t.Fatalf("DumpPrivateKey error: %v", err)
+	}
+	target := Wallets{Wallets: make(map[string]*Wallet)}
+	target.ImportAddress(address)
+	imported, err := target.ImportPrivateKey(wif, false)
+	if err != nil || imported != address {
+		t.Fatalf("ImportPrivateKey error: %s, %v", imported, err)
+	}
+	if target.IsWatchOnly(address) {
+		t.Error("ImportPrivateKey error: 导入私钥后地址仍为只读")
+	}
+}